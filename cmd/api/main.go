@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"html/template"
 	"log"
@@ -8,6 +9,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/himanshu/daily-planner/internal/config"
+	"github.com/himanshu/daily-planner/internal/digest"
+	"github.com/himanshu/daily-planner/internal/jobs"
+	"github.com/himanshu/daily-planner/internal/mail"
+	"github.com/himanshu/daily-planner/internal/models"
 	"github.com/himanshu/daily-planner/internal/repository"
 	"github.com/himanshu/daily-planner/internal/routes"
 	"github.com/himanshu/daily-planner/pkg/middleware"
@@ -45,6 +50,33 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Start background jobs; their changes show in the activity log as the job's
+	if cfg.JobsEnabled {
+		jobDB := db.WithSource(models.ActivitySourceJob)
+		scheduled := []jobs.Job{
+			jobs.KeepInTouch(jobDB),
+			jobs.Occasions(jobDB),
+			jobs.DataExports(jobDB),
+			jobs.AccountPurge(jobDB),
+			jobs.TrashPurge(jobDB, cfg.TrashRetention),
+		}
+
+		// Daily emails are only sent when a mail driver is configured
+		mailer, err := mail.New(cfg.Mail)
+		if err != nil {
+			log.Fatalf("Failed to set up mail: %v", err)
+		}
+		if mailer != nil {
+			templates, err := digest.LoadTemplates("templates/email")
+			if err != nil {
+				log.Fatalf("Failed to load email templates: %v", err)
+			}
+			scheduled = append(scheduled, jobs.EmailDigests(jobDB, mailer, templates, cfg.BaseURL))
+		}
+
+		jobs.NewScheduler(scheduled...).Start(context.Background())
+	}
+
 	// Create Gin router
 	r := gin.Default()
 
@@ -65,7 +97,7 @@ func main() {
 	r.Static("/static", "./static")
 
 	// Set up routes
-	routes.SetupRoutes(r, db)
+	routes.SetupRoutes(r, db, cfg)
 
 	// Start server
 	log.Printf("Server starting on %s", cfg.ServerAddress)
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// recentLogin is how long after logging in a user without a password, such
+// as one who signed up through Google, may delete their account
+const recentLogin = 10 * time.Minute
+
+// deleteAccountPage is the data the delete account page is rendered with
+func (h *AuthHandler) deleteAccountPage(user *models.User) gin.H {
+	return gin.H{
+		"Title":              "Delete Account",
+		"Username":           user.Username,
+		"GraceDays":          int(h.deletionGrace.Hours() / 24),
+		"HasPassword":        user.Password != "",
+		"RecentLoginMinutes": int(recentLogin.Minutes()),
+	}
+}
+
+// ShowDeleteAccountPage renders the page for deleting the account
+func (h *AuthHandler) ShowDeleteAccountPage(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	c.HTML(http.StatusOK, "delete_account.html", h.deleteAccountPage(user))
+}
+
+// DeleteAccountHandler deletes the account once the user has typed their
+// username to confirm and proved it is them: by entering their password
+// again or, for accounts without one, by having logged in within the last
+// few minutes. The account can be restored by logging in until the grace
+// period is over; after that a background job erases it and all its data.
+func (h *AuthHandler) DeleteAccountHandler(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.Redirect(http.StatusFound, "/auth/login")
+		return
+	}
+
+	var deleteData struct {
+		Password string `form:"password"`
+		Confirm  string `form:"confirm" binding:"required"`
+	}
+	page := h.deleteAccountPage(user)
+
+	if err := c.ShouldBind(&deleteData); err != nil || (user.Password != "" && deleteData.Password == "") {
+		page["Error"] = "Please enter your password and username"
+		if user.Password == "" {
+			page["Error"] = "Please enter your username"
+		}
+		c.HTML(http.StatusBadRequest, "delete_account.html", page)
+		return
+	}
+	if deleteData.Confirm != user.Username {
+		page["Error"] = "The username you typed doesn't match"
+		c.HTML(http.StatusBadRequest, "delete_account.html", page)
+		return
+	}
+	if user.Password == "" {
+		// Accounts created through Google have no password to check, so the
+		// session must be a fresh one
+		token, _ := c.Cookie("auth_token")
+		issuedAt, err := tokenIssuedAt(token)
+		if err != nil || time.Since(issuedAt) > recentLogin {
+			page["Error"] = fmt.Sprintf("To confirm it's you, log out and log in again, then delete your account within %d minutes", int(recentLogin.Minutes()))
+			c.HTML(http.StatusUnauthorized, "delete_account.html", page)
+			return
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(deleteData.Password)) != nil {
+		page["Error"] = "Incorrect password"
+		c.HTML(http.StatusUnauthorized, "delete_account.html", page)
+		return
+	}
+
+	purgeAt := time.Now().Add(h.deletionGrace)
+	if err := h.db.DeleteUser(user.ID, purgeAt); err != nil {
+		page["Error"] = "Failed to delete account"
+		c.HTML(http.StatusInternalServerError, "delete_account.html", page)
+		return
+	}
+
+	c.SetCookie("auth_token", "", -1, "/", "", false, true)
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"Title":   "Login",
+		"Message": fmt.Sprintf("Your account has been deleted. Log in before %s to restore it; after that it and all your data are erased.", purgeAt.Format("January 2, 2006")),
+	})
+}
+
+// RestoreAccountHandler restores a deleted account that is still in its
+// grace period and logs the user in
+func (h *AuthHandler) RestoreAccountHandler(c *gin.Context) {
+	var restoreData struct {
+		Username string `form:"username" binding:"required"`
+		Password string `form:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBind(&restoreData); err != nil {
+		c.HTML(http.StatusBadRequest, "login.html", gin.H{
+			"Title": "Login",
+			"Error": "Invalid input data",
+		})
+		return
+	}
+
+	user, err := h.db.FindDeletedUserByUsername(restoreData.Username, time.Now())
+	if err != nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(restoreData.Password)) != nil {
+		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
+			"Title": "Login",
+			"Error": "Invalid credentials",
+		})
+		return
+	}
+
+	if err := h.db.RestoreUser(user.ID); err != nil {
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
+			"Title": "Login",
+			"Error": "Failed to restore account",
+		})
+		return
+	}
+
+	token, err := generateJWTToken(user.ID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
+			"Title": "Login",
+			"Error": "Failed to process login",
+		})
+		return
+	}
+
+	c.SetCookie("auth_token", token, 3600*24, "/", "", false, true)
+	c.Redirect(http.StatusFound, "/planner")
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/himanshu/daily-planner/internal/models"
@@ -11,11 +12,12 @@ import (
 )
 
 type AuthHandler struct {
-	db *repository.Database
+	db            *repository.Database
+	deletionGrace time.Duration // How long a deleted account can be restored
 }
 
-func NewAuthHandler(db *repository.Database) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *repository.Database, deletionGrace time.Duration) *AuthHandler {
+	return &AuthHandler{db: db, deletionGrace: deletionGrace}
 }
 
 // ShowLoginPage renders the login page
@@ -58,9 +60,10 @@ func (h *AuthHandler) RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// Check if username or email already exists
+	// Check if username or email already exists, including on deleted
+	// accounts that can still be restored
 	var existingUser models.User
-	if err := h.db.DB.Where("username = ? OR email = ?", registerData.Username, registerData.Email).First(&existingUser).Error; err == nil {
+	if err := h.db.DB.Unscoped().Where("username = ? OR email = ?", registerData.Username, registerData.Email).First(&existingUser).Error; err == nil {
 		c.HTML(http.StatusBadRequest, "register.html", gin.H{
 			"Title": "Register",
 			"Error": "Username or email already exists",
@@ -114,6 +117,17 @@ func (h *AuthHandler) LoginHandler(c *gin.Context) {
 
 	var user models.User
 	if err := h.db.DB.Where("username = ?", loginData.Username).First(&user).Error; err != nil {
+		// A deleted account can be restored by whoever has its password
+		if deleted, err := h.db.FindDeletedUserByUsername(loginData.Username, time.Now()); err == nil &&
+			bcrypt.CompareHashAndPassword([]byte(deleted.Password), []byte(loginData.Password)) == nil {
+			c.HTML(http.StatusForbidden, "login.html", gin.H{
+				"Title":    "Login",
+				"Error":    fmt.Sprintf("This account was deleted and will be erased on %s.", deleted.PurgeAt.Format("January 2, 2006")),
+				"Restore":  true,
+				"Username": deleted.Username,
+			})
+			return
+		}
 		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
 			"Title": "Login",
 			"Error": "Invalid credentials",
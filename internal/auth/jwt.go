@@ -31,17 +31,37 @@ func generateJWTToken(userID uint) (string, error) {
 }
 
 func ValidateJWTToken(tokenString string) (uint, error) {
+	claims, err := parseJWTToken(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID, nil
+}
+
+// tokenIssuedAt returns when a valid token was issued, i.e. when its user logged in
+func tokenIssuedAt(tokenString string) (time.Time, error) {
+	claims, err := parseJWTToken(tokenString)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if claims.IssuedAt == nil {
+		return time.Time{}, ErrInvalidToken
+	}
+	return claims.IssuedAt.Time, nil
+}
+
+func parseJWTToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
 	})
 
 	if err != nil {
-		return 0, ErrInvalidToken
+		return nil, ErrInvalidToken
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims.UserID, nil
+		return claims, nil
 	}
 
-	return 0, ErrInvalidToken
+	return nil, ErrInvalidToken
 }
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// CheckCredentials returns the user with the given username or email if the
+// password matches. It is used where a login form can't be, such as HTTP
+// Basic auth from calendar apps.
+func CheckCredentials(db *repository.Database, login, password string) (*models.User, error) {
+	user, err := db.FindUserByUsername(login)
+	if err != nil {
+		if user, err = db.FindUserByEmail(login); err != nil {
+			return nil, ErrInvalidCredentials
+		}
+	}
+
+	// Accounts created through Google have no password to check
+	if user.Password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,7 +17,15 @@ type Config struct {
 	DBPassword    string
 	DBName        string
 	JWTSecret     string
-	GoogleOAuth   GoogleOAuthConfig
+	JobsEnabled   bool // Run background jobs such as reminder generation in this process
+	// How long a deleted account can be restored before it and its data are erased
+	AccountDeletionGrace time.Duration
+	// How long deleted todos, priorities and reminders stay in the trash
+	TrashRetention time.Duration
+	// Scheme and host the site is reached on, for links in emails
+	BaseURL     string
+	GoogleOAuth GoogleOAuthConfig
+	Mail        MailConfig
 }
 
 type GoogleOAuthConfig struct {
@@ -23,23 +34,48 @@ type GoogleOAuthConfig struct {
 	RedirectURL  string
 }
 
+// MailConfig selects how outgoing email is delivered. Driver is smtp, file
+// (write each message to FileDir, for development) or empty to send none.
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string
+}
+
 func LoadConfig() (*Config, error) {
 	// Load .env file if it exists
 	godotenv.Load()
 
 	config := &Config{
-		ServerAddress: getEnv("SERVER_ADDRESS", ":8080"),
-		DBHost:        getEnv("DB_HOST", "localhost"),
-		DBPort:        getEnv("DB_PORT", "5432"),
-		DBUser:        getEnv("DB_USER", "postgres"),
-		DBPassword:    getEnv("DB_PASSWORD", "postgres"),
-		DBName:        getEnv("DB_NAME", "daily_planner"),
-		JWTSecret:     getEnv("JWT_SECRET", "7HUZ/hyZKE7IHsahSfipW8/Ec6MRTSDFgjeAKxRDzZk="),
+		ServerAddress:        getEnv("SERVER_ADDRESS", ":8080"),
+		DBHost:               getEnv("DB_HOST", "localhost"),
+		DBPort:               getEnv("DB_PORT", "5432"),
+		DBUser:               getEnv("DB_USER", "postgres"),
+		DBPassword:           getEnv("DB_PASSWORD", "postgres"),
+		DBName:               getEnv("DB_NAME", "daily_planner"),
+		JWTSecret:            getEnv("JWT_SECRET", "7HUZ/hyZKE7IHsahSfipW8/Ec6MRTSDFgjeAKxRDzZk="),
+		JobsEnabled:          getEnv("JOBS_ENABLED", "true") == "true",
+		AccountDeletionGrace: time.Duration(getEnvInt("ACCOUNT_DELETION_GRACE_DAYS", 30)) * 24 * time.Hour,
+		TrashRetention:       time.Duration(getEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		BaseURL:              strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:8080"), "/"),
 		GoogleOAuth: GoogleOAuthConfig{
 			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 			ClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/auth/google/callback"),
 		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", ""),
+			From:         getEnv("MAIL_FROM", "Daily Planner <planner@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "mail"),
+		},
 	}
 
 	return config, nil
@@ -51,3 +87,10 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getEnvInt(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,91 @@
+package dataimport
+
+import (
+	"strconv"
+
+	"github.com/himanshu/daily-planner/internal/export"
+)
+
+// ReadArchive reads a "download my data" archive. Projects, people and
+// calendar links aren't part of an archive, so todos come back without a
+// project and reminders without a person.
+func ReadArchive(data []byte) (*Batch, error) {
+	archive, err := export.Read(data)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Batch{Format: FormatArchive}
+	projects := 0
+	for _, item := range archive.Todos {
+		todo := Todo{
+			Ref:         strconv.FormatUint(uint64(item.ID), 10),
+			Title:       item.Title,
+			Description: item.Description,
+			Priority:    item.PriorityLevel,
+			Completed:   item.Completed,
+		}
+		if !item.DueDate.IsZero() {
+			due := item.DueDate
+			todo.DueDate = &due
+		}
+		for _, tag := range item.Tags {
+			todo.Tags = append(todo.Tags, tag.Name)
+		}
+		for _, subtask := range item.Subtasks {
+			todo.Subtasks = append(todo.Subtasks, Subtask{Title: subtask.Title, Completed: subtask.Completed})
+		}
+		if item.ProjectID != nil {
+			projects++
+		}
+		b.Todos = append(b.Todos, todo)
+	}
+	if projects > 0 {
+		b.warn("%d todos were in projects; archives don't include projects, so they are imported without one", projects)
+	}
+
+	refs := make(map[string]bool, len(b.Todos))
+	for _, todo := range b.Todos {
+		refs[todo.Ref] = true
+	}
+	orphans := 0
+	for _, item := range archive.Priorities {
+		priority := Priority{
+			Date:        item.Date,
+			Title:       item.Title,
+			Description: item.Description,
+			Completed:   item.Completed,
+		}
+		if item.TodoItemID != nil {
+			priority.TodoRef = strconv.FormatUint(uint64(*item.TodoItemID), 10)
+			if !refs[priority.TodoRef] {
+				orphans++
+				continue
+			}
+		}
+		b.Priorities = append(b.Priorities, priority)
+	}
+	if orphans > 0 {
+		b.warn("%d priorities were for todos the archive doesn't have and are left out", orphans)
+	}
+
+	for _, item := range archive.Contacts {
+		b.Contacts = append(b.Contacts, Contact{
+			Date:        item.Date,
+			Name:        item.Name,
+			Type:        item.Type,
+			Description: item.Description,
+			Completed:   item.Completed,
+		})
+	}
+
+	for _, day := range archive.WaterIntake {
+		b.Water = append(b.Water, WaterIntake{Date: day.Date, Glasses: day.Glasses})
+	}
+
+	for _, item := range archive.Thoughts {
+		b.Thoughts = append(b.Thoughts, Thought{Date: item.Date, Content: item.Content})
+	}
+
+	return b, nil
+}
@@ -0,0 +1,219 @@
+package dataimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// csvFields lists the fields each entity reads from a CSV file; the first
+// ones listed are required
+var csvFields = map[string]struct {
+	required int
+	fields   []string
+}{
+	EntityTodos:       {1, []string{"title", "description", "due_date", "priority", "completed", "tags", "project"}},
+	EntityPriorities:  {2, []string{"date", "title", "description", "completed"}},
+	EntityContacts:    {3, []string{"date", "name", "type", "description", "completed"}},
+	EntityWaterIntake: {2, []string{"date", "glasses"}},
+	EntityThoughts:    {2, []string{"date", "content"}},
+}
+
+// CSVFields returns the fields a CSV import of entity can map, or nil for
+// an entity that can't be imported from CSV
+func CSVFields(entity string) []string {
+	return csvFields[entity].fields
+}
+
+// normalizeHeader lets "Due Date", "due-date" and "due_date" match
+func normalizeHeader(name string) string {
+	return strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// ReadCSV reads one entity from a CSV file with a header row. mapping names
+// the column each field comes from, e.g. {"title": "Task"}; fields left out
+// are read from the column with the field's own name, if there is one. The
+// planner's own CSV exports need no mapping.
+func ReadCSV(r io.Reader, entity string, mapping map[string]string) (*Batch, error) {
+	spec, ok := csvFields[entity]
+	if !ok {
+		entities := make([]string, 0, len(csvFields))
+		for name := range csvFields {
+			entities = append(entities, name)
+		}
+		sort.Strings(entities)
+		return nil, fmt.Errorf("entity must be one of %s", strings.Join(entities, ", "))
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Byte order mark from Excel
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[normalizeHeader(name)] = i
+	}
+
+	known := make(map[string]bool, len(spec.fields))
+	for _, field := range spec.fields {
+		known[field] = true
+	}
+	columns := make(map[string]int, len(spec.fields))
+	for field, column := range mapping {
+		if !known[field] {
+			return nil, fmt.Errorf("%s has no field %q; fields are %s", entity, field, strings.Join(spec.fields, ", "))
+		}
+		i, ok := index[normalizeHeader(column)]
+		if !ok {
+			return nil, fmt.Errorf("no column named %q for %s", column, field)
+		}
+		columns[field] = i
+	}
+	for n, field := range spec.fields {
+		if _, ok := columns[field]; ok {
+			continue
+		}
+		if i, ok := index[normalizeHeader(field)]; ok {
+			columns[field] = i
+		} else if n < spec.required {
+			return nil, fmt.Errorf("no column for %s; name one in the mapping", field)
+		}
+	}
+
+	b := &Batch{Format: FormatCSV}
+	for record := 0; ; {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]string, len(columns))
+		blank := true
+		for field, i := range columns {
+			if i < len(fields) {
+				values[field] = strings.TrimSpace(fields[i])
+				blank = blank && values[field] == ""
+			}
+		}
+		if blank {
+			continue
+		}
+		record++
+
+		row := csvRow{batch: b, entity: entity, record: record, values: values}
+		switch entity {
+		case EntityTodos:
+			todo := Todo{
+				Ref:         strconv.Itoa(record),
+				Title:       values["title"],
+				Description: values["description"],
+				Priority:    row.priority("priority"),
+				Completed:   row.bool("completed"),
+				Tags:        splitList(values["tags"]),
+				Project:     values["project"],
+			}
+			if values["due_date"] != "" {
+				due := row.date("due_date")
+				todo.DueDate = &due
+			}
+			b.Todos = append(b.Todos, todo)
+		case EntityPriorities:
+			b.Priorities = append(b.Priorities, Priority{
+				Date:        row.date("date"),
+				Title:       values["title"],
+				Description: values["description"],
+				Completed:   row.bool("completed"),
+			})
+		case EntityContacts:
+			b.Contacts = append(b.Contacts, Contact{
+				Date:        row.date("date"),
+				Name:        values["name"],
+				Type:        contactType(values["type"]),
+				Description: values["description"],
+				Completed:   row.bool("completed"),
+			})
+		case EntityWaterIntake:
+			b.Water = append(b.Water, WaterIntake{Date: row.date("date"), Glasses: row.int("glasses")})
+		case EntityThoughts:
+			b.Thoughts = append(b.Thoughts, Thought{Date: row.date("date"), Content: values["content"]})
+		}
+	}
+
+	return b, nil
+}
+
+// contactType matches a type case-insensitively, so "call" reads as Call
+func contactType(value string) string {
+	for _, t := range models.ContactTypes {
+		if strings.EqualFold(value, t) {
+			return t
+		}
+	}
+	return value
+}
+
+// csvRow reads the values of one row, recording values that don't parse as problems
+type csvRow struct {
+	batch  *Batch
+	entity string
+	record int
+	values map[string]string
+}
+
+func (r csvRow) date(field string) time.Time {
+	if r.values[field] == "" {
+		return time.Time{}
+	}
+	t, err := parseDate(r.values[field])
+	if err != nil {
+		r.batch.problem(r.entity, r.record, field, "%s", err)
+	}
+	return t
+}
+
+func (r csvRow) bool(field string) bool {
+	v, err := parseBool(r.values[field])
+	if err != nil {
+		r.batch.problem(r.entity, r.record, field, "%s", err)
+	}
+	return v
+}
+
+func (r csvRow) priority(field string) int {
+	v, err := parsePriority(r.values[field])
+	if err != nil {
+		r.batch.problem(r.entity, r.record, field, "%s", err)
+	}
+	return v
+}
+
+func (r csvRow) int(field string) int {
+	if r.values[field] == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(r.values[field])
+	if err != nil {
+		r.batch.problem(r.entity, r.record, field, "%q is not a whole number", r.values[field])
+	}
+	return v
+}
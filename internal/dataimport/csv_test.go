@@ -0,0 +1,116 @@
+package dataimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+func TestReadCSV(t *testing.T) {
+	due := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		entity  string
+		mapping map[string]string
+		input   string
+		want    *Batch
+	}{
+		{
+			name:   "planner export needs no mapping",
+			entity: EntityTodos,
+			input: "title,description,due_date,priority,completed,tags,project\n" +
+				"Write report,Quarterly,2025-03-14,P1,true,\"work, urgent\",Reports\n" +
+				"Call plumber,,,,,,\n",
+			want: &Batch{Format: FormatCSV, Todos: []Todo{
+				{Ref: "1", Title: "Write report", Description: "Quarterly", DueDate: &due, Priority: 1, Completed: true, Tags: []string{"work", "urgent"}, Project: "Reports"},
+				{Ref: "2", Title: "Call plumber"},
+			}},
+		},
+		{
+			name:    "mapped columns, loosely named headers and a byte order mark",
+			entity:  EntityTodos,
+			mapping: map[string]string{"title": "Task Name", "completed": "status"},
+			input: "\ufeffTask Name,Due-Date,Status,Notes\n" +
+				"Water plants,2025/03/14,done,ignored\n",
+			want: &Batch{Format: FormatCSV, Todos: []Todo{
+				{Ref: "1", Title: "Water plants", DueDate: &due, Completed: true},
+			}},
+		},
+		{
+			name:   "blank rows are skipped and not counted",
+			entity: EntityWaterIntake,
+			input:  "date,glasses\n\n , \n2025-03-01,6\n",
+			want:   &Batch{Format: FormatCSV, Water: []WaterIntake{{Date: day, Glasses: 6}}},
+		},
+		{
+			name:   "contact types match case-insensitively",
+			entity: EntityContacts,
+			input:  "date,name,type\n2025-03-01T09:30:00Z,Ann,call\n",
+			want: &Batch{Format: FormatCSV, Contacts: []Contact{
+				{Date: day, Name: "Ann", Type: models.ContactTypeCall},
+			}},
+		},
+		{
+			name:   "values that don't parse are problems",
+			entity: EntityTodos,
+			input:  "title,due_date,priority,completed\nA,14/03/2025,P7,maybe\n",
+			want: &Batch{
+				Format: FormatCSV,
+				Todos:  []Todo{{Ref: "1", Title: "A", DueDate: &time.Time{}}},
+				Problems: []Problem{
+					{Entity: EntityTodos, Record: 1, Field: "priority", Message: `"7" is not a priority; use P1 to P4`},
+					{Entity: EntityTodos, Record: 1, Field: "completed", Message: `"maybe" is not yes or no`},
+					{Entity: EntityTodos, Record: 1, Field: "due_date", Message: `"14/03/2025" is not a date; use YYYY-MM-DD`},
+				},
+			},
+		},
+		{
+			name:   "short rows leave the missing fields empty",
+			entity: EntityThoughts,
+			input:  "date,content\n2025-03-01\n",
+			want:   &Batch{Format: FormatCSV, Thoughts: []Thought{{Date: day}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCSV(strings.NewReader(tt.input), tt.entity, tt.mapping)
+			if err != nil {
+				t.Fatalf("ReadCSV() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadCSV() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		entity  string
+		mapping map[string]string
+		input   string
+		want    string
+	}{
+		{"unknown entity", "habits", nil, "name\n", "entity must be one of contacts, priorities, thoughts, todos, water_intake"},
+		{"empty file", EntityTodos, nil, "", "the file is empty"},
+		{"mapping an unknown field", EntityTodos, map[string]string{"owner": "Owner"}, "title,owner\n", `todos has no field "owner"`},
+		{"mapping a missing column", EntityTodos, map[string]string{"title": "Task"}, "title\n", `no column named "Task" for title`},
+		{"no column for a required field", EntityPriorities, nil, "date,description\n", "no column for title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSV(strings.NewReader(tt.input), tt.entity, tt.mapping)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ReadCSV() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,278 @@
+// Package dataimport reads planner data from the planner's own export
+// archive, CSV files, and Todoist and Trello JSON exports, into one shape
+// that can be checked before any of it is saved.
+package dataimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// Source formats
+const (
+	FormatArchive = "archive" // A zip made by "download my data"
+	FormatCSV     = "csv"
+	FormatTodoist = "todoist"
+	FormatTrello  = "trello"
+)
+
+// Entities, as named in problems and CSV imports
+const (
+	EntityTodos       = "todos"
+	EntityPriorities  = "priorities"
+	EntityContacts    = "contacts"
+	EntityWaterIntake = "water_intake"
+	EntityThoughts    = "thoughts"
+)
+
+const dateLayout = "2006-01-02"
+
+// Todo is a todo to create
+type Todo struct {
+	Ref         string // The source's ID for it, which priorities refer to
+	Title       string
+	Description string
+	DueDate     *time.Time // nil when the source gives none
+	Priority    int        // 1 (P1) to 4 (P4); 0 when the source gives none
+	Completed   bool
+	Tags        []string
+	Project     string
+	Subtasks    []Subtask
+}
+
+// Subtask is a checklist item of a todo
+type Subtask struct {
+	Title     string
+	Completed bool
+}
+
+// Priority is one of a day's priorities. A promoted todo refers to the
+// todo by its Ref and takes its title from it.
+type Priority struct {
+	Date        time.Time
+	Title       string
+	Description string
+	Completed   bool
+	TodoRef     string
+}
+
+// Contact is a follow-up reminder
+type Contact struct {
+	Date        time.Time
+	Name        string
+	Type        string
+	Description string
+	Completed   bool
+}
+
+// WaterIntake is a day's count for the water habit
+type WaterIntake struct {
+	Date    time.Time
+	Glasses int
+}
+
+// Thought is a day's thought
+type Thought struct {
+	Date    time.Time
+	Content string
+}
+
+// Batch is everything read from one source
+type Batch struct {
+	Format     string
+	Todos      []Todo
+	Priorities []Priority
+	Contacts   []Contact
+	Water      []WaterIntake
+	Thoughts   []Thought
+	Warnings   []string  // What the source had that is left out
+	Problems   []Problem // Records that can't be imported as they are
+}
+
+// Problem is a record that can't be imported
+type Problem struct {
+	Entity  string `json:"entity"`
+	Record  int    `json:"record"` // 1-based, in the order the source lists them; for CSV, the data row, not counting blank ones
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// problem records a problem, unless the field already has one
+func (b *Batch) problem(entity string, record int, field, format string, args ...interface{}) {
+	for _, p := range b.Problems {
+		if p.Entity == entity && p.Record == record && p.Field == field {
+			return
+		}
+	}
+	b.Problems = append(b.Problems, Problem{Entity: entity, Record: record, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (b *Batch) warn(format string, args ...interface{}) {
+	b.Warnings = append(b.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Validate checks every record and returns the problems found while reading
+// and by the checks. An import with any problem must not be applied.
+func (b *Batch) Validate() []Problem {
+	refs := make(map[string]bool, len(b.Todos))
+	for i, todo := range b.Todos {
+		if strings.TrimSpace(todo.Title) == "" {
+			b.problem(EntityTodos, i+1, "title", "Title is required")
+		}
+		if todo.Priority < 0 || todo.Priority > models.TodoPriorityP4 {
+			b.problem(EntityTodos, i+1, "priority", "Priority must be between 1 and 4")
+		}
+		for _, subtask := range todo.Subtasks {
+			if strings.TrimSpace(subtask.Title) == "" {
+				b.problem(EntityTodos, i+1, "subtasks", "Subtask titles are required")
+				break
+			}
+		}
+		if todo.Ref != "" {
+			refs[todo.Ref] = true
+		}
+	}
+
+	promoted := make(map[string]bool)
+	for i, priority := range b.Priorities {
+		if priority.Date.IsZero() {
+			b.problem(EntityPriorities, i+1, "date", "Date is required")
+		}
+		key := priority.TodoRef + " " + priority.Date.Format(dateLayout)
+		switch {
+		case priority.TodoRef != "" && !refs[priority.TodoRef]:
+			b.problem(EntityPriorities, i+1, "todo", "Refers to todo %s, which is not in the import", priority.TodoRef)
+		case priority.TodoRef != "" && promoted[key]:
+			b.problem(EntityPriorities, i+1, "todo", "Todo %s is already a priority on %s", priority.TodoRef, priority.Date.Format(dateLayout))
+		case priority.TodoRef == "" && strings.TrimSpace(priority.Title) == "":
+			b.problem(EntityPriorities, i+1, "title", "Title is required")
+		}
+		if priority.TodoRef != "" {
+			promoted[key] = true
+		}
+	}
+
+	for i, contact := range b.Contacts {
+		if contact.Date.IsZero() {
+			b.problem(EntityContacts, i+1, "date", "Date is required")
+		}
+		if strings.TrimSpace(contact.Name) == "" {
+			b.problem(EntityContacts, i+1, "name", "Name is required")
+		}
+		if !validContactType(contact.Type) {
+			b.problem(EntityContacts, i+1, "type", "Type must be one of %s", strings.Join(models.ContactTypes, ", "))
+		}
+	}
+
+	days := make(map[string]bool, len(b.Water))
+	for i, day := range b.Water {
+		if day.Date.IsZero() {
+			b.problem(EntityWaterIntake, i+1, "date", "Date is required")
+		} else if key := day.Date.Format(dateLayout); days[key] {
+			b.problem(EntityWaterIntake, i+1, "date", "%s appears more than once", key)
+		} else {
+			days[key] = true
+		}
+		if day.Glasses < 0 {
+			b.problem(EntityWaterIntake, i+1, "glasses", "Glasses can't be negative")
+		}
+	}
+
+	days = make(map[string]bool, len(b.Thoughts))
+	for i, thought := range b.Thoughts {
+		if thought.Date.IsZero() {
+			b.problem(EntityThoughts, i+1, "date", "Date is required")
+		} else if key := thought.Date.Format(dateLayout); days[key] {
+			b.problem(EntityThoughts, i+1, "date", "There is one thought per day; %s appears more than once", key)
+		} else {
+			days[key] = true
+		}
+		if strings.TrimSpace(thought.Content) == "" {
+			b.problem(EntityThoughts, i+1, "content", "Content is required")
+		}
+	}
+
+	return b.Problems
+}
+
+func validContactType(value string) bool {
+	for _, t := range models.ContactTypes {
+		if value == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect guesses a file's format from its content
+func Detect(data []byte) string {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatArchive
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var probe map[string]json.RawMessage
+		if json.Unmarshal(trimmed, &probe) == nil {
+			if _, ok := probe["cards"]; ok {
+				return FormatTrello
+			}
+			if _, ok := probe["items"]; ok {
+				return FormatTodoist
+			}
+		}
+	}
+	return FormatCSV
+}
+
+// parseDate reads a date, or the date part of a timestamp
+func parseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{dateLayout, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006/01/02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date; use YYYY-MM-DD", value)
+}
+
+// parseBool reads the ways spreadsheets and apps write yes and no
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "y", "1", "x", "done", "completed", "complete":
+		return true, nil
+	case "false", "no", "n", "0", "", "open", "todo", "pending":
+		return false, nil
+	}
+	return false, fmt.Errorf("%q is not yes or no", value)
+}
+
+// parsePriority reads P1-P4 or 1-4; empty means none
+func parsePriority(value string) (int, error) {
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "P")
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < models.TodoPriorityP1 || n > models.TodoPriorityP4 {
+		return 0, fmt.Errorf("%q is not a priority; use P1 to P4", value)
+	}
+	return n, nil
+}
+
+// splitList splits a list of names on commas or semicolons
+func splitList(value string) []string {
+	var names []string
+	for _, name := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
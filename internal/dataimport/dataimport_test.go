@@ -0,0 +1,46 @@
+package dataimport
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidatePriorities(t *testing.T) {
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name       string
+		priorities []Priority
+		want       []Problem
+	}{
+		{
+			name: "a todo promoted on two days",
+			priorities: []Priority{
+				{Date: day, TodoRef: "1"},
+				{Date: next, TodoRef: "1"},
+			},
+		},
+		{
+			name: "a todo promoted twice on one day",
+			priorities: []Priority{
+				{Date: day, TodoRef: "1"},
+				{Date: day, Title: "Exercise"},
+				{Date: day, TodoRef: "1"},
+			},
+			want: []Problem{
+				{Entity: EntityPriorities, Record: 3, Field: "todo", Message: "Todo 1 is already a priority on 2025-03-14"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Batch{Todos: []Todo{{Ref: "1", Title: "Write report"}}, Priorities: tt.priorities}
+			if got := b.Validate(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package dataimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// flexID reads an ID written as a string or a number; Todoist changed from
+// one to the other
+type flexID string
+
+func (id *flexID) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = flexID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid ID %s", data)
+	}
+	*id = flexID(n.String())
+	return nil
+}
+
+// flexBool reads true/false as well as the 1/0 older exports use
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	case "false", "0", "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+type todoistExport struct {
+	Items []struct {
+		ID          flexID   `json:"id"`
+		Content     string   `json:"content"`
+		Description string   `json:"description"`
+		Priority    int      `json:"priority"` // 4 is the most urgent, 1 the default
+		Checked     flexBool `json:"checked"`
+		IsDeleted   flexBool `json:"is_deleted"`
+		ProjectID   flexID   `json:"project_id"`
+		ParentID    flexID   `json:"parent_id"`
+		Labels      []flexID `json:"labels"` // Names, or label IDs in older exports
+		Due         *struct {
+			Date        string   `json:"date"`
+			IsRecurring flexBool `json:"is_recurring"`
+		} `json:"due"`
+	} `json:"items"`
+	Projects []struct {
+		ID   flexID `json:"id"`
+		Name string `json:"name"`
+	} `json:"projects"`
+	Labels []struct {
+		ID   flexID `json:"id"`
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// todoistPriority maps Todoist's 4 (urgent) to 1 (normal) onto P1 to P4
+func todoistPriority(priority int) int {
+	if priority < 1 || priority > 4 {
+		return 0
+	}
+	return 5 - priority
+}
+
+// ReadTodoist reads a Todoist JSON export (the sync API's items, projects
+// and labels). Sub-tasks become subtasks of their top-level task; Todoist
+// projects become projects and labels become tags.
+func ReadTodoist(data []byte) (*Batch, error) {
+	var source todoistExport
+	if err := json.Unmarshal(data, &source); err != nil {
+		return nil, fmt.Errorf("invalid Todoist export: %w", err)
+	}
+
+	projects := make(map[flexID]string, len(source.Projects))
+	for _, project := range source.Projects {
+		projects[project.ID] = project.Name
+	}
+	labels := make(map[flexID]string, len(source.Labels))
+	for _, label := range source.Labels {
+		labels[label.ID] = label.Name
+	}
+
+	parents := make(map[flexID]flexID, len(source.Items))
+	for _, item := range source.Items {
+		parents[item.ID] = item.ParentID
+	}
+	// topLevel follows parents up to the task a sub-task belongs to
+	topLevel := func(id flexID) flexID {
+		for i := 0; i < len(parents) && parents[id] != ""; i++ {
+			id = parents[id]
+		}
+		return id
+	}
+
+	b := &Batch{Format: FormatTodoist}
+	index := make(map[flexID]int, len(source.Items))
+	recurring := 0
+
+	for _, item := range source.Items {
+		if item.IsDeleted || item.ParentID != "" {
+			continue
+		}
+		todo := Todo{
+			Ref:         string(item.ID),
+			Title:       item.Content,
+			Description: item.Description,
+			Priority:    todoistPriority(item.Priority),
+			Completed:   bool(item.Checked),
+			Project:     projects[item.ProjectID],
+		}
+		if item.Due != nil && item.Due.Date != "" {
+			due, err := parseDate(item.Due.Date)
+			if err != nil {
+				b.problem(EntityTodos, len(b.Todos)+1, "due_date", "%s", err)
+			}
+			todo.DueDate = &due
+			if item.Due.IsRecurring {
+				recurring++
+			}
+		}
+		for _, label := range item.Labels {
+			name, ok := labels[label]
+			if !ok {
+				name = string(label)
+			}
+			todo.Tags = append(todo.Tags, name)
+		}
+		index[item.ID] = len(b.Todos)
+		b.Todos = append(b.Todos, todo)
+	}
+
+	orphans := 0
+	for _, item := range source.Items {
+		if item.IsDeleted || item.ParentID == "" {
+			continue
+		}
+		i, ok := index[topLevel(item.ID)]
+		if !ok {
+			orphans++
+			continue
+		}
+		b.Todos[i].Subtasks = append(b.Todos[i].Subtasks, Subtask{Title: item.Content, Completed: bool(item.Checked)})
+	}
+
+	if recurring > 0 {
+		b.warn("%d recurring tasks are imported once, on their next due date", recurring)
+	}
+	if orphans > 0 {
+		b.warn("%d sub-tasks whose parent task isn't in the export are left out", orphans)
+	}
+	return b, nil
+}
@@ -0,0 +1,121 @@
+package dataimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type trelloExport struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		Name        string  `json:"name"`
+		Desc        string  `json:"desc"`
+		Due         *string `json:"due"`
+		DueComplete bool    `json:"dueComplete"`
+		Closed      bool    `json:"closed"`
+		IDList      string  `json:"idList"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string `json:"idCard"`
+		CheckItems []struct {
+			Name  string  `json:"name"`
+			State string  `json:"state"` // complete or incomplete
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+// doneList reports whether a list's name says its cards are finished
+func doneList(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "done", "complete", "completed", "finished":
+		return true
+	}
+	return false
+}
+
+// ReadTrello reads a Trello board's JSON export. Cards become todos in a
+// project named after the board, labels become tags and checklist items
+// become subtasks. Cards count as done when their due date is marked
+// complete or they sit in a list called Done. Archived cards and lists are
+// left out.
+func ReadTrello(data []byte) (*Batch, error) {
+	var source trelloExport
+	if err := json.Unmarshal(data, &source); err != nil {
+		return nil, fmt.Errorf("invalid Trello export: %w", err)
+	}
+
+	lists := make(map[string]bool, len(source.Lists)) // ID to whether it is a done list
+	closedLists := make(map[string]bool)
+	for _, list := range source.Lists {
+		lists[list.ID] = doneList(list.Name)
+		if list.Closed {
+			closedLists[list.ID] = true
+		}
+	}
+
+	b := &Batch{Format: FormatTrello}
+	index := make(map[string]int, len(source.Cards))
+	archived := 0
+
+	for _, card := range source.Cards {
+		if card.Closed || closedLists[card.IDList] {
+			archived++
+			continue
+		}
+		todo := Todo{
+			Ref:         card.ID,
+			Title:       card.Name,
+			Description: card.Desc,
+			Completed:   card.DueComplete || lists[card.IDList],
+			Project:     strings.TrimSpace(source.Name),
+		}
+		if card.Due != nil && *card.Due != "" {
+			due, err := parseDate(*card.Due)
+			if err != nil {
+				b.problem(EntityTodos, len(b.Todos)+1, "due_date", "%s", err)
+			}
+			todo.DueDate = &due
+		}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color
+			}
+			if name != "" {
+				todo.Tags = append(todo.Tags, name)
+			}
+		}
+		index[card.ID] = len(b.Todos)
+		b.Todos = append(b.Todos, todo)
+	}
+
+	for _, checklist := range source.Checklists {
+		i, ok := index[checklist.IDCard]
+		if !ok {
+			continue
+		}
+		items := checklist.CheckItems
+		sort.SliceStable(items, func(a, c int) bool { return items[a].Pos < items[c].Pos })
+		for _, item := range items {
+			b.Todos[i].Subtasks = append(b.Todos[i].Subtasks, Subtask{Title: item.Name, Completed: item.State == "complete"})
+		}
+	}
+
+	if archived > 0 {
+		b.warn("%d archived cards are left out", archived)
+	}
+	return b, nil
+}
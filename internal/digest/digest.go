@@ -0,0 +1,248 @@
+// Package digest builds the daily emails: a morning plan of the day and an
+// evening recap of it.
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/mail"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Items are todos, priorities and follow-ups, e.g. the ones done in a day
+type Items struct {
+	Priorities []models.Priority
+	Todos      []models.TodoItem
+	FollowUps  []models.Contact
+}
+
+// Empty reports whether there are no items at all
+func (i Items) Empty() bool {
+	return len(i.Priorities) == 0 && len(i.Todos) == 0 && len(i.FollowUps) == 0
+}
+
+// Count is the number of items
+func (i Items) Count() int {
+	return len(i.Priorities) + len(i.Todos) + len(i.FollowUps)
+}
+
+// Digest is the content of one user's email for a day
+type Digest struct {
+	Kind       string // One of the models.DigestKind constants
+	Name       string
+	Date       time.Time
+	Today      Items             // Morning: the day's priorities, todos due and follow-ups, including overdue ones
+	Overdue    []models.TodoItem // Morning: open todos from earlier days
+	Thought    string            // Morning: the day's thought, if one is set
+	Done       Items             // Evening: what got done
+	Unfinished Items             // Evening: what didn't
+	Tomorrow   []models.Priority // Evening: what is planned next
+
+	PlannerURL     string
+	ReviewURL      string // Evening: where the day is reviewed
+	UnsubscribeURL string
+}
+
+// Morning reports whether this is the morning email
+func (d *Digest) Morning() bool {
+	return d.Kind == models.DigestKindMorning
+}
+
+// Empty reports whether there is nothing worth sending
+func (d *Digest) Empty() bool {
+	if d.Morning() {
+		return d.Today.Empty() && len(d.Overdue) == 0 && d.Thought == ""
+	}
+	return d.Done.Empty() && d.Unfinished.Empty()
+}
+
+// Planned is how many things the evening recap covers, done or not
+func (d *Digest) Planned() int {
+	return d.Done.Count() + d.Unfinished.Count()
+}
+
+// Subject is the email's subject line
+func (d *Digest) Subject() string {
+	if d.Morning() {
+		return "Your plan for " + d.Date.Format("Monday, January 2")
+	}
+	return "Your day in review: " + d.Date.Format("Monday, January 2")
+}
+
+// Load gathers the user's email of the given kind for day. Links point at
+// the site reached on baseURL.
+func Load(db *repository.Database, user *models.User, kind string, day time.Time, baseURL string) (*Digest, error) {
+	d := &Digest{
+		Kind:       kind,
+		Name:       user.Username,
+		Date:       day,
+		PlannerURL: baseURL + "/planner",
+		ReviewURL:  fmt.Sprintf("%s/planner/review?date=%s", baseURL, day.Format("2006-01-02")),
+	}
+	if user.DigestToken != nil {
+		d.UnsubscribeURL = fmt.Sprintf("%s/digest/unsubscribe/%s", baseURL, *user.DigestToken)
+	}
+
+	priorities, err := db.FindPrioritiesByUserIDAndDate(user.ID, day)
+	if err != nil {
+		return nil, err
+	}
+	todos, err := db.FindTodosDueOn(user.ID, day)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case models.DigestKindMorning:
+		if err := d.loadMorning(db, user.ID, priorities, todos); err != nil {
+			return nil, err
+		}
+	case models.DigestKindEvening:
+		if err := d.loadEvening(db, user.ID, priorities, todos); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown digest kind %q", kind)
+	}
+	return d, nil
+}
+
+// loadMorning fills in what is on for the day
+func (d *Digest) loadMorning(db *repository.Database, userID uint, priorities []models.Priority, todos []models.TodoItem) error {
+	promoted := resolvePriorities(priorities)
+	for _, priority := range priorities {
+		if !priority.Completed {
+			d.Today.Priorities = append(d.Today.Priorities, priority)
+		}
+	}
+	for _, todo := range todos {
+		if !todo.Completed && !promoted[todo.ID] {
+			d.Today.Todos = append(d.Today.Todos, todo)
+		}
+	}
+
+	var err error
+	if d.Overdue, err = db.FindOverdueTodos(userID, d.Date); err != nil {
+		return err
+	}
+	if d.Today.FollowUps, err = db.FindPendingContacts(userID, d.Date); err != nil {
+		return err
+	}
+
+	thought, err := db.FindThoughtByUserIDAndDate(userID, d.Date.Format("2006-01-02"))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		d.Thought = thought.Content
+	}
+	return nil
+}
+
+// loadEvening fills in what got done in the day and what is next
+func (d *Digest) loadEvening(db *repository.Database, userID uint, priorities []models.Priority, todos []models.TodoItem) error {
+	promoted := resolvePriorities(priorities)
+	for _, priority := range priorities {
+		if priority.Completed {
+			d.Done.Priorities = append(d.Done.Priorities, priority)
+		} else {
+			d.Unfinished.Priorities = append(d.Unfinished.Priorities, priority)
+		}
+	}
+	for _, todo := range todos {
+		switch {
+		case promoted[todo.ID]:
+		case todo.Completed:
+			d.Done.Todos = append(d.Done.Todos, todo)
+		default:
+			d.Unfinished.Todos = append(d.Unfinished.Todos, todo)
+		}
+	}
+
+	contacts, err := db.FindContactsByUserIDAndDate(userID, d.Date)
+	if err != nil {
+		return err
+	}
+	for _, contact := range contacts {
+		if contact.Completed {
+			d.Done.FollowUps = append(d.Done.FollowUps, contact)
+		} else {
+			d.Unfinished.FollowUps = append(d.Unfinished.FollowUps, contact)
+		}
+	}
+
+	if d.Tomorrow, err = db.FindPrioritiesByUserIDAndDate(userID, d.Date.AddDate(0, 0, 1)); err != nil {
+		return err
+	}
+	resolvePriorities(d.Tomorrow)
+	return nil
+}
+
+// resolvePriorities numbers a day's priorities and takes promoted ones'
+// title and completion from their todo. It returns the promoted todos,
+// which are only listed as priorities.
+func resolvePriorities(priorities []models.Priority) map[uint]bool {
+	promoted := make(map[uint]bool, len(priorities))
+	for i := range priorities {
+		priorities[i].Rank = i + 1
+		if todo := priorities[i].TodoItem; todo != nil {
+			priorities[i].Title = todo.Title
+			priorities[i].Completed = todo.Completed
+			promoted[todo.ID] = true
+		}
+	}
+	return promoted
+}
+
+// Templates render digests as HTML and plain text
+type Templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// LoadTemplates parses digest.html.tmpl and digest.txt.tmpl from dir
+func LoadTemplates(dir string) (*Templates, error) {
+	html, err := htmltemplate.ParseFiles(filepath.Join(dir, "digest.html.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.ParseFiles(filepath.Join(dir, "digest.txt.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{html: html, text: text}, nil
+}
+
+// Compose renders d as an email to the address to, with a header that lets
+// mail apps offer a one-click unsubscribe
+func (t *Templates) Compose(d *Digest, to string) (mail.Message, error) {
+	var html, text bytes.Buffer
+	if err := t.html.Execute(&html, d); err != nil {
+		return mail.Message{}, err
+	}
+	if err := t.text.Execute(&text, d); err != nil {
+		return mail.Message{}, err
+	}
+
+	msg := mail.Message{
+		To:      to,
+		Subject: d.Subject(),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}
+	if d.UnsubscribeURL != "" {
+		msg.Headers = map[string]string{
+			"List-Unsubscribe":      "<" + d.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	return msg, nil
+}
@@ -0,0 +1,267 @@
+// Package export writes a user's planner data as a zip archive of JSON and
+// CSV files, for "download my data", and reads such archives back in.
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// Version is the archive format written into manifest.json
+const Version = 1
+
+const dateFormat = "2006-01-02"
+
+// Manifest describes an archive
+type Manifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Files      []string  `json:"files"`
+}
+
+// Profile is the part of the user's account an archive includes; secrets
+// such as the password hash are left out
+type Profile struct {
+	Username           string    `json:"username"`
+	Email              string    `json:"email"`
+	Timezone           string    `json:"timezone"`
+	MaxDailyPriorities int       `json:"maxDailyPriorities"`
+	OccasionLeadDays   int       `json:"occasionLeadDays"`
+	CreatedAt          time.Time `json:"createdAt"`
+	LastLoginAt        time.Time `json:"lastLoginAt"`
+}
+
+// NewProfile takes the exported fields of a user
+func NewProfile(user *models.User) Profile {
+	return Profile{
+		Username:           user.Username,
+		Email:              user.Email,
+		Timezone:           user.Timezone,
+		MaxDailyPriorities: user.MaxDailyPriorities,
+		OccasionLeadDays:   user.OccasionLeadDays,
+		CreatedAt:          user.CreatedAt,
+		LastLoginAt:        user.LastLoginAt,
+	}
+}
+
+// WaterIntake is one day of the water habit
+type WaterIntake struct {
+	Date    time.Time `json:"date"`
+	Glasses int       `json:"glasses"`
+	Target  int       `json:"target"`
+}
+
+// Archive is everything exported for one user
+type Archive struct {
+	ExportedAt  time.Time
+	Profile     Profile
+	Todos       []models.TodoItem // With tags and subtasks
+	Priorities  []models.Priority
+	Contacts    []models.Contact
+	WaterIntake []WaterIntake
+	Thoughts    []models.Thought
+}
+
+// Write encodes the archive as a zip with a JSON and, for lists, a CSV file per entity
+func Write(w io.Writer, a *Archive) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name  string
+		value interface{}
+		rows  [][]string
+	}{
+		{name: "profile", value: a.Profile},
+		{name: "todos", value: orEmpty(a.Todos), rows: todoRows(a.Todos)},
+		{name: "priorities", value: orEmpty(a.Priorities), rows: priorityRows(a.Priorities, a.Todos)},
+		{name: "contacts", value: orEmpty(a.Contacts), rows: contactRows(a.Contacts)},
+		{name: "water_intake", value: orEmpty(a.WaterIntake), rows: waterRows(a.WaterIntake)},
+		{name: "thoughts", value: orEmpty(a.Thoughts), rows: thoughtRows(a.Thoughts)},
+	}
+
+	manifest := Manifest{Version: Version, ExportedAt: a.ExportedAt}
+	for _, file := range files {
+		manifest.Files = append(manifest.Files, file.name+".json")
+		if file.rows != nil {
+			manifest.Files = append(manifest.Files, file.name+".csv")
+		}
+	}
+	if err := writeJSON(zw, "manifest.json", a.ExportedAt, manifest); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := writeJSON(zw, file.name+".json", a.ExportedAt, file.value); err != nil {
+			return err
+		}
+		if file.rows == nil {
+			continue
+		}
+		if err := writeCSV(zw, file.name+".csv", a.ExportedAt, file.rows); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// orEmpty makes an empty list encode as [] rather than null
+func orEmpty[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+func create(zw *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+}
+
+func writeJSON(zw *zip.Writer, name string, modified time.Time, value interface{}) error {
+	f, err := create(zw, name, modified)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func writeCSV(zw *zip.Writer, name string, modified time.Time, rows [][]string) error {
+	f, err := create(zw, name, modified)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateFormat)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func todoRows(todos []models.TodoItem) [][]string {
+	rows := [][]string{{"id", "title", "description", "due_date", "priority", "completed", "project_id", "tags", "subtasks_done", "subtasks_total", "created_at", "updated_at"}}
+	for _, todo := range todos {
+		tags := make([]string, 0, len(todo.Tags))
+		for _, tag := range todo.Tags {
+			tags = append(tags, tag.Name)
+		}
+		done := 0
+		for _, subtask := range todo.Subtasks {
+			if subtask.Completed {
+				done++
+			}
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(todo.ID), 10),
+			todo.Title,
+			todo.Description,
+			formatDate(todo.DueDate),
+			"P" + strconv.Itoa(todo.PriorityLevel),
+			strconv.FormatBool(todo.Completed),
+			formatID(todo.ProjectID),
+			strings.Join(tags, ";"),
+			strconv.Itoa(done),
+			strconv.Itoa(len(todo.Subtasks)),
+			formatTime(todo.CreatedAt),
+			formatTime(todo.UpdatedAt),
+		})
+	}
+	return rows
+}
+
+// priorityRows fills in the title and completion of promoted todos, which
+// the todo holds rather than the priority
+func priorityRows(priorities []models.Priority, todos []models.TodoItem) [][]string {
+	todosByID := make(map[uint]models.TodoItem, len(todos))
+	for _, todo := range todos {
+		todosByID[todo.ID] = todo
+	}
+
+	rows := [][]string{{"id", "date", "title", "description", "completed", "todo_id", "created_at", "updated_at"}}
+	for _, priority := range priorities {
+		title, description, completed := priority.Title, priority.Description, priority.Completed
+		if priority.TodoItemID != nil {
+			if todo, ok := todosByID[*priority.TodoItemID]; ok {
+				title, description, completed = todo.Title, todo.Description, todo.Completed
+			}
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(priority.ID), 10),
+			formatDate(priority.Date),
+			title,
+			description,
+			strconv.FormatBool(completed),
+			formatID(priority.TodoItemID),
+			formatTime(priority.CreatedAt),
+			formatTime(priority.UpdatedAt),
+		})
+	}
+	return rows
+}
+
+func contactRows(contacts []models.Contact) [][]string {
+	rows := [][]string{{"id", "date", "name", "type", "description", "completed", "person_id", "source", "created_at", "updated_at"}}
+	for _, contact := range contacts {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(contact.ID), 10),
+			formatDate(contact.Date),
+			contact.Name,
+			contact.Type,
+			contact.Description,
+			strconv.FormatBool(contact.Completed),
+			formatID(contact.PersonID),
+			contact.Source,
+			formatTime(contact.CreatedAt),
+			formatTime(contact.UpdatedAt),
+		})
+	}
+	return rows
+}
+
+func waterRows(intake []WaterIntake) [][]string {
+	rows := [][]string{{"date", "glasses", "target"}}
+	for _, day := range intake {
+		rows = append(rows, []string{formatDate(day.Date), strconv.Itoa(day.Glasses), strconv.Itoa(day.Target)})
+	}
+	return rows
+}
+
+func thoughtRows(thoughts []models.Thought) [][]string {
+	rows := [][]string{{"id", "date", "content", "created_at", "updated_at"}}
+	for _, thought := range thoughts {
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(thought.ID), 10),
+			formatDate(thought.Date),
+			thought.Content,
+			formatTime(thought.CreatedAt),
+			formatTime(thought.UpdatedAt),
+		})
+	}
+	return rows
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotArchive is returned for a zip file without an export manifest
+var ErrNotArchive = errors.New("not a Daily Planner export archive")
+
+// maxFileBytes caps how much of any one file in an archive is read
+const maxFileBytes = 100 << 20
+
+// Read decodes an archive written by Write. Files the archive lacks are left empty.
+func Read(data []byte) (*Archive, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var manifest Manifest
+	if err := readJSON(files["manifest.json"], &manifest); err != nil {
+		return nil, ErrNotArchive
+	}
+	if manifest.Version < 1 || manifest.Version > Version {
+		return nil, fmt.Errorf("unsupported archive version %d", manifest.Version)
+	}
+
+	a := &Archive{ExportedAt: manifest.ExportedAt}
+	for name, value := range map[string]interface{}{
+		"profile.json":      &a.Profile,
+		"todos.json":        &a.Todos,
+		"priorities.json":   &a.Priorities,
+		"contacts.json":     &a.Contacts,
+		"water_intake.json": &a.WaterIntake,
+		"thoughts.json":     &a.Thoughts,
+	} {
+		f, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := readJSON(f, value); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return a, nil
+}
+
+func readJSON(f *zip.File, value interface{}) error {
+	if f == nil {
+		return errors.New("missing")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return json.NewDecoder(io.LimitReader(r, maxFileBytes)).Decode(value)
+}
@@ -0,0 +1,198 @@
+// Package ical reads and writes the parts of iCalendar (RFC 5545) that the
+// planner's calendar feed and import use.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Date and time value formats
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+)
+
+// Calendar is a VCALENDAR holding events and tasks
+type Calendar struct {
+	Name     string // Shown by calendar apps as the subscription's name
+	Timezone string // IANA name the calendar is meant for; times are written in UTC
+	Feed     bool   // Written for subscribing, with METHOD and refresh hints; CalDAV resources must not have them
+	Events   []Event
+	Todos    []Todo
+}
+
+// Event is a VEVENT
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time // Exclusive; for all-day events the day after the last day
+	AllDay      bool      // Start and End are dates without a time of day
+	Busy        bool      // Blocks time in free/busy lookups
+	Categories  []string
+	Modified    time.Time
+
+	// Read by Parse only
+	Location     string
+	Status       string        // e.g. CONFIRMED or CANCELLED
+	Attendees    []string      // Display names of the attendees that give one
+	Duration     time.Duration // Set instead of End by some calendars
+	Recurrence   *Recurrence
+	ExDates      []time.Time // Occurrences removed from the recurrence
+	RecurrenceID *time.Time  // Set on an edited occurrence of a recurring event
+}
+
+// Todo is a VTODO
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	Due         *time.Time
+	DueAllDay   bool // Due is a date without a time of day
+	Completed   bool
+	CompletedAt *time.Time
+	Priority    int // 1 (highest) to 9 (lowest), 0 when undefined
+	Categories  []string
+	Modified    time.Time
+
+	// Read by Parse only
+	Start       *time.Time
+	StartAllDay bool
+}
+
+// Write encodes cal as an iCalendar stream
+func Write(w io.Writer, cal Calendar) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now()
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:-//Daily Planner//Daily Planner//EN")
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	if cal.Feed {
+		writeLine(bw, "METHOD:PUBLISH")
+	}
+	if cal.Name != "" {
+		writeLine(bw, "X-WR-CALNAME:"+escape(cal.Name))
+	}
+	if cal.Timezone != "" {
+		writeLine(bw, "X-WR-TIMEZONE:"+escape(cal.Timezone))
+	}
+	if cal.Feed {
+		// Ask subscribers to refresh hourly
+		writeLine(bw, "REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+		writeLine(bw, "X-PUBLISHED-TTL:PT1H")
+	}
+
+	for _, event := range cal.Events {
+		writeLine(bw, "BEGIN:VEVENT")
+		writeLine(bw, "UID:"+escape(event.UID))
+		writeLine(bw, "DTSTAMP:"+stamp(event.Modified, now))
+		if event.AllDay {
+			writeLine(bw, "DTSTART;VALUE=DATE:"+event.Start.Format(dateFormat))
+			writeLine(bw, "DTEND;VALUE=DATE:"+event.End.Format(dateFormat))
+		} else {
+			writeLine(bw, "DTSTART:"+event.Start.UTC().Format(dateTimeFormat))
+			writeLine(bw, "DTEND:"+event.End.UTC().Format(dateTimeFormat))
+		}
+		writeLine(bw, "SUMMARY:"+escape(event.Summary))
+		writeText(bw, "DESCRIPTION", event.Description)
+		writeCategories(bw, event.Categories)
+		writeModified(bw, event.Modified)
+		if event.Busy {
+			writeLine(bw, "TRANSP:OPAQUE")
+		} else {
+			writeLine(bw, "TRANSP:TRANSPARENT")
+		}
+		writeLine(bw, "END:VEVENT")
+	}
+
+	for _, todo := range cal.Todos {
+		writeLine(bw, "BEGIN:VTODO")
+		writeLine(bw, "UID:"+escape(todo.UID))
+		writeLine(bw, "DTSTAMP:"+stamp(todo.Modified, now))
+		writeLine(bw, "SUMMARY:"+escape(todo.Summary))
+		writeText(bw, "DESCRIPTION", todo.Description)
+		if todo.Due != nil {
+			if todo.DueAllDay {
+				writeLine(bw, "DUE;VALUE=DATE:"+todo.Due.Format(dateFormat))
+			} else {
+				writeLine(bw, "DUE:"+todo.Due.UTC().Format(dateTimeFormat))
+			}
+		}
+		if todo.Priority > 0 {
+			writeLine(bw, "PRIORITY:"+strconv.Itoa(todo.Priority))
+		}
+		if todo.Completed {
+			writeLine(bw, "STATUS:COMPLETED")
+			if todo.CompletedAt != nil {
+				writeLine(bw, "COMPLETED:"+todo.CompletedAt.UTC().Format(dateTimeFormat))
+			}
+		} else {
+			writeLine(bw, "STATUS:NEEDS-ACTION")
+		}
+		writeCategories(bw, todo.Categories)
+		writeModified(bw, todo.Modified)
+		writeLine(bw, "END:VTODO")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// stamp formats DTSTAMP, which is required, from the last change or now
+func stamp(modified, now time.Time) string {
+	if modified.IsZero() {
+		modified = now
+	}
+	return modified.UTC().Format(dateTimeFormat)
+}
+
+func writeText(w *bufio.Writer, name, value string) {
+	if value != "" {
+		writeLine(w, name+":"+escape(value))
+	}
+}
+
+func writeCategories(w *bufio.Writer, categories []string) {
+	if len(categories) == 0 {
+		return
+	}
+	escaped := make([]string, len(categories))
+	for i, c := range categories {
+		escaped[i] = escape(c)
+	}
+	writeLine(w, "CATEGORIES:"+strings.Join(escaped, ","))
+}
+
+func writeModified(w *bufio.Writer, modified time.Time) {
+	if !modified.IsZero() {
+		writeLine(w, "LAST-MODIFIED:"+modified.UTC().Format(dateTimeFormat))
+	}
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeLine writes a content line folded at 75 octets without splitting UTF-8 sequences
+func writeLine(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
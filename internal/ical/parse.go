@@ -0,0 +1,498 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoComponents is returned when the input holds no VEVENT or VTODO
+var ErrNoComponents = errors.New("no events or tasks found")
+
+// Statuses the importer acts on
+const (
+	StatusCancelled = "CANCELLED"
+	StatusCompleted = "COMPLETED"
+)
+
+// windowsZones maps the Windows time zone names Outlook writes as TZIDs to
+// IANA names
+var windowsZones = map[string]string{
+	"UTC":                             "UTC",
+	"GMT Standard Time":               "Europe/London",
+	"Greenwich Standard Time":         "Atlantic/Reykjavik",
+	"W. Europe Standard Time":         "Europe/Berlin",
+	"Central Europe Standard Time":    "Europe/Budapest",
+	"Romance Standard Time":           "Europe/Paris",
+	"Central European Standard Time":  "Europe/Warsaw",
+	"E. Europe Standard Time":         "Europe/Chisinau",
+	"FLE Standard Time":               "Europe/Kiev",
+	"GTB Standard Time":               "Europe/Bucharest",
+	"Russian Standard Time":           "Europe/Moscow",
+	"India Standard Time":             "Asia/Kolkata",
+	"China Standard Time":             "Asia/Shanghai",
+	"Tokyo Standard Time":             "Asia/Tokyo",
+	"Singapore Standard Time":         "Asia/Singapore",
+	"AUS Eastern Standard Time":       "Australia/Sydney",
+	"New Zealand Standard Time":       "Pacific/Auckland",
+	"Eastern Standard Time":           "America/New_York",
+	"Central Standard Time":           "America/Chicago",
+	"Mountain Standard Time":          "America/Denver",
+	"US Mountain Standard Time":       "America/Phoenix",
+	"Pacific Standard Time":           "America/Los_Angeles",
+	"Alaskan Standard Time":           "America/Anchorage",
+	"Hawaiian Standard Time":          "Pacific/Honolulu",
+	"Atlantic Standard Time":          "America/Halifax",
+	"E. South America Standard Time":  "America/Sao_Paulo",
+	"SA Pacific Standard Time":        "America/Bogota",
+	"South Africa Standard Time":      "Africa/Johannesburg",
+	"Arabian Standard Time":           "Asia/Dubai",
+	"Israel Standard Time":            "Asia/Jerusalem",
+	"Turkey Standard Time":            "Europe/Istanbul",
+	"Korea Standard Time":             "Asia/Seoul",
+	"SE Asia Standard Time":           "Asia/Bangkok",
+	"Cen. Australia Standard Time":    "Australia/Adelaide",
+	"W. Australia Standard Time":      "Australia/Perth",
+	"Canada Central Standard Time":    "America/Regina",
+	"Central America Standard Time":   "America/Guatemala",
+	"Argentina Standard Time":         "America/Argentina/Buenos_Aires",
+	"Pacific SA Standard Time":        "America/Santiago",
+	"Egypt Standard Time":             "Africa/Cairo",
+	"W. Central Africa Standard Time": "Africa/Lagos",
+}
+
+// contentLine is one unfolded content line
+type contentLine struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+func (l contentLine) param(key string) string {
+	if values := l.params[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Parse reads the events and tasks in r. Times without a time zone, or with
+// one that is not recognised, are read in loc.
+func Parse(r io.Reader, loc *time.Location) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	var stack []string // Open components, innermost last
+	var event *Event
+	var todo *Todo
+	found := false
+
+	for i, raw := range lines {
+		l, err := parseContentLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch l.name {
+		case "BEGIN":
+			name := strings.ToUpper(l.value)
+			stack = append(stack, name)
+			switch {
+			case name == "VEVENT" && len(stack) <= 2:
+				event = &Event{}
+				found = true
+			case name == "VTODO" && len(stack) <= 2:
+				todo = &Todo{}
+				found = true
+			}
+			continue
+		case "END":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: END without BEGIN", i+1)
+			}
+			name := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			switch {
+			case name == "VEVENT" && event != nil:
+				cal.Events = append(cal.Events, *event)
+				event = nil
+			case name == "VTODO" && todo != nil:
+				cal.Todos = append(cal.Todos, *todo)
+				todo = nil
+			}
+			continue
+		case "X-WR-CALNAME":
+			if len(stack) == 1 {
+				cal.Name = unescape(l.value)
+			}
+			continue
+		case "X-WR-TIMEZONE":
+			if len(stack) == 1 {
+				cal.Timezone = l.value
+			}
+			continue
+		}
+
+		// Skip properties of nested components such as VALARM
+		if len(stack) == 0 || (stack[len(stack)-1] != "VEVENT" && stack[len(stack)-1] != "VTODO") {
+			continue
+		}
+
+		switch {
+		case event != nil:
+			if err := event.set(l, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		case todo != nil:
+			if err := todo.set(l, loc); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+
+	if !found {
+		return nil, ErrNoComponents
+	}
+	return cal, nil
+}
+
+func (e *Event) set(l contentLine, loc *time.Location) error {
+	switch l.name {
+	case "UID":
+		e.UID = strings.TrimSpace(unescape(l.value))
+	case "SUMMARY":
+		e.Summary = unescape(l.value)
+	case "DESCRIPTION":
+		e.Description = unescape(l.value)
+	case "LOCATION":
+		e.Location = unescape(l.value)
+	case "STATUS":
+		e.Status = strings.ToUpper(strings.TrimSpace(l.value))
+	case "CATEGORIES":
+		e.Categories = append(e.Categories, splitList(l.value)...)
+	case "ATTENDEE":
+		if name := l.param("CN"); name != "" {
+			e.Attendees = append(e.Attendees, name)
+		}
+	case "DTSTART":
+		start, allDay, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = start, allDay
+	case "DTEND":
+		end, _, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		e.End = end
+	case "DURATION":
+		d, err := parseDuration(l.value)
+		if err != nil {
+			return err
+		}
+		e.Duration = d
+	case "RRULE":
+		rule, err := ParseRecurrence(l.value, loc)
+		if err != nil {
+			return err
+		}
+		e.Recurrence = rule
+	case "EXDATE":
+		for _, value := range strings.Split(l.value, ",") {
+			l.value = value
+			t, _, err := parseTime(l, loc)
+			if err != nil {
+				return err
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		t, _, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		e.RecurrenceID = &t
+	case "LAST-MODIFIED":
+		if t, _, err := parseTime(l, loc); err == nil {
+			e.Modified = t
+		}
+	}
+	return nil
+}
+
+func (t *Todo) set(l contentLine, loc *time.Location) error {
+	switch l.name {
+	case "UID":
+		t.UID = strings.TrimSpace(unescape(l.value))
+	case "SUMMARY":
+		t.Summary = unescape(l.value)
+	case "DESCRIPTION":
+		t.Description = unescape(l.value)
+	case "CATEGORIES":
+		t.Categories = append(t.Categories, splitList(l.value)...)
+	case "STATUS":
+		t.Completed = strings.EqualFold(strings.TrimSpace(l.value), StatusCompleted)
+	case "COMPLETED":
+		completed, _, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		t.Completed = true
+		t.CompletedAt = &completed
+	case "PRIORITY":
+		if p, err := strconv.Atoi(strings.TrimSpace(l.value)); err == nil && p >= 0 && p <= 9 {
+			t.Priority = p
+		}
+	case "DUE":
+		due, allDay, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		t.Due, t.DueAllDay = &due, allDay
+	case "DTSTART":
+		start, allDay, err := parseTime(l, loc)
+		if err != nil {
+			return err
+		}
+		t.Start, t.StartAllDay = &start, allDay
+	case "LAST-MODIFIED":
+		if modified, _, err := parseTime(l, loc); err == nil {
+			t.Modified = modified
+		}
+	}
+	return nil
+}
+
+// EndTime returns when the event ends, from DTEND or DURATION. Events with
+// neither last a day when they are all-day and no time otherwise.
+func (e Event) EndTime() time.Time {
+	switch {
+	case !e.End.IsZero():
+		return e.End
+	case e.Duration != 0:
+		return e.Start.Add(e.Duration)
+	case e.AllDay:
+		return e.Start.AddDate(0, 0, 1)
+	}
+	return e.Start
+}
+
+// location resolves a TZID, including the Windows names Outlook uses
+func location(tzid string, fallback *time.Location) *time.Location {
+	tzid = strings.TrimPrefix(strings.Trim(tzid, `"`), "/")
+	if tzid == "" {
+		return fallback
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		tzid = name
+	}
+	loc, err := time.LoadLocation(tzid)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// parseTime reads a DATE or DATE-TIME value; dates are returned as midnight UTC
+func parseTime(l contentLine, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(l.value)
+	if strings.EqualFold(l.param("VALUE"), "DATE") || len(value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid date-time %q", value)
+		}
+		return t, false, nil
+	}
+
+	t, err := time.ParseInLocation("20060102T150405", value, location(l.param("TZID"), loc))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date-time %q", value)
+	}
+	return t, false, nil
+}
+
+// parseDuration reads an RFC 5545 duration such as P1D, PT1H30M or P2W
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign = -1
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+	if !strings.HasPrefix(value, "P") || len(value) < 3 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	var d time.Duration
+	inTime := false
+	number := 0
+	digits := false
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			number = number*10 + int(r-'0')
+			digits = true
+			continue
+		case r == 'T':
+			inTime = true
+			continue
+		}
+		if !digits {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		unit := time.Duration(number)
+		switch {
+		case r == 'W' && !inTime:
+			d += unit * 7 * 24 * time.Hour
+		case r == 'D' && !inTime:
+			d += unit * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += unit * time.Hour
+		case r == 'M' && inTime:
+			d += unit * time.Minute
+		case r == 'S' && inTime:
+			d += unit * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number, digits = 0, false
+	}
+	if digits {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * d, nil
+}
+
+// unfold joins folded continuation lines and drops blank ones
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			lines[len(lines)-1] += text[1:]
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return lines, scanner.Err()
+}
+
+func parseContentLine(raw string) (contentLine, error) {
+	colon := indexOutsideQuotes(raw, ':')
+	if colon < 0 {
+		return contentLine{}, errors.New("missing ':'")
+	}
+
+	parts := splitOutsideQuotes(raw[:colon], ';')
+	params := make(map[string][]string)
+	for _, p := range parts[1:] {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		for _, v := range splitOutsideQuotes(value, ',') {
+			params[key] = append(params[key], strings.Trim(v, `"`))
+		}
+	}
+
+	return contentLine{name: strings.ToUpper(parts[0]), params: params, value: raw[colon+1:]}, nil
+}
+
+func indexOutsideQuotes(s string, sep byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexOutsideQuotes(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// splitList splits a comma-separated text value, ignoring escaped commas, and unescapes each item
+func splitList(s string) []string {
+	var items []string
+	var b strings.Builder
+	flush := func() {
+		if item := strings.TrimSpace(unescape(b.String())); item != "" {
+			items = append(items, item)
+		}
+		b.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteByte(s[i])
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == ',':
+			flush()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	flush()
+	return items
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
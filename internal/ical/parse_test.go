@@ -0,0 +1,199 @@
+package ical
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar wraps content lines in a VCALENDAR with CRLF line endings
+func calendar(lines ...string) string {
+	return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "VERSION:2.0"}, lines...), "END:VCALENDAR"), "\r\n") + "\r\n"
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time zone data is not available:", err)
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+
+	input := calendar(
+		"X-WR-CALNAME:Work",
+		"X-WR-TIMEZONE:Europe/Berlin",
+		"BEGIN:VEVENT",
+		"UID:standup@example.com",
+		"SUMMARY:Stand-up\\, daily",
+		"DESCRIPTION:Line one\\nLine two",
+		"LOCATION:Room 4",
+		"DTSTART;TZID=W. Europe Standard Time:20250106T093000",
+		"DURATION:PT15M",
+		"CATEGORIES:Work,Team\\,Core",
+		"ATTENDEE;CN=\"Ann Lee\";ROLE=REQ-PARTICIPANT:mailto:ann@example.com",
+		"ATTENDEE:mailto:nobody@example.com",
+		"BEGIN:VALARM",
+		"ACTION:DISPLAY",
+		"DESCRIPTION:Reminder",
+		"TRIGGER:-PT5M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:holiday@example.com",
+		"SUMMARY:Holiday",
+		"DTSTART;VALUE=DATE:20250120",
+		"DTEND;VALUE=DATE:20250122",
+		"STATUS:cancelled",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:call@example.com",
+		"SUMMARY:Call",
+		"DTSTART:20250107T150000",
+		"DTEND:20250107T153000",
+		"LAST-MODIFIED:20250101T120000Z",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:report@example.com",
+		"SUMMARY:File the re",
+		" port",
+		"DUE;VALUE=DATE:20250110",
+		"PRIORITY:2",
+		"STATUS:COMPLETED",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"SUMMARY:Book flights",
+		"DTSTART;TZID=America/New_York:20250108T080000",
+		"COMPLETED:20250108T140000Z",
+		"PRIORITY:12",
+		"END:VTODO",
+	)
+
+	cal, err := Parse(strings.NewReader(input), newYork)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if cal.Name != "Work" || cal.Timezone != "Europe/Berlin" {
+		t.Errorf("calendar = %q in %q, want Work in Europe/Berlin", cal.Name, cal.Timezone)
+	}
+
+	completedAt := time.Date(2025, 1, 8, 14, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 1, 8, 8, 0, 0, 0, newYork)
+
+	wantEvents := []Event{
+		{
+			UID:         "standup@example.com",
+			Summary:     "Stand-up, daily",
+			Description: "Line one\nLine two",
+			Location:    "Room 4",
+			Start:       time.Date(2025, 1, 6, 9, 30, 0, 0, berlin),
+			Duration:    15 * time.Minute,
+			Categories:  []string{"Work", "Team,Core"},
+			Attendees:   []string{"Ann Lee"},
+		},
+		{
+			UID:     "holiday@example.com",
+			Summary: "Holiday",
+			Start:   time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC),
+			End:     time.Date(2025, 1, 22, 0, 0, 0, 0, time.UTC),
+			AllDay:  true,
+			Status:  StatusCancelled,
+		},
+		{
+			UID:      "call@example.com",
+			Summary:  "Call",
+			Start:    time.Date(2025, 1, 7, 15, 0, 0, 0, newYork),
+			End:      time.Date(2025, 1, 7, 15, 30, 0, 0, newYork),
+			Modified: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	wantTodos := []Todo{
+		{
+			UID:       "report@example.com",
+			Summary:   "File the report",
+			Due:       &due,
+			DueAllDay: true,
+			Completed: true,
+			Priority:  2,
+		},
+		{
+			Summary:     "Book flights",
+			Start:       &start,
+			Completed:   true,
+			CompletedAt: &completedAt,
+		},
+	}
+
+	if len(cal.Events) != len(wantEvents) {
+		t.Fatalf("got %d events, want %d", len(cal.Events), len(wantEvents))
+	}
+	for i, want := range wantEvents {
+		got := cal.Events[i]
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || !got.Modified.Equal(want.Modified) {
+			t.Errorf("event %d times = %s to %s modified %s, want %s to %s modified %s", i, got.Start, got.End, got.Modified, want.Start, want.End, want.Modified)
+		}
+		got.Start, got.End, got.Modified = want.Start, want.End, want.Modified
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("event %d = %+v, want %+v", i, got, want)
+		}
+	}
+	if !reflect.DeepEqual(cal.Todos, wantTodos) {
+		t.Errorf("todos = %+v, want %+v", cal.Todos, wantTodos)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{"no components", calendar("X-WR-CALNAME:Empty"), ErrNoComponents},
+		{"END without BEGIN", "END:VCALENDAR\r\n", nil},
+		{"line without a colon", calendar("BEGIN:VEVENT", "SUMMARY Lunch", "END:VEVENT"), nil},
+		{"invalid DTSTART", calendar("BEGIN:VEVENT", "DTSTART:tomorrow", "END:VEVENT"), nil},
+		{"invalid DURATION", calendar("BEGIN:VEVENT", "DURATION:1H", "END:VEVENT"), nil},
+		{"RRULE without FREQ", calendar("BEGIN:VEVENT", "RRULE:COUNT=3", "END:VEVENT"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input), time.UTC)
+			if err == nil {
+				t.Fatal("Parse() succeeded, want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"PT1H30M", 90 * time.Minute, true},
+		{"P1D", 24 * time.Hour, true},
+		{"P2W", 14 * 24 * time.Hour, true},
+		{"P1DT12H", 36 * time.Hour, true},
+		{"-PT15M", -15 * time.Minute, true},
+		{"PT", 0, false},
+		{"P1H", 0, false},
+		{"PT5", 0, false},
+		{"1D", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseDuration(tt.value)
+			if (err == nil) != tt.ok || got != tt.want {
+				t.Errorf("parseDuration(%q) = %v, %v, want %v, ok %v", tt.value, got, err, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies the planner expands
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+	FreqYearly  = "YEARLY"
+)
+
+// maxOccurrences bounds how many occurrences one event expands to
+const maxOccurrences = 1000
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR
+type WeekdayNum struct {
+	N   int // Which occurrence in the month; 0 for every one
+	Day time.Weekday
+}
+
+// Recurrence is a parsed RRULE
+type Recurrence struct {
+	Freq       string
+	Interval   int
+	Count      int // 0 when the count does not limit the rule
+	Until      *time.Time
+	UntilDate  bool // Until is a date and includes the whole day
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+
+	unsupported []string // Rule parts the expansion ignores
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRecurrence reads an RRULE value. Times in UNTIL without a zone are read in loc.
+func ParseRecurrence(value string, loc *time.Location) (*Recurrence, error) {
+	rule := &Recurrence{Interval: 1, WeekStart: time.Monday}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToUpper(key)
+		val = strings.ToUpper(val)
+
+		switch key {
+		case "FREQ":
+			rule.Freq = val
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, isDate, err := parseTime(contentLine{value: val}, loc)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until, rule.UntilDate = &until, isDate
+		case "BYDAY":
+			for _, v := range strings.Split(val, ",") {
+				if len(v) < 2 {
+					return nil, fmt.Errorf("invalid BYDAY %q", v)
+				}
+				day, ok := weekdays[v[len(v)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q", v)
+				}
+				n := 0
+				if prefix := v[:len(v)-2]; prefix != "" {
+					var err error
+					if n, err = strconv.Atoi(prefix); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY %q", v)
+					}
+				}
+				rule.ByDay = append(rule.ByDay, WeekdayNum{N: n, Day: day})
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("invalid BYMONTH %q", v)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			day, ok := weekdays[val]
+			if !ok {
+				return nil, fmt.Errorf("invalid WKST %q", val)
+			}
+			rule.WeekStart = day
+		default:
+			rule.unsupported = append(rule.unsupported, key)
+		}
+	}
+
+	switch rule.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	case "":
+		return nil, fmt.Errorf("RRULE %q has no FREQ", value)
+	default:
+		rule.unsupported = append(rule.unsupported, "FREQ="+rule.Freq)
+	}
+	return rule, nil
+}
+
+// Unsupported lists the rule parts the expansion ignores, e.g. BYSETPOS or
+// FREQ=HOURLY. A rule with an unsupported FREQ only yields its first occurrence.
+func (r *Recurrence) Unsupported() []string {
+	return r.unsupported
+}
+
+// Occurrences returns the start times of the event's occurrences that begin
+// in [from, to), skipping EXDATEs. An event without a recurrence rule has at
+// most one occurrence.
+func (e Event) Occurrences(from, to time.Time) []time.Time {
+	var starts []time.Time
+	add := func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) && !e.excluded(t) {
+			starts = append(starts, t)
+		}
+		return len(starts) < maxOccurrences
+	}
+
+	if e.Recurrence == nil {
+		add(e.Start)
+		return starts
+	}
+	e.Recurrence.expand(e.Start, add)
+	return starts
+}
+
+func (e Event) excluded(t time.Time) bool {
+	for _, ex := range e.ExDates {
+		if ex.Equal(t) || (e.AllDay && sameDate(ex, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// expand calls emit with each occurrence in order, starting with start,
+// until emit returns false or the rule ends
+func (r *Recurrence) expand(start time.Time, emit func(time.Time) bool) {
+	n := 0
+	ended := func(t time.Time) bool {
+		if r.Count > 0 && n >= r.Count {
+			return true
+		}
+		if r.Until != nil {
+			if r.UntilDate {
+				return t.Format(dateFormat) > r.Until.Format(dateFormat)
+			}
+			return t.After(*r.Until)
+		}
+		return false
+	}
+
+	// DTSTART is always the first occurrence
+	if ended(start) {
+		return
+	}
+	n++
+	if !emit(start) {
+		return
+	}
+
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+	default:
+		return
+	}
+
+	// Stop eventually even if no period produces an occurrence
+	for period := 0; period < 100000; period++ {
+		candidates := r.candidates(start, period)
+		for _, t := range candidates {
+			if !t.After(start) {
+				continue
+			}
+			if ended(t) {
+				return
+			}
+			n++
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the occurrences in the period'th interval of the rule,
+// sorted, at the start's time of day
+func (r *Recurrence) candidates(start time.Time, period int) []time.Time {
+	loc := start.Location()
+	hour, minute, second := start.Clock()
+	at := func(year int, month time.Month, day int) (time.Time, bool) {
+		if day < 1 || day > daysIn(year, month) {
+			return time.Time{}, false
+		}
+		return time.Date(year, month, day, hour, minute, second, 0, loc), true
+	}
+
+	var dates []time.Time
+	step := period * r.Interval
+
+	switch r.Freq {
+	case FreqDaily:
+		day := start.AddDate(0, 0, step)
+		if r.matchesMonth(day.Month()) && r.matchesMonthDay(day) && r.matchesWeekday(day.Weekday()) {
+			dates = append(dates, day)
+		}
+
+	case FreqWeekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := start.AddDate(0, 0, step*7-offset)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchesWeekday(day.Weekday()) && r.matchesMonth(day.Month()) {
+				if t, ok := at(day.Date()); ok {
+					dates = append(dates, t)
+				}
+			}
+		}
+
+	case FreqMonthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if r.matchesMonth(first.Month()) {
+			for _, day := range r.daysInMonth(first.Year(), first.Month(), start.Day()) {
+				if t, ok := at(first.Year(), first.Month(), day); ok {
+					dates = append(dates, t)
+				}
+			}
+		}
+
+	case FreqYearly:
+		year := start.Year() + step
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, month := range months {
+			for _, day := range r.daysInMonth(year, month, start.Day()) {
+				if t, ok := at(year, month, day); ok {
+					dates = append(dates, t)
+				}
+			}
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// daysInMonth returns the days of the month a MONTHLY or YEARLY rule picks,
+// defaulting to the start's day of the month
+func (r *Recurrence) daysInMonth(year int, month time.Month, startDay int) []int {
+	last := daysIn(year, month)
+
+	if len(r.ByMonthDay) > 0 {
+		var days []int
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + 1 + d
+			}
+			if d >= 1 && d <= last && r.matchesWeekday(time.Date(year, month, d, 0, 0, 0, 0, time.UTC).Weekday()) {
+				days = append(days, d)
+			}
+		}
+		return days
+	}
+
+	if len(r.ByDay) > 0 {
+		var days []int
+		for d := 1; d <= last; d++ {
+			weekday := time.Date(year, month, d, 0, 0, 0, 0, time.UTC).Weekday()
+			for _, byDay := range r.ByDay {
+				if byDay.Day != weekday {
+					continue
+				}
+				nth := (d-1)/7 + 1           // 1 for the first such weekday of the month
+				nthLast := -((last-d)/7 + 1) // -1 for the last
+				if byDay.N == 0 || byDay.N == nth || byDay.N == nthLast {
+					days = append(days, d)
+					break
+				}
+			}
+		}
+		return days
+	}
+
+	return []int{startDay}
+}
+
+func (r *Recurrence) matchesMonth(month time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, m := range r.ByMonth {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recurrence) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(day.Year(), day.Month())
+	for _, d := range r.ByMonthDay {
+		if d == day.Day() || last+1+d == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday ignores BYDAY ordinals, which only apply to MONTHLY and YEARLY rules
+func (r *Recurrence) matchesWeekday(weekday time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, d := range r.ByDay {
+		if d.Day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
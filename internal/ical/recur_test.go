@@ -0,0 +1,273 @@
+package ical
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// parseEvents reads the VEVENTs of a calendar built from lines
+func parseEvents(t *testing.T, loc *time.Location, lines ...string) []Event {
+	t.Helper()
+	cal, err := Parse(strings.NewReader(calendar(lines...)), loc)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return cal.Events
+}
+
+// formatTimes writes times in UTC so that expectations read the same in any zone
+func formatTimes(times []time.Time) []string {
+	formatted := make([]string, len(times))
+	for i, t := range times {
+		formatted[i] = t.UTC().Format(time.RFC3339)
+	}
+	return formatted
+}
+
+func TestOccurrences(t *testing.T) {
+	if _, err := time.LoadLocation("Europe/London"); err != nil {
+		t.Skip("time zone data is not available:", err)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2034, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		lines    []string
+		from, to time.Time
+		want     []string
+	}{
+		{
+			name:  "single event",
+			lines: []string{"DTSTART:20250101T090000Z"},
+			want:  []string{"2025-01-01T09:00:00Z"},
+		},
+		{
+			name:  "single event outside the window",
+			lines: []string{"DTSTART:20250101T090000Z"},
+			from:  time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "daily with a count",
+			lines: []string{"DTSTART:20250101T090000Z", "RRULE:FREQ=DAILY;COUNT=3"},
+			want:  []string{"2025-01-01T09:00:00Z", "2025-01-02T09:00:00Z", "2025-01-03T09:00:00Z"},
+		},
+		{
+			name:  "every other day until a time",
+			lines: []string{"DTSTART:20250101T090000Z", "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20250107T090000Z"},
+			want:  []string{"2025-01-01T09:00:00Z", "2025-01-03T09:00:00Z", "2025-01-05T09:00:00Z", "2025-01-07T09:00:00Z"},
+		},
+		{
+			name:  "until a date includes that day",
+			lines: []string{"DTSTART:20250101T090000Z", "RRULE:FREQ=DAILY;UNTIL=20250102"},
+			want:  []string{"2025-01-01T09:00:00Z", "2025-01-02T09:00:00Z"},
+		},
+		{
+			name:  "weekly on several days",
+			lines: []string{"DTSTART:20250106T090000Z", "RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5"},
+			want: []string{
+				"2025-01-06T09:00:00Z", "2025-01-08T09:00:00Z", "2025-01-10T09:00:00Z",
+				"2025-01-13T09:00:00Z", "2025-01-15T09:00:00Z",
+			},
+		},
+		{
+			name:  "monthly on the last day",
+			lines: []string{"DTSTART:20250131T090000Z", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3"},
+			want:  []string{"2025-01-31T09:00:00Z", "2025-02-28T09:00:00Z", "2025-03-31T09:00:00Z"},
+		},
+		{
+			name:  "monthly on the 31st skips shorter months",
+			lines: []string{"DTSTART:20250131T090000Z", "RRULE:FREQ=MONTHLY;COUNT=3"},
+			want:  []string{"2025-01-31T09:00:00Z", "2025-03-31T09:00:00Z", "2025-05-31T09:00:00Z"},
+		},
+		{
+			name:  "monthly on the second Tuesday",
+			lines: []string{"DTSTART:20250114T090000Z", "RRULE:FREQ=MONTHLY;BYDAY=2TU;COUNT=3"},
+			want:  []string{"2025-01-14T09:00:00Z", "2025-02-11T09:00:00Z", "2025-03-11T09:00:00Z"},
+		},
+		{
+			name:  "yearly on 29 February only in leap years",
+			lines: []string{"DTSTART;VALUE=DATE:20240229", "RRULE:FREQ=YEARLY"},
+			want:  []string{"2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z", "2032-02-29T00:00:00Z"},
+		},
+		{
+			name:  "local time is kept across a daylight saving change",
+			lines: []string{"DTSTART;TZID=Europe/London:20250329T090000", "RRULE:FREQ=DAILY;COUNT=2"},
+			want:  []string{"2025-03-29T09:00:00Z", "2025-03-30T08:00:00Z"},
+		},
+		{
+			name: "EXDATE removes an occurrence but still counts it",
+			lines: []string{
+				"DTSTART:20250101T090000Z",
+				"RRULE:FREQ=DAILY;COUNT=4",
+				"EXDATE:20250102T090000Z",
+			},
+			want: []string{"2025-01-01T09:00:00Z", "2025-01-03T09:00:00Z", "2025-01-04T09:00:00Z"},
+		},
+		{
+			name: "EXDATE in the event's time zone",
+			lines: []string{
+				"DTSTART;TZID=Europe/London:20250701T090000",
+				"RRULE:FREQ=DAILY;COUNT=3",
+				"EXDATE;TZID=Europe/London:20250701T090000,20250703T090000",
+			},
+			want: []string{"2025-07-02T08:00:00Z"},
+		},
+		{
+			name: "all-day EXDATE",
+			lines: []string{
+				"DTSTART;VALUE=DATE:20250101",
+				"RRULE:FREQ=DAILY;COUNT=3",
+				"EXDATE;VALUE=DATE:20250102",
+			},
+			want: []string{"2025-01-01T00:00:00Z", "2025-01-03T00:00:00Z"},
+		},
+		{
+			name:  "an endless rule is cut at the window",
+			lines: []string{"DTSTART:20250101T090000Z", "RRULE:FREQ=DAILY"},
+			from:  time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC),
+			to:    time.Date(2025, 1, 13, 0, 0, 0, 0, time.UTC),
+			want:  []string{"2025-01-10T09:00:00Z", "2025-01-11T09:00:00Z", "2025-01-12T09:00:00Z"},
+		},
+		{
+			name:  "unsupported frequency yields only the first occurrence",
+			lines: []string{"DTSTART:20250101T090000Z", "RRULE:FREQ=HOURLY;COUNT=3"},
+			want:  []string{"2025-01-01T09:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := append(append([]string{"BEGIN:VEVENT"}, tt.lines...), "END:VEVENT")
+			events := parseEvents(t, time.UTC, lines...)
+
+			windowFrom, windowTo := from, to
+			if !tt.from.IsZero() {
+				windowFrom = tt.from
+			}
+			if !tt.to.IsZero() {
+				windowTo = tt.to
+			}
+			got := formatTimes(events[0].Occurrences(windowFrom, windowTo))
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Occurrences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceID(t *testing.T) {
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skip("time zone data is not available:", err)
+	}
+
+	events := parseEvents(t, time.UTC,
+		"BEGIN:VEVENT",
+		"UID:sync@example.com",
+		"SUMMARY:Weekly sync",
+		"DTSTART;TZID=America/New_York:20250106T100000",
+		"DTEND;TZID=America/New_York:20250106T103000",
+		"RRULE:FREQ=WEEKLY;COUNT=4",
+		"EXDATE;TZID=America/New_York:20250120T100000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:sync@example.com",
+		"SUMMARY:Weekly sync (moved)",
+		"RECURRENCE-ID:20250113T150000Z",
+		"DTSTART;TZID=America/New_York:20250114T140000",
+		"DTEND;TZID=America/New_York:20250114T143000",
+		"END:VEVENT",
+	)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	master, override := events[0], events[1]
+
+	if master.RecurrenceID != nil {
+		t.Errorf("master RecurrenceID = %v, want nil", master.RecurrenceID)
+	}
+	if override.RecurrenceID == nil {
+		t.Fatal("override RecurrenceID = nil")
+	}
+
+	occurrences := master.Occurrences(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	want := []string{"2025-01-06T15:00:00Z", "2025-01-13T15:00:00Z", "2025-01-27T15:00:00Z"}
+	if got := formatTimes(occurrences); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Occurrences() = %v, want %v", got, want)
+	}
+
+	// The override's RECURRENCE-ID, given in UTC, names the zoned occurrence it replaces
+	if !override.RecurrenceID.Equal(occurrences[1]) {
+		t.Errorf("RecurrenceID = %s, want %s", override.RecurrenceID, occurrences[1])
+	}
+	if got := override.EndTime().Sub(override.Start); got != 30*time.Minute {
+		t.Errorf("override lasts %s, want 30m", got)
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		value       string
+		want        *Recurrence
+		unsupported []string
+		ok          bool
+	}{
+		{
+			value: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,-1FR;WKST=SU",
+			want:  &Recurrence{Freq: FreqWeekly, Interval: 2, ByDay: []WeekdayNum{{0, time.Monday}, {-1, time.Friday}}, WeekStart: time.Sunday},
+			ok:    true,
+		},
+		{
+			value: "freq=yearly;bymonth=2,8;bymonthday=-1",
+			want:  &Recurrence{Freq: FreqYearly, Interval: 1, ByMonth: []time.Month{2, 8}, ByMonthDay: []int{-1}, WeekStart: time.Monday},
+			ok:    true,
+		},
+		{
+			value:       "FREQ=MONTHLY;BYSETPOS=-1;BYDAY=MO",
+			want:        &Recurrence{Freq: FreqMonthly, Interval: 1, ByDay: []WeekdayNum{{0, time.Monday}}, WeekStart: time.Monday},
+			unsupported: []string{"BYSETPOS"},
+			ok:          true,
+		},
+		{
+			value:       "FREQ=MINUTELY",
+			want:        &Recurrence{Freq: "MINUTELY", Interval: 1, WeekStart: time.Monday},
+			unsupported: []string{"FREQ=MINUTELY"},
+			ok:          true,
+		},
+		{value: "COUNT=3"},
+		{value: "FREQ=DAILY;INTERVAL=0"},
+		{value: "FREQ=DAILY;COUNT=x"},
+		{value: "FREQ=WEEKLY;BYDAY=XX"},
+		{value: "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{value: "FREQ=YEARLY;BYMONTH=13"},
+		{value: "FREQ=DAILY;UNTIL=soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRecurrence(tt.value, time.UTC)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("ParseRecurrence(%q) succeeded, want an error", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRecurrence(%q) error = %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got.Unsupported(), tt.unsupported) {
+				t.Errorf("Unsupported() = %v, want %v", got.Unsupported(), tt.unsupported)
+			}
+			got.unsupported = nil
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRecurrence(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// AccountPurge erases deleted accounts, with all their data, once their
+// grace period is over
+func AccountPurge(db *repository.Database) Job {
+	return Job{
+		Name:     "account-purge",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			return PurgeDeletedAccounts(ctx, db, now)
+		},
+	}
+}
+
+// PurgeDeletedAccounts hard-deletes every account due for purging. Each
+// account goes in its own statement so one large account doesn't hold up
+// the rest.
+func PurgeDeletedAccounts(ctx context.Context, db *repository.Database, now time.Time) error {
+	ids, err := db.FindUsersToPurge(now)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := db.PurgeUser(id, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/export"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// staleExportAge is how long an export may stay running before it is given up on
+const staleExportAge = time.Hour
+
+// DataExports builds requested "download my data" archives and deletes
+// them once their download window has closed
+func DataExports(db *repository.Database) Job {
+	return Job{
+		Name:     "data-exports",
+		Interval: time.Minute,
+		Run: func(ctx context.Context, now time.Time) error {
+			return ProcessDataExports(ctx, db, now)
+		},
+	}
+}
+
+// ProcessDataExports builds every pending export. An export that fails is
+// marked failed so the user can ask for a new one.
+func ProcessDataExports(ctx context.Context, db *repository.Database, now time.Time) error {
+	if err := db.FailStaleDataExports(now.Add(-staleExportAge)); err != nil {
+		return err
+	}
+	if err := db.PurgeDataExports(now); err != nil {
+		return err
+	}
+
+	var failures []error
+	for ctx.Err() == nil {
+		dataExport, err := db.ClaimDataExport()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		archive, err := BuildDataExport(db, dataExport.UserID, time.Now())
+		if err != nil {
+			failures = append(failures, fmt.Errorf("export %d: %w", dataExport.ID, err))
+			if err := db.FailDataExport(dataExport, "Export failed"); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := db.CompleteDataExport(dataExport, archive, time.Now().Add(models.DataExportTTL)); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+// BuildDataExport writes the user's data as a zip archive
+func BuildDataExport(db *repository.Database, userID uint, now time.Time) ([]byte, error) {
+	data, err := db.FindUserData(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &export.Archive{
+		ExportedAt: now.UTC(),
+		Profile:    export.NewProfile(&data.User),
+		Todos:      data.Todos,
+		Priorities: data.Priorities,
+		Contacts:   data.Contacts,
+		Thoughts:   data.Thoughts,
+	}
+	for _, checkIn := range data.Water {
+		archive.WaterIntake = append(archive.WaterIntake, export.WaterIntake{
+			Date:    checkIn.Date,
+			Glasses: checkIn.Value,
+			Target:  data.WaterTarget,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, archive); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/digest"
+	"github.com/himanshu/daily-planner/internal/mail"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// digestSendWindow is how long after a user's chosen time their email may
+// still go out, e.g. when the server was down at that time
+const digestSendWindow = 2 * time.Hour
+
+// EmailDigests sends each user's morning and evening emails at the times
+// they chose, in their own time zone. Links in the emails point at baseURL.
+func EmailDigests(db *repository.Database, mailer mail.Mailer, templates *digest.Templates, baseURL string) Job {
+	return Job{
+		Name:     "email-digests",
+		Interval: 5 * time.Minute,
+		Run: func(ctx context.Context, now time.Time) error {
+			return SendEmailDigests(ctx, db, mailer, templates, baseURL, now)
+		},
+	}
+}
+
+// SendEmailDigests sends the emails that are due. Each goes out at most once
+// a day; one that fails is retried on the next run. Days with nothing in
+// them send nothing.
+func SendEmailDigests(ctx context.Context, db *repository.Database, mailer mail.Mailer, templates *digest.Templates, baseURL string, now time.Time) error {
+	users, err := db.FindDigestUsers()
+	if err != nil {
+		return err
+	}
+
+	var failures []error
+	for i := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		user := &users[i]
+
+		loc := user.Location()
+		day := user.Today(now)
+		elapsed := now.In(loc).Sub(time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc))
+		for _, kind := range []string{models.DigestKindMorning, models.DigestKindEvening} {
+			enabled, minutes := user.DigestMorning, user.DigestMorningAt
+			if kind == models.DigestKindEvening {
+				enabled, minutes = user.DigestEvening, user.DigestEveningAt
+			}
+			at := time.Duration(minutes) * time.Minute
+			if !enabled || elapsed < at || elapsed >= at+digestSendWindow {
+				continue
+			}
+
+			if err := sendEmailDigest(ctx, db, mailer, templates, baseURL, user, kind, day); err != nil {
+				failures = append(failures, fmt.Errorf("%s email to user %d: %w", kind, user.ID, err))
+			}
+		}
+	}
+
+	return errors.Join(failures...)
+}
+
+// sendEmailDigest sends one user's email of the given kind for day, unless
+// it has already been sent
+func sendEmailDigest(ctx context.Context, db *repository.Database, mailer mail.Mailer, templates *digest.Templates, baseURL string, user *models.User, kind string, day time.Time) error {
+	claimed, err := db.ClaimDigest(user.ID, kind, day)
+	if err != nil || !claimed {
+		return err
+	}
+
+	err = func() error {
+		d, err := digest.Load(db, user, kind, day, baseURL)
+		if err != nil || d.Empty() {
+			return err
+		}
+		msg, err := templates.Compose(d, user.Email)
+		if err != nil {
+			return err
+		}
+		return mailer.Send(ctx, msg)
+	}()
+	if err != nil {
+		return errors.Join(err, db.ReleaseDigest(user.ID, kind, day))
+	}
+	return nil
+}
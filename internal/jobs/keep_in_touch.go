@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// KeepInTouch creates a follow-up reminder for every person whose
+// keep-in-touch cadence has run out since their last interaction
+func KeepInTouch(db *repository.Database) Job {
+	return Job{
+		Name:     "keep-in-touch",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			return GenerateCadenceReminders(ctx, db, now)
+		},
+	}
+}
+
+// GenerateCadenceReminders adds today's reminders, in each user's time
+// zone, for people who are due. People who already have a pending
+// keep-in-touch reminder keep it, moved up to today if it is overdue.
+func GenerateCadenceReminders(ctx context.Context, db *repository.Database, now time.Time) error {
+	days := make(map[uint]time.Time)
+
+	people, err := db.FindCadencePeople(nil)
+	if err != nil {
+		return err
+	}
+
+	for _, person := range people {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		today, ok := days[person.UserID]
+		if !ok {
+			user, err := db.FindUserByID(person.UserID)
+			if err != nil {
+				return err
+			}
+			today = user.Today(now)
+			days[person.UserID] = today
+		}
+		if person.DueDate().After(today) {
+			continue
+		}
+		if _, err := db.CreateCadenceReminder(person.Person, today); err != nil {
+			return err
+		}
+	}
+	return nil
+}
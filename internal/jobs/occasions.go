@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/occasions"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// Occasions creates a "wish them a happy birthday" reminder on the day of
+// each birthday, anniversary and custom date. It runs hourly so that every
+// user gets their reminders soon after midnight in their own time zone.
+func Occasions(db *repository.Database) Job {
+	return Job{
+		Name:     "occasions",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			return GenerateOccasionReminders(ctx, db, now)
+		},
+	}
+}
+
+// GenerateOccasionReminders adds a reminder for every occasion that falls
+// today in its user's time zone. Each occurrence gets at most one reminder.
+func GenerateOccasionReminders(ctx context.Context, db *repository.Database, now time.Time) error {
+	users, err := db.FindOccasionUsers()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		people, err := db.FindOccasionPeople(user.ID)
+		if err != nil {
+			return err
+		}
+		dates, err := db.FindSignificantDatesByUserID(user.ID)
+		if err != nil {
+			return err
+		}
+
+		day := user.Today(now)
+		for _, occasion := range occasions.Upcoming(people, dates, day, 0) {
+			personID := occasion.PersonID
+			reminder := models.Contact{
+				UserID:      user.ID,
+				PersonID:    &personID,
+				Name:        occasion.Name,
+				Type:        models.ContactTypeCall,
+				Description: occasion.Reminder(),
+				Date:        day,
+				Source:      models.ContactSourceOccasion,
+				OccasionKey: occasion.Key,
+			}
+			if _, err := db.CreateOccasionReminder(&reminder); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
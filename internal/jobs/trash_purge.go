@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// TrashPurge deletes todos, priorities and follow-up reminders for good once
+// they have been in the trash for longer than retention
+func TrashPurge(db *repository.Database, retention time.Duration) Job {
+	return Job{
+		Name:     "trash-purge",
+		Interval: time.Hour,
+		Run: func(ctx context.Context, now time.Time) error {
+			_, err := db.PurgeTrash(now.Add(-retention))
+			return err
+		},
+	}
+}
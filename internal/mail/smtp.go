@@ -0,0 +1,40 @@
+package mail
+
+import (
+	"context"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP server, upgrading to TLS when the
+// server offers it. It logs in only when Username is set.
+type SMTPMailer struct {
+	From     *netmail.Address
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// Send delivers msg to the SMTP server
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	body, err := render(m.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, m.From.Address, []string{to.Address}, body)
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,44 +11,208 @@ import (
 
 type User struct {
 	gorm.Model
-	Username     string  `gorm:"uniqueIndex;not null"`
-	Email        string  `gorm:"uniqueIndex;not null"`
-	Password     string  `gorm:"not null"`
-	GoogleID     *string `gorm:"uniqueIndex"`
-	LastLoginAt  time.Time
-	TodoItems    []TodoItem
-	Priorities   []Priority
-	Contacts     []Contact
-	WaterIntakes []WaterIntake
-	Thoughts     []Thought
+	Username           string  `gorm:"uniqueIndex;not null"`
+	Email              string  `gorm:"uniqueIndex;not null"`
+	Password           string  `gorm:"not null"`
+	GoogleID           *string `gorm:"uniqueIndex"`
+	LastLoginAt        time.Time
+	MaxDailyPriorities int        `gorm:"not null;default:3"`     // Cap on priorities per day
+	Timezone           string     `gorm:"not null;default:UTC"`   // IANA name, e.g. Europe/Berlin
+	OccasionLeadDays   int        `gorm:"not null;default:14"`    // How far ahead upcoming birthdays are shown
+	CalendarToken      *string    `gorm:"uniqueIndex" json:"-"`   // Secret in the calendar feed URL; nil when the feed is off
+	PurgeAt            *time.Time `json:"-"`                      // When a deleted account is erased for good; until then it can be restored
+	DigestMorning      bool       `gorm:"not null;default:false"` // Email the day's plan each morning
+	DigestEvening      bool       `gorm:"not null;default:false"` // Email a recap of the day each evening
+	DigestMorningAt    int        `gorm:"not null;default:420"`   // Minutes after midnight, in Timezone, to send the morning email
+	DigestEveningAt    int        `gorm:"not null;default:1200"`  // Minutes after midnight, in Timezone, to send the recap
+	DigestToken        *string    `gorm:"uniqueIndex" json:"-"`   // Secret in the unsubscribe link
+	DigestMorningSent  *time.Time `json:"-"`                      // Day the last morning email was sent for
+	DigestEveningSent  *time.Time `json:"-"`                      // Day the last recap was sent for
+	TodoItems          []TodoItem
+	Priorities         []Priority
+	Contacts           []Contact
+	People             []Person
+	Interactions       []Interaction
+	WaterIntakes       []WaterIntake
+	Thoughts           []Thought
+	MoodEntries        []MoodEntry
+	Habits             []Habit
+	FocusSessions      []FocusSession
+	TimeBlocks         []TimeBlock
+	Tags               []Tag
+	Projects           []Project
+	DailyReviews       []DailyReview
 }
 
+// Location is the user's time zone, UTC when it is empty or unknown
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the calendar day it is at now in the user's time zone, as
+// midnight UTC the way the planner stores dates
+func (u *User) Today(now time.Time) time.Time {
+	y, m, d := now.In(u.Location()).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Kinds of daily email
+const (
+	DigestKindMorning = "morning" // The day's plan
+	DigestKindEvening = "evening" // A recap of the day
+)
+
+// Todo priority levels, P1 being the most urgent
+const (
+	TodoPriorityP1 = 1
+	TodoPriorityP2 = 2
+	TodoPriorityP3 = 3
+	TodoPriorityP4 = 4
+)
+
 type TodoItem struct {
 	gorm.Model
-	UserID      uint
-	Title       string `gorm:"not null"`
-	Description string
-	DueDate     time.Time
-	Completed   bool `gorm:"default:false"`
+	UserID           uint
+	Title            string `gorm:"not null"`
+	Description      string
+	DueDate          time.Time
+	Completed        bool    `gorm:"default:false"`
+	PriorityLevel    int     `gorm:"not null;default:4"` // 1 (P1) to 4 (P4)
+	Position         float64 `gorm:"not null;default:0"` // Manual sort order
+	ProjectID        *uint
+	Tags             []Tag `gorm:"many2many:todo_item_tags;"`
+	Subtasks         []Subtask
+	ManualCompletion bool   `gorm:"default:false"`               // Don't complete automatically when all subtasks are done
+	ExternalUID      string `json:",omitempty"`                  // UID of the calendar entry the todo was imported from
+	CalDAVName       string `gorm:"column:caldav_name" json:"-"` // Resource name a CalDAV client created the todo under
+	Progress         int    `gorm:"-"`                           // Percentage of subtasks completed
+}
+
+type Subtask struct {
+	gorm.Model
+	UserID     uint
+	TodoItemID uint
+	Title      string  `gorm:"not null"`
+	Completed  bool    `gorm:"default:false"`
+	Position   float64 `gorm:"not null;default:0"`
 }
 
+// DefaultMaxDailyPriorities is the "top 3" a user starts with
+const DefaultMaxDailyPriorities = 3
+
 type Priority struct {
 	gorm.Model
 	UserID      uint
 	Title       string `gorm:"not null"`
 	Description string
 	Date        time.Time
-	Completed   bool `gorm:"default:false"`
+	Completed   bool      `gorm:"default:false"`
+	Position    float64   `gorm:"not null;default:0"` // Manual sort order
+	TodoItemID  *uint     // Set when a todo was promoted; the todo holds the title and completion
+	TodoItem    *TodoItem `json:",omitempty"`
+	Rank        int       `gorm:"-"` // 1-based place in the day's list
 }
 
+// Follow-up channels, shared by contact reminders and interactions
+const (
+	ContactTypeCall    = "Call"
+	ContactTypeEmail   = "Email"
+	ContactTypeText    = "Text"
+	ContactTypeMeeting = "Meeting"
+	ContactTypeOther   = "Other"
+)
+
+// ContactTypes lists the valid follow-up channels in display order
+var ContactTypes = []string{ContactTypeCall, ContactTypeEmail, ContactTypeText, ContactTypeMeeting, ContactTypeOther}
+
+// Contact is a follow-up reminder to reach a person on a given day
 type Contact struct {
 	gorm.Model
 	UserID      uint
-	Name        string `gorm:"not null"`
-	Type        string `gorm:"not null"` // Call, Email, or Text
+	PersonID    *uint
+	Person      *Person `json:",omitempty"`
+	Name        string  `gorm:"not null"` // The person's name when the reminder was created
+	Type        string  `gorm:"not null"` // One of ContactTypes
 	Description string
 	Date        time.Time
-	Completed   bool `gorm:"default:false"`
+	Completed   bool    `gorm:"default:false"`
+	Position    float64 `gorm:"not null;default:0"` // Manual sort order
+	Source      string  // Empty for reminders the user added, otherwise what generated it
+	OccasionKey string  `json:",omitempty"` // The birthday or date occurrence an occasion reminder is for
+	ExternalUID string  `json:",omitempty"` // UID of the calendar entry the reminder was imported from
+}
+
+// Sources of generated contact reminders
+const (
+	ContactSourceCadence  = "cadence"
+	ContactSourceOccasion = "occasion"
+)
+
+// Person is an entry in the user's contact book
+type Person struct {
+	gorm.Model
+	UserID      uint
+	Name        string `gorm:"not null"`
+	Notes       string
+	Birthday    *time.Time
+	CadenceDays int    `gorm:"not null;default:0"` // Keep in touch every this many days; 0 turns it off
+	CadenceType string // Channel for generated reminders, one of ContactTypes
+	Emails      []PersonEmail
+	Phones      []PersonPhone
+	Tags        []Tag `gorm:"many2many:person_tags;"`
+	Dates       []SignificantDate
+}
+
+// Kinds of yearly occasions; birthdays are stored on Person
+const (
+	OccasionBirthday    = "birthday"
+	OccasionAnniversary = "anniversary"
+	OccasionCustom      = "custom"
+)
+
+// DefaultOccasionLeadDays is how many days ahead upcoming occasions are shown
+const DefaultOccasionLeadDays = 14
+
+// SignificantDate is a yearly occasion for a person other than their birthday
+type SignificantDate struct {
+	gorm.Model
+	UserID   uint
+	PersonID uint
+	Kind     string    `gorm:"not null"` // anniversary or custom
+	Label    string    // e.g. Wedding anniversary; required for custom dates
+	Date     time.Time `gorm:"not null"` // The original date; its year counts the years
+}
+
+type PersonEmail struct {
+	ID       uint
+	PersonID uint
+	Label    string // e.g. home or work
+	Address  string `gorm:"not null"`
+}
+
+type PersonPhone struct {
+	ID       uint
+	PersonID uint
+	Label    string // e.g. mobile or work
+	Number   string `gorm:"not null"`
+}
+
+// Interaction records a past conversation with a person
+type Interaction struct {
+	gorm.Model
+	UserID    uint
+	PersonID  uint
+	ContactID *uint  // The follow-up reminder this completed, if any
+	Type      string `gorm:"not null"` // One of ContactTypes
+	Date      time.Time
+	Notes     string
 }
 
 type WaterIntake struct {
@@ -56,9 +223,201 @@ type WaterIntake struct {
 	Target  int `gorm:"default:10"`
 }
 
+// TableName keeps WaterIntake on the table the initial schema created
+func (WaterIntake) TableName() string {
+	return "water_intake"
+}
+
 type Thought struct {
 	gorm.Model
 	UserID  uint
 	Content string `gorm:"not null"`
 	Date    time.Time
 }
+
+type MoodEntry struct {
+	gorm.Model
+	UserID uint
+	Date   time.Time
+	Mood   int `gorm:"not null"` // 1 (low) to 5 (great)
+	Energy int `gorm:"not null"` // 1 (drained) to 5 (energized)
+	Emoji  string
+	Notes  string
+}
+
+// Habit kinds
+const (
+	HabitKindCounter = "counter"
+	HabitKindBoolean = "boolean"
+)
+
+// Habit periods
+const (
+	HabitPeriodDaily  = "daily"
+	HabitPeriodWeekly = "weekly"
+)
+
+// HabitKeyWater identifies the built-in water habit that replaced WaterIntake
+const HabitKeyWater = "water"
+
+type Habit struct {
+	gorm.Model
+	UserID    uint
+	SystemKey string `gorm:"index"` // Set for built-in habits such as water
+	Name      string `gorm:"not null"`
+	Icon      string // Font Awesome icon class, e.g. fa-glass-whiskey
+	Unit      string
+	Kind      string `gorm:"not null;default:counter"` // counter or boolean
+	Period    string `gorm:"not null;default:daily"`   // daily or weekly
+	Target    int    `gorm:"not null;default:1"`
+	Archived  bool   `gorm:"default:false"`
+	CheckIns  []HabitCheckIn
+}
+
+type HabitCheckIn struct {
+	gorm.Model
+	UserID  uint
+	HabitID uint
+	Date    time.Time
+	Value   int `gorm:"default:0"`
+}
+
+// Focus session statuses
+const (
+	FocusStatusRunning = "running"
+	FocusStatusPaused  = "paused"
+	FocusStatusStopped = "stopped"
+)
+
+type FocusSession struct {
+	gorm.Model
+	UserID          uint
+	TodoItemID      *uint
+	PriorityID      *uint
+	Label           string
+	Status          string `gorm:"not null;default:running"`
+	PlannedMinutes  int
+	StartedAt       time.Time
+	ResumedAt       *time.Time // Start of the current running segment
+	EndedAt         *time.Time
+	DurationSeconds int `gorm:"default:0"` // Time spent running, excluding the current segment
+}
+
+type TimeBlock struct {
+	gorm.Model
+	UserID      uint
+	Date        time.Time
+	StartAt     time.Time `gorm:"not null"`
+	EndAt       time.Time `gorm:"not null"`
+	Title       string    `gorm:"not null"`
+	Color       string
+	TodoItemID  *uint
+	PriorityID  *uint
+	ContactID   *uint
+	ExternalUID string `json:",omitempty"` // UID of the calendar entry the block was imported from
+}
+
+type Tag struct {
+	gorm.Model
+	UserID uint
+	Name   string `gorm:"not null"`
+	Color  string
+}
+
+type Project struct {
+	gorm.Model
+	UserID    uint
+	Name      string `gorm:"not null"`
+	Color     string
+	Archived  bool    `gorm:"default:false"`
+	Position  float64 `gorm:"not null;default:0"`
+	TodoItems []TodoItem
+}
+
+// DailyReview is the user's end-of-day look back at one day
+type DailyReview struct {
+	gorm.Model
+	UserID     uint
+	Date       time.Time
+	Reflection string
+	ReviewedAt *time.Time // When the day was marked as reviewed
+}
+
+// Data export statuses
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+)
+
+// DataExportTTL is how long a finished export can be downloaded
+const DataExportTTL = 24 * time.Hour
+
+// DataExport is a "download my data" archive, built by a background job
+type DataExport struct {
+	gorm.Model
+	UserID    uint
+	Status    string     `gorm:"not null;default:pending"`
+	Token     string     `gorm:"uniqueIndex;not null" json:"-"` // Secret in the download link
+	Archive   []byte     `json:"-"`                             // The zip file, once ready
+	Size      int        // Bytes in the archive
+	Error     string     `json:",omitempty"`
+	ExpiresAt *time.Time // When the download link stops working; set once ready
+}
+
+// Where a change in the activity log came from
+const (
+	ActivitySourceWeb = "web" // The dashboard and its pages
+	ActivitySourceAPI = "api" // Sync clients such as CalDAV apps
+	ActivitySourceJob = "job" // Background jobs such as reminder generation
+)
+
+// Activity log actions
+const (
+	ActivityCreate   = "create"
+	ActivityUpdate   = "update"
+	ActivityComplete = "complete"
+	ActivityDelete   = "delete"  // Moved to the trash
+	ActivityRestore  = "restore" // Brought back from the trash
+	ActivityPurge    = "purge"   // Deleted for good
+)
+
+// FieldChange is a field's value before and after a change. Before is nil
+// for a new record and After for a deleted one.
+type FieldChange struct {
+	Before interface{}
+	After  interface{}
+}
+
+// ActivityChanges maps column names to how they changed, stored as JSON
+type ActivityChanges map[string]FieldChange
+
+func (c ActivityChanges) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *ActivityChanges) Scan(value interface{}) error {
+	data, ok := value.([]byte)
+	if !ok {
+		s, isString := value.(string)
+		if !isString {
+			return errors.New("activity changes must be JSON")
+		}
+		data = []byte(s)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// ActivityLog is one change to a planner record. Rows are only ever added.
+type ActivityLog struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UserID    uint            // Whose record changed
+	ActorID   *uint           // Who changed it; nil for background jobs
+	Source    string          // One of the ActivitySource constants
+	Entity    string          // Kind of record, e.g. todo or priority
+	EntityID  uint            // ID of the record
+	Action    string          // One of the Activity action constants
+	Changes   ActivityChanges `gorm:"type:jsonb"`
+}
@@ -0,0 +1,124 @@
+// Package occasions works out when yearly dates such as birthdays and
+// anniversaries next come around.
+package occasions
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// Occasion is the next occurrence of a person's yearly date
+type Occasion struct {
+	PersonID uint   `json:"personId"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // birthday, anniversary or custom
+	Label    string `json:"label,omitempty"`
+	Date     string `json:"date"`            // YYYY-MM-DD of the next occurrence
+	DaysAway int    `json:"daysAway"`        // 0 when it is today
+	Years    int    `json:"years,omitempty"` // Age reached or years since the original date
+	Key      string `json:"key"`             // Identifies this occurrence, e.g. birthday:2026
+}
+
+// Title describes the occasion for a reminder, e.g. "Ann's birthday (30)"
+func (o Occasion) Title() string {
+	what := o.Kind
+	if o.Label != "" {
+		what = o.Label
+	}
+	if o.Years > 0 {
+		return fmt.Sprintf("%s's %s (%d)", o.Name, what, o.Years)
+	}
+	return fmt.Sprintf("%s's %s", o.Name, what)
+}
+
+// Reminder is the follow-up text for the day of the occasion, e.g. "Wish Ann
+// a happy birthday"
+func (o Occasion) Reminder() string {
+	switch o.Kind {
+	case models.OccasionBirthday:
+		return fmt.Sprintf("Wish %s a happy birthday", o.Name)
+	case models.OccasionAnniversary:
+		what := "anniversary"
+		if o.Label != "" {
+			what = o.Label
+		}
+		return fmt.Sprintf("Wish %s a happy %s", o.Name, what)
+	}
+	return "Remember " + o.Title()
+}
+
+// Next returns the first anniversary of original on or after day. A 29
+// February date falls on 28 February in other years.
+func Next(original, day time.Time) time.Time {
+	for year := day.Year(); ; year++ {
+		next := onYear(original, year)
+		if !next.Before(day) {
+			return next
+		}
+	}
+}
+
+func onYear(original time.Time, year int) time.Time {
+	month, d := original.Month(), original.Day()
+	if month == time.February && d == 29 && !isLeap(year) {
+		d = 28
+	}
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// Upcoming lists the birthdays and significant dates that fall within the
+// days after day (0 for just today), soonest first. people supply birthdays
+// and the names for dates.
+func Upcoming(people []models.Person, dates []models.SignificantDate, day time.Time, days int) []Occasion {
+	names := make(map[uint]string, len(people))
+	var upcoming []Occasion
+
+	add := func(o Occasion, original time.Time) {
+		next := Next(original, day)
+		o.DaysAway = int(next.Sub(day).Hours() / 24)
+		if o.DaysAway > days {
+			return
+		}
+		o.Date = next.Format("2006-01-02")
+		if original.Year() > 1 {
+			o.Years = next.Year() - original.Year()
+		}
+		o.Key = fmt.Sprintf("%s:%d", o.Key, next.Year())
+		upcoming = append(upcoming, o)
+	}
+
+	for _, person := range people {
+		names[person.ID] = person.Name
+		if person.Birthday != nil {
+			add(Occasion{PersonID: person.ID, Name: person.Name, Kind: models.OccasionBirthday, Key: models.OccasionBirthday}, *person.Birthday)
+		}
+	}
+	for _, date := range dates {
+		name, ok := names[date.PersonID]
+		if !ok {
+			continue
+		}
+		add(Occasion{
+			PersonID: date.PersonID,
+			Name:     name,
+			Kind:     date.Kind,
+			Label:    date.Label,
+			Key:      fmt.Sprintf("date-%d", date.ID),
+		}, date.Date)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if upcoming[i].DaysAway != upcoming[j].DaysAway {
+			return upcoming[i].DaysAway < upcoming[j].DaysAway
+		}
+		return upcoming[i].Name < upcoming[j].Name
+	})
+	return upcoming
+}
@@ -0,0 +1,91 @@
+package occasions
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"gorm.io/gorm"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name     string
+		original time.Time
+		day      time.Time
+		want     time.Time
+	}{
+		{"later this year", date(1990, 6, 15), date(2025, 3, 1), date(2025, 6, 15)},
+		{"today", date(1990, 6, 15), date(2025, 6, 15), date(2025, 6, 15)},
+		{"already passed this year", date(1990, 6, 15), date(2025, 6, 16), date(2026, 6, 15)},
+		{"new year's eve from new year's day", date(1990, 12, 31), date(2025, 1, 1), date(2025, 12, 31)},
+		{"29 February in a leap year", date(2000, 2, 29), date(2024, 1, 10), date(2024, 2, 29)},
+		{"29 February falls on 28 February otherwise", date(2000, 2, 29), date(2025, 1, 10), date(2025, 2, 28)},
+		{"29 February on its stand-in day", date(2000, 2, 29), date(2025, 2, 28), date(2025, 2, 28)},
+		{"29 February after the stand-in day", date(2000, 2, 29), date(2025, 3, 1), date(2026, 2, 28)},
+		{"29 February rolls into a leap year", date(2000, 2, 29), date(2027, 3, 1), date(2028, 2, 29)},
+		{"1900 was not a leap year", date(1896, 2, 29), date(1900, 1, 1), date(1900, 2, 28)},
+		{"2000 was a leap year", date(1996, 2, 29), date(2000, 1, 1), date(2000, 2, 29)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Next(tt.original, tt.day); !got.Equal(tt.want) {
+				t.Errorf("Next(%s, %s) = %s, want %s", tt.original.Format("2006-01-02"), tt.day.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	annBirthday := date(1995, 3, 12)
+	bobBirthday := date(1, 3, 10) // Year unknown
+	people := []models.Person{
+		{Model: gorm.Model{ID: 1}, Name: "Ann", Birthday: &annBirthday},
+		{Model: gorm.Model{ID: 2}, Name: "Bob", Birthday: &bobBirthday},
+		{Model: gorm.Model{ID: 3}, Name: "Cy"},
+	}
+	dates := []models.SignificantDate{
+		{Model: gorm.Model{ID: 7}, PersonID: 3, Kind: models.OccasionAnniversary, Date: date(2015, 3, 10)},
+		{Model: gorm.Model{ID: 8}, PersonID: 3, Kind: models.OccasionCustom, Label: "Gotcha day", Date: date(2020, 9, 1)},
+		{Model: gorm.Model{ID: 9}, PersonID: 99, Kind: models.OccasionCustom, Label: "Unknown person", Date: date(2020, 3, 10)},
+	}
+	day := date(2025, 3, 10)
+
+	tests := []struct {
+		name string
+		days int
+		want []Occasion
+	}{
+		{
+			name: "today only",
+			days: 0,
+			want: []Occasion{
+				{PersonID: 2, Name: "Bob", Kind: models.OccasionBirthday, Date: "2025-03-10", Key: "birthday:2025"},
+				{PersonID: 3, Name: "Cy", Kind: models.OccasionAnniversary, Date: "2025-03-10", Years: 10, Key: "date-7:2025"},
+			},
+		},
+		{
+			name: "the next few days, soonest first",
+			days: 7,
+			want: []Occasion{
+				{PersonID: 2, Name: "Bob", Kind: models.OccasionBirthday, Date: "2025-03-10", Key: "birthday:2025"},
+				{PersonID: 3, Name: "Cy", Kind: models.OccasionAnniversary, Date: "2025-03-10", Years: 10, Key: "date-7:2025"},
+				{PersonID: 1, Name: "Ann", Kind: models.OccasionBirthday, Date: "2025-03-12", DaysAway: 2, Years: 30, Key: "birthday:2025"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Upcoming(people, dates, day, tt.days); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Upcoming() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package planner
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// GetActivity handles the user's activity feed, newest first. It can be
+// narrowed with ?entity= to one kind of record, and pages back with
+// ?before= set to the nextBefore of the previous page.
+func (h *PlannerHandler) GetActivity(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	entity := c.Query("entity")
+	if entity != "" && !repository.IsActivityEntity(entity) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown entity"})
+		return
+	}
+
+	var before uint64
+	if value := c.Query("before"); value != "" {
+		var err error
+		if before, err = strconv.ParseUint(value, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid before"})
+			return
+		}
+	}
+
+	limit := defaultActivityLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, maxActivityLimit)
+	}
+
+	logs, err := h.db.FindActivity(userID.(uint), entity, uint(before), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+
+	response := gin.H{"activity": logs, "nextBefore": nil}
+	if len(logs) == limit {
+		response["nextBefore"] = logs[len(logs)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRecordActivity handles the history of one record, such as
+// /activity/todo/12, newest first. Deleted and purged records keep theirs.
+func (h *PlannerHandler) GetRecordActivity(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	entity := c.Param("entity")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if !repository.IsActivityEntity(entity) || err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+
+	logs, err := h.db.FindRecordActivity(userID.(uint), entity, uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+	if len(logs) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"activity": logs})
+}
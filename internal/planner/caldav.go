@@ -0,0 +1,581 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/ical"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// caldavRoot is where the CalDAV server is mounted
+const caldavRoot = "/caldav/"
+
+// caldavAllow lists the methods the CalDAV endpoint answers
+const caldavAllow = "OPTIONS, PROPFIND, REPORT, GET, HEAD, PUT, DELETE"
+
+// CalDAV resource kinds, from the request path
+const (
+	davRoot       = "root"       // /caldav/
+	davPrincipal  = "principal"  // /caldav/principals/<user>/
+	davHome       = "home"       // /caldav/calendars/<user>/
+	davCollection = "collection" // /caldav/calendars/<user>/todos/
+	davTodo       = "todo"       // /caldav/calendars/<user>/todos/<name>
+)
+
+var (
+	propResourceType     = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName      = xml.Name{Space: nsDAV, Local: "displayname"}
+	propUserPrincipal    = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL     = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propPrivileges       = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReports = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propETag             = xml.Name{Space: nsDAV, Local: "getetag"}
+	propContentType      = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propLastModified     = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHome     = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propUserAddresses    = xml.Name{Space: nsCalDAV, Local: "calendar-user-address-set"}
+	propComponents       = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData     = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propCTag             = xml.Name{Space: nsCalServer, Local: "getctag"}
+)
+
+// davPath is a parsed CalDAV request path
+type davPath struct {
+	Kind   string
+	UserID uint
+	Name   string // Resource name of a todo
+}
+
+// parseDAVPath reads a path below /caldav/; ok is false for paths the
+// server has nothing at
+func parseDAVPath(path string) (davPath, bool) {
+	path = strings.TrimPrefix(path, "/")
+	if path == "" {
+		return davPath{Kind: davRoot}, true
+	}
+
+	parts := strings.Split(path, "/")
+	// A trailing slash leaves an empty last part
+	if parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) < 2 {
+		return davPath{}, false
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return davPath{}, false
+	}
+	p := davPath{UserID: uint(id)}
+
+	switch {
+	case parts[0] == "principals" && len(parts) == 2:
+		p.Kind = davPrincipal
+	case parts[0] == "calendars" && len(parts) == 2:
+		p.Kind = davHome
+	case parts[0] == "calendars" && len(parts) == 3 && parts[2] == "todos":
+		p.Kind = davCollection
+	case parts[0] == "calendars" && len(parts) == 4 && parts[2] == "todos" && parts[3] != "":
+		p.Kind = davTodo
+		p.Name = parts[3]
+	default:
+		return davPath{}, false
+	}
+	return p, true
+}
+
+func principalHref(userID uint) string {
+	return fmt.Sprintf("%sprincipals/%d/", caldavRoot, userID)
+}
+
+func calendarHomeHref(userID uint) string {
+	return fmt.Sprintf("%scalendars/%d/", caldavRoot, userID)
+}
+
+func todoCollectionHref(userID uint) string {
+	return calendarHomeHref(userID) + "todos/"
+}
+
+// caldavName is a todo's resource name: the one its client chose, or one
+// derived from its ID for todos made in the planner
+func caldavName(todo *models.TodoItem) string {
+	if todo.CalDAVName != "" {
+		return todo.CalDAVName
+	}
+	return fmt.Sprintf("todo-%d.ics", todo.ID)
+}
+
+// caldavUID is the UID a todo is served with
+func caldavUID(todo *models.TodoItem) string {
+	if todo.ExternalUID != "" {
+		return todo.ExternalUID
+	}
+	return calendarUID("todo", todo.ID)
+}
+
+// caldavETag changes whenever the todo does. Postgres keeps microseconds,
+// so that is all the tag uses.
+func caldavETag(todo *models.TodoItem) string {
+	return fmt.Sprintf(`"%d"`, todo.UpdatedAt.UnixMicro())
+}
+
+// caldavData encodes a todo as a calendar resource
+func caldavData(todo *models.TodoItem) ([]byte, error) {
+	var buf bytes.Buffer
+	err := ical.Write(&buf, ical.Calendar{Todos: []ical.Todo{icalTodo(*todo, caldavUID(todo))}})
+	return buf.Bytes(), err
+}
+
+// findCalDAVTodo looks up a todo, with its tags, by its resource name
+func (h *PlannerHandler) findCalDAVTodo(userID uint, name string) (*models.TodoItem, error) {
+	var id uint
+	if _, err := fmt.Sscanf(name, "todo-%d.ics", &id); err == nil && name == fmt.Sprintf("todo-%d.ics", id) {
+		var todo models.TodoItem
+		err := h.db.DB.Preload("Tags").Where("id = ? AND user_id = ? AND caldav_name = ''", id, userID).First(&todo).Error
+		if err == nil {
+			return &todo, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	}
+	return h.db.FindTodoByCalDAVName(userID, name)
+}
+
+// CalDAV serves the user's todos as a CalDAV (RFC 4791) task list, so
+// reminders apps can sync them both ways. Clients authenticate with HTTP
+// Basic auth; each user can only reach their own collection.
+func (h *PlannerHandler) CalDAV(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if c.Request.Method == http.MethodOptions {
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", caldavAllow)
+		c.Status(http.StatusOK)
+		return
+	}
+
+	path, ok := parseDAVPath(c.Param("path"))
+	// Other users' principals and calendars don't exist as far as a client can tell
+	if !ok || (path.Kind != davRoot && path.UserID != userID.(uint)) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	switch c.Request.Method {
+	case "PROPFIND":
+		h.caldavPropfind(c, path)
+	case "REPORT":
+		h.caldavReport(c, path)
+	case http.MethodGet, http.MethodHead:
+		h.caldavGet(c, path)
+	case http.MethodPut:
+		h.caldavPut(c, path)
+	case http.MethodDelete:
+		h.caldavDelete(c, path)
+	default:
+		c.Header("Allow", caldavAllow)
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// collectionProps are the properties of everything but a todo
+func (h *PlannerHandler) collectionProps(user *models.User, kind string) ([]davProp, error) {
+	props := []davProp{
+		{Name: propUserPrincipal, Inner: hrefXML(principalHref(user.ID))},
+	}
+
+	switch kind {
+	case davRoot:
+		props = append(props, davProp{Name: propResourceType, Inner: "<d:collection/>"})
+	case davPrincipal:
+		props = append(props,
+			davProp{Name: propResourceType, Inner: "<d:principal/>"},
+			davProp{Name: propDisplayName, Inner: xmlEscape(user.Username)},
+			davProp{Name: propPrincipalURL, Inner: hrefXML(principalHref(user.ID))},
+			davProp{Name: propCalendarHome, Inner: hrefXML(calendarHomeHref(user.ID))},
+		)
+		if user.Email != "" {
+			props = append(props, davProp{Name: propUserAddresses, Inner: hrefXML("mailto:" + user.Email)})
+		}
+	case davHome:
+		props = append(props, davProp{Name: propResourceType, Inner: "<d:collection/>"})
+	case davCollection:
+		ctag, err := h.db.CalDAVCollectionTag(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props,
+			davProp{Name: propResourceType, Inner: "<d:collection/><c:calendar/>"},
+			davProp{Name: propDisplayName, Inner: "Daily Planner"},
+			davProp{Name: propComponents, Inner: `<c:comp name="VTODO"/>`},
+			davProp{Name: propCTag, Inner: xmlEscape(ctag)},
+			davProp{Name: propPrivileges, Inner: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege><d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"},
+			davProp{Name: propSupportedReports, Inner: "<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report><d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>"},
+		)
+	}
+	return props, nil
+}
+
+// todoResponse describes one todo; calendar-data is only built when asked for
+func todoResponse(userID uint, todo *models.TodoItem, withData bool) (davResponse, error) {
+	response := davResponse{
+		Href: todoCollectionHref(userID) + caldavName(todo),
+		Props: []davProp{
+			{Name: propResourceType},
+			{Name: propETag, Inner: xmlEscape(caldavETag(todo))},
+			{Name: propContentType, Inner: "text/calendar; charset=utf-8; component=VTODO"},
+			{Name: propLastModified, Inner: todo.UpdatedAt.UTC().Format(http.TimeFormat)},
+		},
+	}
+	if withData {
+		data, err := caldavData(todo)
+		if err != nil {
+			return response, err
+		}
+		response.Props = append(response.Props, davProp{Name: propCalendarData, Inner: xmlEscape(string(data))})
+	}
+	return response, nil
+}
+
+// wantsCalendarData reports whether a request names calendar-data
+func wantsCalendarData(requested []xml.Name) bool {
+	for _, name := range requested {
+		if name == propCalendarData {
+			return true
+		}
+	}
+	return false
+}
+
+// readDAVBody parses a PROPFIND or REPORT body, answering the request itself if it can't
+func readDAVBody(c *gin.Context) (*xmlNode, bool) {
+	body, err := parseXMLBody(c.Request.Body)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid XML body")
+		return nil, false
+	}
+	return body, true
+}
+
+func (h *PlannerHandler) caldavPropfind(c *gin.Context, path davPath) {
+	userID, _ := c.Get("user_id")
+
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	requested := requestedProps(body)
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// Depth: infinity is treated as 1; nothing here is nested deeper than that
+	depth := c.GetHeader("Depth")
+	var responses []davResponse
+
+	if path.Kind == davTodo {
+		todo, err := h.findCalDAVTodo(user.ID, path.Name)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		response, err := todoResponse(user.ID, todo, wantsCalendarData(requested))
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		writeMultistatus(c, []davResponse{response}, requested)
+		return
+	}
+
+	props, err := h.collectionProps(user, path.Kind)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	href := strings.TrimSuffix(caldavRoot+strings.TrimPrefix(c.Param("path"), "/"), "/") + "/"
+	responses = append(responses, davResponse{Href: href, Props: props})
+
+	if depth != "0" {
+		switch path.Kind {
+		case davHome:
+			props, err := h.collectionProps(user, davCollection)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, davResponse{Href: todoCollectionHref(user.ID), Props: props})
+		case davCollection:
+			todos, err := h.db.FindCalDAVTodos(user.ID)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for i := range todos {
+				response, err := todoResponse(user.ID, &todos[i], wantsCalendarData(requested))
+				if err != nil {
+					c.Status(http.StatusInternalServerError)
+					return
+				}
+				responses = append(responses, response)
+			}
+		}
+	}
+
+	writeMultistatus(c, responses, requested)
+}
+
+// caldavReport answers calendar-multiget and calendar-query reports on the
+// todo collection. Queries match every todo unless they filter on a
+// component other than VTODO; property and time-range filters aren't applied.
+func (h *PlannerHandler) caldavReport(c *gin.Context, path davPath) {
+	userID, _ := c.Get("user_id")
+
+	body, ok := readDAVBody(c)
+	if !ok {
+		return
+	}
+	if body == nil || path.Kind != davCollection {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+		return
+	}
+	requested := requestedProps(body)
+	withData := wantsCalendarData(requested)
+
+	var responses []davResponse
+	switch {
+	case body.Name.Space == nsCalDAV && body.Name.Local == "calendar-multiget":
+		for _, node := range body.Children {
+			if node.Name.Space != nsDAV || node.Name.Local != "href" {
+				continue
+			}
+			href := strings.TrimSpace(node.Text)
+			name := href[strings.LastIndex(href, "/")+1:]
+			todo, err := h.findCalDAVTodo(userID.(uint), name)
+			if err != nil {
+				responses = append(responses, davResponse{Href: href, Status: http.StatusNotFound})
+				continue
+			}
+			response, err := todoResponse(userID.(uint), todo, withData)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			responses = append(responses, response)
+		}
+
+	case body.Name.Space == nsCalDAV && body.Name.Local == "calendar-query":
+		matches := true
+		body.child(nsCalDAV, "filter").walk(func(n *xmlNode) {
+			if n.Name.Space != nsCalDAV || n.Name.Local != "comp-filter" {
+				return
+			}
+			if name := n.attr("name"); !strings.EqualFold(name, "VCALENDAR") && !strings.EqualFold(name, "VTODO") {
+				matches = false
+			}
+		})
+		if matches {
+			todos, err := h.db.FindCalDAVTodos(userID.(uint))
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for i := range todos {
+				response, err := todoResponse(userID.(uint), &todos[i], withData)
+				if err != nil {
+					c.Status(http.StatusInternalServerError)
+					return
+				}
+				responses = append(responses, response)
+			}
+		}
+
+	default:
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+		return
+	}
+
+	writeMultistatus(c, responses, requested)
+}
+
+func (h *PlannerHandler) caldavGet(c *gin.Context, path davPath) {
+	userID, _ := c.Get("user_id")
+
+	if path.Kind != davTodo {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	todo, err := h.findCalDAVTodo(userID.(uint), path.Name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	data, err := caldavData(todo)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", caldavETag(todo))
+	c.Header("Last-Modified", todo.UpdatedAt.UTC().Format(http.TimeFormat))
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Type", "text/calendar; charset=utf-8")
+		c.Status(http.StatusOK)
+		return
+	}
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", data)
+}
+
+// caldavPreconditions checks If-Match and If-None-Match against the todo,
+// which is nil when the resource doesn't exist yet
+func caldavPreconditions(c *gin.Context, todo *models.TodoItem) bool {
+	if match := c.GetHeader("If-Match"); match != "" {
+		if todo == nil || (match != "*" && !etagListed(match, caldavETag(todo))) {
+			return false
+		}
+	}
+	if noneMatch := c.GetHeader("If-None-Match"); noneMatch != "" && todo != nil {
+		if noneMatch == "*" || etagListed(noneMatch, caldavETag(todo)) {
+			return false
+		}
+	}
+	return true
+}
+
+func etagListed(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// caldavPut creates or replaces a todo from the VTODO in the request body
+func (h *PlannerHandler) caldavPut(c *gin.Context, path davPath) {
+	userID, _ := c.Get("user_id")
+
+	if path.Kind != davTodo {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	todo, err := h.findCalDAVTodo(userID.(uint), path.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		todo = nil
+	} else if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	if !caldavPreconditions(c, todo) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	loc := user.Location()
+
+	cal, err := ical.Parse(io.LimitReader(c.Request.Body, maxDAVBodyBytes), loc)
+	if err != nil {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"})
+		return
+	}
+	if len(cal.Todos) != 1 || len(cal.Events) != 0 {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "supported-calendar-component"})
+		return
+	}
+	entry := cal.Todos[0]
+	if entry.UID == "" {
+		writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-calendar-object-resource"})
+		return
+	}
+
+	created := todo == nil
+	if created {
+		todo = &models.TodoItem{
+			UserID:      user.ID,
+			DueDate:     user.Today(time.Now()),
+			ExternalUID: entry.UID,
+			CalDAVName:  path.Name,
+		}
+	}
+
+	todo.Title = orUntitled(entry.Summary)
+	todo.Description = entry.Description
+	todo.Completed = entry.Completed
+	todo.PriorityLevel = todoPriority(entry.Priority)
+	switch {
+	case entry.Due != nil:
+		todo.DueDate = dayOf(*entry.Due, entry.DueAllDay, loc)
+	case entry.Start != nil:
+		todo.DueDate = dayOf(*entry.Start, entry.StartAllDay, loc)
+	}
+
+	if err := h.db.SaveCalDAVTodo(todo); err != nil {
+		if errors.Is(err, repository.ErrUIDConflict) {
+			writeDAVError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "no-uid-conflict"})
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", caldavETag(todo))
+	if created {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *PlannerHandler) caldavDelete(c *gin.Context, path davPath) {
+	userID, _ := c.Get("user_id")
+
+	if path.Kind != davTodo {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	todo, err := h.findCalDAVTodo(userID.(uint), path.Name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if !caldavPreconditions(c, todo) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	if err := h.db.DeleteTodoAndPriorities(todo); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// WellKnownCalDAV points clients that only know the server's address at the CalDAV root
+func (h *PlannerHandler) WellKnownCalDAV(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, caldavRoot)
+}
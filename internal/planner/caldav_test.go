@@ -0,0 +1,374 @@
+package planner
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"github.com/himanshu/daily-planner/internal/testdb"
+	"github.com/himanshu/daily-planner/pkg/middleware"
+)
+
+func TestParseDAVPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want davPath
+		ok   bool
+	}{
+		{path: "/", want: davPath{Kind: davRoot}, ok: true},
+		{path: "", want: davPath{Kind: davRoot}, ok: true},
+		{path: "/principals/7/", want: davPath{Kind: davPrincipal, UserID: 7}, ok: true},
+		{path: "/calendars/7", want: davPath{Kind: davHome, UserID: 7}, ok: true},
+		{path: "/calendars/7/todos/", want: davPath{Kind: davCollection, UserID: 7}, ok: true},
+		{path: "/calendars/7/todos/abc.ics", want: davPath{Kind: davTodo, UserID: 7, Name: "abc.ics"}, ok: true},
+		{path: "/calendars/ann/todos/"},
+		{path: "/calendars/7/events/"},
+		{path: "/calendars/7/todos/a/b"},
+		{path: "/principals/"},
+		{path: "/other/7/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := parseDAVPath(tt.path)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseDAVPath(%q) = %+v, %v, want %+v, %v", tt.path, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestETagListed(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"42"`, want: true},
+		{header: `W/"42"`, want: true},
+		{header: `"7", "42"`, want: true},
+		{header: `"7"`},
+		{header: `42`},
+		{header: ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := etagListed(tt.header, `"42"`); got != tt.want {
+				t.Errorf("etagListed(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+// caldavPassword is the password every CalDAV test user logs in with
+const caldavPassword = "correct horse"
+
+// caldavServer is the CalDAV endpoint as routes.go mounts it, behind Basic auth
+type caldavServer struct {
+	t      *testing.T
+	db     *repository.Database
+	router *gin.Engine
+	user   *models.User
+}
+
+func newCalDAVServer(t *testing.T) *caldavServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t)
+	h := NewPlannerHandler(db.WithSource(models.ActivitySourceAPI), time.Hour)
+
+	router := gin.New()
+	group := router.Group("/caldav", middleware.BasicAuth(db))
+	for _, method := range []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"} {
+		group.Handle(method, "/*path", h.CalDAV)
+	}
+	return &caldavServer{t: t, db: db, router: router, user: testdb.User(t, db, caldavPassword)}
+}
+
+// do sends a request as the server's user; headers are name, value pairs
+func (s *caldavServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth(s.user.Username, caldavPassword)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// todo saves a todo made in the planner rather than over CalDAV
+func (s *caldavServer) todo(title string) *models.TodoItem {
+	s.t.Helper()
+	todo := &models.TodoItem{
+		UserID:   s.user.ID,
+		Title:    title,
+		DueDate:  time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC),
+		Position: 1,
+	}
+	if err := s.db.CreateTodo(todo); err != nil {
+		s.t.Fatal(err)
+	}
+	return todo
+}
+
+func (s *caldavServer) collection() string {
+	return fmt.Sprintf("/caldav/calendars/%d/todos/", s.user.ID)
+}
+
+// multistatus is the part of a 207 response the tests look at
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Status   string `xml:"DAV: status"`
+		Propstat []struct {
+			Prop struct {
+				ETag         string `xml:"DAV: getetag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+			Status string `xml:"DAV: status"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+func readMultistatus(t *testing.T, w *httptest.ResponseRecorder) multistatus {
+	t.Helper()
+	if w.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusMultiStatus, w.Body)
+	}
+	var ms multistatus
+	if err := xml.Unmarshal(w.Body.Bytes(), &ms); err != nil {
+		t.Fatalf("invalid multistatus: %v: %s", err, w.Body)
+	}
+	return ms
+}
+
+// hrefs lists each response's href, with its status when it has one
+func (ms multistatus) hrefs() []string {
+	var hrefs []string
+	for _, r := range ms.Responses {
+		if r.Status != "" {
+			hrefs = append(hrefs, r.Href+" "+r.Status)
+			continue
+		}
+		hrefs = append(hrefs, r.Href)
+	}
+	return hrefs
+}
+
+const (
+	propfindData = `<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><d:getetag/><c:calendar-data/></d:prop></d:propfind>`
+	propfindETag = `<d:propfind xmlns:d="DAV:"><d:prop><d:getetag/></d:prop></d:propfind>`
+)
+
+func TestCalDAVBasicAuth(t *testing.T) {
+	s := newCalDAVServer(t)
+	google := testdb.User(t, s.db, "")
+
+	tests := []struct {
+		name            string
+		login, password string
+		noAuth          bool
+		want            int
+	}{
+		{name: "no credentials", noAuth: true, want: http.StatusUnauthorized},
+		{name: "username", login: s.user.Username, password: caldavPassword, want: http.StatusMultiStatus},
+		{name: "email", login: s.user.Email, password: caldavPassword, want: http.StatusMultiStatus},
+		{name: "wrong password", login: s.user.Username, password: "wrong", want: http.StatusUnauthorized},
+		{name: "unknown user", login: "nobody", password: caldavPassword, want: http.StatusUnauthorized},
+		{name: "account without a password", login: google.Username, password: "", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PROPFIND", "/caldav/", strings.NewReader(propfindETag))
+			req.Header.Set("Depth", "0")
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.login, tt.password)
+			}
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); (tt.want == http.StatusUnauthorized) != strings.HasPrefix(challenge, "Basic ") {
+				t.Errorf("WWW-Authenticate = %q", challenge)
+			}
+		})
+	}
+}
+
+func TestCalDAVPropfind(t *testing.T) {
+	s := newCalDAVServer(t)
+	todo := s.todo("Write report")
+	todoHref := s.collection() + fmt.Sprintf("todo-%d.ics", todo.ID)
+
+	tests := []struct {
+		name  string
+		path  string
+		depth string
+		hrefs []string
+	}{
+		{name: "collection alone", path: s.collection(), depth: "0", hrefs: []string{s.collection()}},
+		{name: "collection and its todos", path: s.collection(), depth: "1", hrefs: []string{s.collection(), todoHref}},
+		{name: "a todo", path: todoHref, depth: "0", hrefs: []string{todoHref}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := readMultistatus(t, s.do("PROPFIND", tt.path, propfindData, "Depth", tt.depth))
+			if got := ms.hrefs(); !reflect.DeepEqual(got, tt.hrefs) {
+				t.Fatalf("hrefs = %v, want %v", got, tt.hrefs)
+			}
+
+			for _, r := range ms.Responses {
+				if r.Href != todoHref {
+					continue
+				}
+				prop := r.Propstat[0].Prop
+				if prop.ETag != caldavETag(todo) {
+					t.Errorf("getetag = %q, want %q", prop.ETag, caldavETag(todo))
+				}
+				if !strings.Contains(prop.CalendarData, "SUMMARY:Write report") {
+					t.Errorf("calendar-data = %q, want the todo's summary", prop.CalendarData)
+				}
+			}
+		})
+	}
+
+	t.Run("another user's collection", func(t *testing.T) {
+		other := testdb.User(t, s.db, caldavPassword)
+		path := fmt.Sprintf("/caldav/calendars/%d/todos/", other.ID)
+		if w := s.do("PROPFIND", path, propfindETag, "Depth", "1"); w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestCalDAVReport(t *testing.T) {
+	s := newCalDAVServer(t)
+	first, second := s.todo("Write report"), s.todo("Call Ann")
+	href := func(todo *models.TodoItem) string {
+		return s.collection() + fmt.Sprintf("todo-%d.ics", todo.ID)
+	}
+	missing := s.collection() + "missing.ics"
+
+	query := func(component string) string {
+		return `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+			`<d:prop><d:getetag/></d:prop>` +
+			`<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="` + component + `"/></c:comp-filter></c:filter>` +
+			`</c:calendar-query>`
+	}
+
+	tests := []struct {
+		name  string
+		body  string
+		hrefs []string
+	}{
+		{name: "query for todos", body: query("VTODO"), hrefs: []string{href(first), href(second)}},
+		{name: "query for events", body: query("VEVENT")},
+		{
+			name: "multiget",
+			body: `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+				`<d:prop><d:getetag/><c:calendar-data/></d:prop>` +
+				`<d:href>` + href(second) + `</d:href><d:href>` + missing + `</d:href>` +
+				`</c:calendar-multiget>`,
+			hrefs: []string{href(second), missing + " HTTP/1.1 404 Not Found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ms := readMultistatus(t, s.do("REPORT", s.collection(), tt.body, "Depth", "1"))
+			if got := ms.hrefs(); !reflect.DeepEqual(got, tt.hrefs) {
+				t.Errorf("hrefs = %v, want %v", got, tt.hrefs)
+			}
+		})
+	}
+
+	t.Run("unsupported report", func(t *testing.T) {
+		body := `<d:sync-collection xmlns:d="DAV:"><d:prop><d:getetag/></d:prop></d:sync-collection>`
+		if w := s.do("REPORT", s.collection(), body); w.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestCalDAVPutAndDelete(t *testing.T) {
+	s := newCalDAVServer(t)
+	path := s.collection() + "milk.ics"
+	vtodo := func(summary string) string {
+		return strings.Join([]string{
+			"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//EN",
+			"BEGIN:VTODO", "UID:milk@example.com", "SUMMARY:" + summary, "DUE;VALUE=DATE:20250314",
+			"END:VTODO", "END:VCALENDAR", "",
+		}, "\r\n")
+	}
+
+	// Each step builds on the last, so the ETag from one is used in the next
+	var etag, stale string
+	steps := []struct {
+		name    string
+		method  string
+		body    string
+		headers func() []string
+		want    int
+	}{
+		{name: "create", method: http.MethodPut, body: vtodo("Buy milk"), headers: func() []string { return []string{"If-None-Match", "*"} }, want: http.StatusCreated},
+		{name: "create again", method: http.MethodPut, body: vtodo("Buy milk"), headers: func() []string { return []string{"If-None-Match", "*"} }, want: http.StatusPreconditionFailed},
+		{name: "get", method: http.MethodGet, want: http.StatusOK},
+		{name: "update", method: http.MethodPut, body: vtodo("Buy oat milk"), headers: func() []string { return []string{"If-Match", etag} }, want: http.StatusNoContent},
+		{name: "update with a stale ETag", method: http.MethodPut, body: vtodo("Buy soy milk"), headers: func() []string { return []string{"If-Match", stale} }, want: http.StatusPreconditionFailed},
+		{name: "update without a UID", method: http.MethodPut, body: strings.Replace(vtodo("Buy milk"), "UID:milk@example.com\r\n", "", 1), want: http.StatusForbidden},
+		{name: "delete with a stale ETag", method: http.MethodDelete, headers: func() []string { return []string{"If-Match", stale} }, want: http.StatusPreconditionFailed},
+		{name: "delete", method: http.MethodDelete, headers: func() []string { return []string{"If-Match", etag} }, want: http.StatusNoContent},
+		{name: "get after delete", method: http.MethodGet, want: http.StatusNotFound},
+		{name: "delete again", method: http.MethodDelete, want: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		var headers []string
+		if step.headers != nil {
+			headers = step.headers()
+		}
+		w := s.do(step.method, path, step.body, headers...)
+		if w.Code != step.want {
+			t.Fatalf("%s: status = %d, want %d: %s", step.name, w.Code, step.want, w.Body)
+		}
+
+		switch step.name {
+		case "create", "update":
+			got := w.Header().Get("ETag")
+			if got == "" || got == etag {
+				t.Fatalf("%s: ETag = %q, want a new one", step.name, got)
+			}
+			stale, etag = etag, got
+		case "get":
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("get: ETag = %q, want %q from the PUT", got, etag)
+			}
+			if !strings.Contains(w.Body.String(), "UID:milk@example.com") || !strings.Contains(w.Body.String(), "SUMMARY:Buy milk") {
+				t.Errorf("get: body = %q, want the todo as it was put", w.Body)
+			}
+		}
+	}
+
+	// The update's title must have been saved before the todo was deleted
+	var todo models.TodoItem
+	if err := s.db.DB.Unscoped().Where("user_id = ? AND caldav_name = ?", s.user.ID, "milk.ics").First(&todo).Error; err != nil {
+		t.Fatal(err)
+	}
+	if todo.Title != "Buy oat milk" || !todo.DeletedAt.Valid {
+		t.Errorf("todo = %q, deleted %v, want %q, deleted", todo.Title, todo.DeletedAt.Valid, "Buy oat milk")
+	}
+}
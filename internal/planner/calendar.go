@@ -0,0 +1,244 @@
+package planner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/ical"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// calendarFeedHistoryDays is how far back the calendar feed reaches
+const calendarFeedHistoryDays = 90
+
+// newSecretToken returns a random, URL-safe token for links that work without logging in
+func newSecretToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// baseURL is the scheme and host the request came in on
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// calendarFeedURL is the address calendar apps subscribe to
+func calendarFeedURL(c *gin.Context, token string) string {
+	return fmt.Sprintf("%s/calendar/%s.ics", baseURL(c), token)
+}
+
+// calendarUID builds a stable UID for a planner item, e.g. todo-12@daily-planner
+func calendarUID(kind string, id uint) string {
+	return fmt.Sprintf("%s-%d@daily-planner", kind, id)
+}
+
+// icalPriority maps P1-P3 onto iCalendar's 1-9 scale; P4 is left undefined
+func icalPriority(level int) int {
+	switch level {
+	case models.TodoPriorityP1:
+		return 1
+	case models.TodoPriorityP2:
+		return 3
+	case models.TodoPriorityP3:
+		return 5
+	}
+	return 0
+}
+
+// inZone reads a stored wall-clock time, which the planner keeps as UTC, as
+// that time of day in loc
+func inZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+}
+
+// icalTodo converts a todo, with its tags loaded, to a VTODO
+func icalTodo(todo models.TodoItem, uid string) ical.Todo {
+	due := todo.DueDate
+	entry := ical.Todo{
+		UID:         uid,
+		Summary:     todo.Title,
+		Description: todo.Description,
+		Due:         &due,
+		DueAllDay:   true,
+		Completed:   todo.Completed,
+		Priority:    icalPriority(todo.PriorityLevel),
+		Modified:    todo.UpdatedAt,
+	}
+	if todo.Completed {
+		entry.CompletedAt = &todo.UpdatedAt
+	}
+	for _, tag := range todo.Tags {
+		entry.Categories = append(entry.Categories, tag.Name)
+	}
+	return entry
+}
+
+// buildCalendar collects the user's todos, priorities, follow-up reminders
+// and time blocks from since onwards
+func (h *PlannerHandler) buildCalendar(user *models.User, since time.Time) (ical.Calendar, error) {
+	loc := user.Location()
+	cal := ical.Calendar{Name: "Daily Planner", Timezone: loc.String(), Feed: true}
+
+	todos, err := h.db.FindCalendarTodos(user.ID, since)
+	if err != nil {
+		return cal, err
+	}
+	for _, todo := range todos {
+		cal.Todos = append(cal.Todos, icalTodo(todo, calendarUID("todo", todo.ID)))
+	}
+
+	priorities, err := h.db.FindPrioritiesSince(user.ID, since)
+	if err != nil {
+		return cal, err
+	}
+	rankPriorities(priorities)
+	for _, priority := range priorities {
+		// A promoted todo is already in the feed as itself
+		if priority.TodoItemID != nil {
+			continue
+		}
+		date := priority.Date
+		entry := ical.Todo{
+			UID:         calendarUID("priority", priority.ID),
+			Summary:     priority.Title,
+			Description: priority.Description,
+			Due:         &date,
+			DueAllDay:   true,
+			Completed:   priority.Completed,
+			Priority:    1,
+			Categories:  []string{"Priority"},
+			Modified:    priority.UpdatedAt,
+		}
+		if priority.Completed {
+			entry.CompletedAt = &priority.UpdatedAt
+		}
+		cal.Todos = append(cal.Todos, entry)
+	}
+
+	contacts, err := h.db.FindContactsSince(user.ID, since)
+	if err != nil {
+		return cal, err
+	}
+	for _, contact := range contacts {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         calendarUID("contact", contact.ID),
+			Summary:     fmt.Sprintf("%s %s", contact.Type, contact.Name),
+			Description: contact.Description,
+			Start:       contact.Date,
+			End:         contact.Date.AddDate(0, 0, 1),
+			AllDay:      true,
+			Categories:  []string{"Follow-up"},
+			Modified:    contact.UpdatedAt,
+		})
+	}
+
+	blocks, err := h.db.FindTimeBlocksSince(user.ID, since)
+	if err != nil {
+		return cal, err
+	}
+	for _, block := range blocks {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:      calendarUID("time-block", block.ID),
+			Summary:  block.Title,
+			Start:    inZone(block.StartAt, loc),
+			End:      inZone(block.EndAt, loc),
+			Busy:     true,
+			Modified: block.UpdatedAt,
+		})
+	}
+
+	return cal, nil
+}
+
+// CalendarFeed serves a user's planner as an iCalendar subscription. It is
+// public; the secret token in the URL identifies the user.
+func (h *PlannerHandler) CalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	user, err := h.db.FindUserByCalendarToken(token)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Calendar not found"})
+		return
+	}
+
+	cal, err := h.buildCalendar(user, user.Today(time.Now()).AddDate(0, 0, -calendarFeedHistoryDays))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build calendar"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Content-Disposition", `inline; filename="daily-planner.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// GetCalendarFeed handles retrieving the user's feed URL, if the feed is on
+func (h *PlannerHandler) GetCalendarFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch calendar feed"})
+		return
+	}
+
+	if user.CalendarToken == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "url": calendarFeedURL(c, *user.CalendarToken)})
+}
+
+// RegenerateCalendarToken handles turning the feed on or giving it a new URL.
+// Subscriptions to the old URL stop working.
+func (h *PlannerHandler) RegenerateCalendarToken(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	token, err := newSecretToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	if err := h.db.UpdateCalendarToken(userID.(uint), &token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "url": calendarFeedURL(c, token)})
+}
+
+// DisableCalendarFeed handles turning the feed off
+func (h *PlannerHandler) DisableCalendarFeed(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.db.UpdateCalendarToken(userID.(uint), nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": false})
+}
@@ -0,0 +1,521 @@
+package planner
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/ical"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// Recurring events are expanded this many days ahead unless ?to= says otherwise
+const calendarImportDefaultDays = 90
+
+// importUpdate is the action for an entry that changes an earlier import
+const importUpdate = "update"
+
+// Where imported events go
+const (
+	eventsAuto      = "auto"      // Timed events to the schedule, all-day events to reminders
+	eventsSchedule  = "schedule"  // Time blocks only; all-day events are skipped
+	eventsReminders = "reminders" // Follow-up reminders only
+)
+
+// Kinds of planner item an entry becomes
+const (
+	importTodo      = "todo"
+	importTimeBlock = "timeBlock"
+	importContact   = "contact"
+)
+
+// calendarImportResult says what an import did, or would do in a dry run, with one entry
+type calendarImportResult struct {
+	UID      string   `json:"uid"`
+	Title    string   `json:"title"`
+	Date     string   `json:"date"`
+	Kind     string   `json:"kind"`   // todo, timeBlock or contact
+	Action   string   `json:"action"` // create, update or skip
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// calendarImporter turns parsed calendar entries into planner items,
+// matching them to earlier imports by UID
+type calendarImporter struct {
+	userID   uint
+	loc      *time.Location
+	mode     string
+	batch    repository.CalendarImport
+	todos    map[string]*models.TodoItem
+	blocks   map[string]*models.TimeBlock
+	contacts map[string]*models.Contact
+	people   map[string]*models.Person // By lower-case name
+	results  []calendarImportResult
+	counts   map[string]int
+}
+
+// wallClock is the inverse of inZone: it stores t's time of day in its own
+// zone the way the planner keeps times, as UTC
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+// dayOf returns the planner date an entry falls on in loc. All-day values
+// are already dates.
+func dayOf(t time.Time, allDay bool, loc *time.Location) time.Time {
+	if allDay {
+		return t
+	}
+	return wallClock(t.In(loc)).Truncate(24 * time.Hour)
+}
+
+// entryUID returns the entry's UID, or one derived from its content when
+// the file gives none, so that re-importing the same file stays idempotent
+func entryUID(uid, summary string, start time.Time) string {
+	if uid != "" {
+		return uid
+	}
+	sum := sha1.Sum([]byte(summary + "|" + start.UTC().Format(time.RFC3339)))
+	return "nouid-" + hex.EncodeToString(sum[:8])
+}
+
+// occurrenceUID identifies one occurrence of a recurring event
+func occurrenceUID(uid string, start time.Time, allDay bool) string {
+	if allDay {
+		return uid + "/" + start.Format("20060102")
+	}
+	return uid + "/" + start.UTC().Format("20060102T150405Z")
+}
+
+// todoPriority maps iCalendar's 1-9 priority onto P1-P4
+func todoPriority(priority int) int {
+	switch {
+	case priority >= 1 && priority <= 2:
+		return models.TodoPriorityP1
+	case priority >= 3 && priority <= 4:
+		return models.TodoPriorityP2
+	case priority >= 5 && priority <= 6:
+		return models.TodoPriorityP3
+	}
+	return models.TodoPriorityP4
+}
+
+func orUntitled(summary string) string {
+	if summary = strings.TrimSpace(summary); summary != "" {
+		return summary
+	}
+	return "(No title)"
+}
+
+func (imp *calendarImporter) record(result calendarImportResult) {
+	imp.counts[result.Action]++
+	imp.results = append(imp.results, result)
+}
+
+// addTodo maps a VTODO onto a todo, dated by its DUE or else its DTSTART
+func (imp *calendarImporter) addTodo(entry ical.Todo) {
+	result := calendarImportResult{Kind: importTodo, Title: orUntitled(entry.Summary)}
+
+	var due time.Time
+	switch {
+	case entry.Due != nil:
+		due = dayOf(*entry.Due, entry.DueAllDay, imp.loc)
+	case entry.Start != nil:
+		due = dayOf(*entry.Start, entry.StartAllDay, imp.loc)
+	default:
+		due = dayOf(time.Now(), false, imp.loc)
+		result.Warnings = append(result.Warnings, "No due date; due today")
+	}
+	result.Date = due.Format(dateLayout)
+
+	var start time.Time
+	if entry.Start != nil {
+		start = *entry.Start
+	}
+	result.UID = entryUID(entry.UID, entry.Summary, start)
+
+	todo, ok := imp.todos[result.UID]
+	if !ok {
+		todo = &models.TodoItem{UserID: imp.userID, ExternalUID: result.UID}
+		imp.todos[result.UID] = todo
+		result.Action = importCreate
+	} else if todo.DeletedAt.Valid {
+		result.Action = importSkip
+		result.Warnings = append(result.Warnings, "Deleted in the planner")
+		imp.record(result)
+		return
+	} else {
+		result.Action = importUpdate
+	}
+
+	before := *todo
+	todo.Title = result.Title
+	todo.Description = entry.Description
+	todo.DueDate = due
+	todo.PriorityLevel = todoPriority(entry.Priority)
+	// Never reopen a todo the user has completed in the planner
+	if entry.Completed {
+		todo.Completed = true
+	}
+
+	if result.Action == importUpdate && before.Title == todo.Title && before.Description == todo.Description &&
+		before.DueDate.Equal(todo.DueDate) && before.PriorityLevel == todo.PriorityLevel && before.Completed == todo.Completed {
+		result.Action = importSkip
+		imp.record(result)
+		return
+	}
+
+	imp.batch.Todos = append(imp.batch.Todos, todo)
+	if len(entry.Categories) > 0 {
+		imp.batch.TodoTags[todo] = entry.Categories
+	}
+	imp.record(result)
+}
+
+// addEvent maps one occurrence of a VEVENT onto a time block or a follow-up
+// reminder, depending on the import mode
+func (imp *calendarImporter) addEvent(entry ical.Event, uid string, start, end time.Time, warnings []string) {
+	result := calendarImportResult{
+		UID:      uid,
+		Title:    orUntitled(entry.Summary),
+		Date:     dayOf(start, entry.AllDay, imp.loc).Format(dateLayout),
+		Kind:     imp.eventKind(entry),
+		Warnings: append([]string(nil), warnings...),
+	}
+
+	switch {
+	case result.Kind == importContact:
+		imp.addContact(entry, start, result)
+	case entry.AllDay:
+		result.Action = importSkip
+		result.Warnings = append(result.Warnings, "All-day events can't go on the schedule")
+		imp.record(result)
+	default:
+		imp.addTimeBlock(start, end, result)
+	}
+}
+
+// eventKind says whether an event becomes a time block or a follow-up reminder
+func (imp *calendarImporter) eventKind(entry ical.Event) string {
+	if imp.mode == eventsSchedule || (imp.mode == eventsAuto && !entry.AllDay) {
+		return importTimeBlock
+	}
+	return importContact
+}
+
+func (imp *calendarImporter) addTimeBlock(start, end time.Time, result calendarImportResult) {
+	date := dayOf(start, false, imp.loc)
+	startAt := wallClock(start.In(imp.loc))
+	endAt := wallClock(end.In(imp.loc))
+	if !endAt.After(startAt) {
+		endAt = startAt.Add(30 * time.Minute)
+		result.Warnings = append(result.Warnings, "No end time; scheduled for 30 minutes")
+	}
+	if dayEnd := date.AddDate(0, 0, 1); endAt.After(dayEnd) {
+		endAt = dayEnd
+		result.Warnings = append(result.Warnings, "Runs past midnight; cut off at 24:00")
+	}
+
+	block, ok := imp.blocks[result.UID]
+	if !ok {
+		block = &models.TimeBlock{UserID: imp.userID, ExternalUID: result.UID}
+		imp.blocks[result.UID] = block
+		result.Action = importCreate
+	} else if block.DeletedAt.Valid {
+		result.Action = importSkip
+		result.Warnings = append(result.Warnings, "Deleted in the planner")
+		imp.record(result)
+		return
+	} else {
+		result.Action = importUpdate
+	}
+
+	before := *block
+	block.Date = date
+	block.StartAt = startAt
+	block.EndAt = endAt
+	block.Title = result.Title
+
+	if result.Action == importUpdate && before.Title == block.Title && before.Date.Equal(block.Date) &&
+		before.StartAt.Equal(block.StartAt) && before.EndAt.Equal(block.EndAt) {
+		result.Action = importSkip
+		imp.record(result)
+		return
+	}
+
+	imp.batch.TimeBlocks = append(imp.batch.TimeBlocks, block)
+	imp.record(result)
+}
+
+func (imp *calendarImporter) addContact(entry ical.Event, start time.Time, result calendarImportResult) {
+	// The reminder is for the first named attendee, or failing that the event itself
+	name := result.Title
+	description := entry.Description
+	if len(entry.Attendees) > 0 {
+		name = entry.Attendees[0]
+		description = strings.TrimSpace(result.Title + "\n" + entry.Description)
+	}
+
+	contact, ok := imp.contacts[result.UID]
+	if !ok {
+		contact = &models.Contact{UserID: imp.userID, Type: models.ContactTypeMeeting, ExternalUID: result.UID}
+		imp.contacts[result.UID] = contact
+		result.Action = importCreate
+	} else if contact.DeletedAt.Valid {
+		result.Action = importSkip
+		result.Warnings = append(result.Warnings, "Deleted in the planner")
+		imp.record(result)
+		return
+	} else {
+		result.Action = importUpdate
+	}
+
+	before := *contact
+	contact.Name = name
+	contact.Description = description
+	contact.Date = dayOf(start, entry.AllDay, imp.loc)
+	if person, ok := imp.people[strings.ToLower(name)]; ok {
+		contact.PersonID = &person.ID
+	}
+
+	if result.Action == importUpdate && before.Name == contact.Name && before.Description == contact.Description && before.Date.Equal(contact.Date) {
+		result.Action = importSkip
+		imp.record(result)
+		return
+	}
+
+	imp.batch.Contacts = append(imp.batch.Contacts, contact)
+	imp.record(result)
+}
+
+// addEvents expands each event's recurrence within [from, to) and applies
+// edited and cancelled occurrences
+func (imp *calendarImporter) addEvents(events []ical.Event, from, to time.Time) {
+	// Edited occurrences, by the occurrence they replace
+	overrides := make(map[string]ical.Event)
+	masters := make(map[string]bool)
+	for _, entry := range events {
+		uid := entryUID(entry.UID, entry.Summary, entry.Start)
+		if entry.RecurrenceID != nil {
+			overrides[occurrenceUID(uid, *entry.RecurrenceID, entry.AllDay)] = entry
+		} else {
+			masters[uid] = true
+		}
+	}
+
+	// The window in the event's own terms; all-day values are UTC dates
+	window := func(entry ical.Event) (time.Time, time.Time) {
+		if entry.AllDay {
+			return from, to
+		}
+		return time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, imp.loc),
+			time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, imp.loc)
+	}
+
+	used := make(map[string]bool)
+	for _, entry := range events {
+		if entry.RecurrenceID != nil {
+			continue
+		}
+		uid := entryUID(entry.UID, entry.Summary, entry.Start)
+		if entry.Status == ical.StatusCancelled {
+			imp.record(calendarImportResult{
+				UID:      uid,
+				Title:    orUntitled(entry.Summary),
+				Date:     dayOf(entry.Start, entry.AllDay, imp.loc).Format(dateLayout),
+				Kind:     imp.eventKind(entry),
+				Action:   importSkip,
+				Warnings: []string{"Cancelled"},
+			})
+			continue
+		}
+
+		if entry.Recurrence == nil {
+			imp.addEvent(entry, uid, entry.Start, entry.EndTime(), nil)
+			continue
+		}
+
+		var warnings []string
+		if parts := entry.Recurrence.Unsupported(); len(parts) > 0 {
+			warnings = append(warnings, "Ignored unsupported recurrence rule parts: "+strings.Join(parts, ", "))
+		}
+		duration := entry.EndTime().Sub(entry.Start)
+		windowFrom, windowTo := window(entry)
+		for _, start := range entry.Occurrences(windowFrom, windowTo) {
+			key := occurrenceUID(uid, start, entry.AllDay)
+			occurrence, end := entry, start.Add(duration)
+			if override, ok := overrides[key]; ok {
+				used[key] = true
+				if override.Status == ical.StatusCancelled {
+					continue
+				}
+				occurrence, start, end = override, override.Start, override.EndTime()
+			}
+			imp.addEvent(occurrence, key, start, end, warnings)
+		}
+	}
+
+	// Edited occurrences moved into the window, or sent without their series
+	for key, override := range overrides {
+		if used[key] || override.Status == ical.StatusCancelled {
+			continue
+		}
+		windowFrom, windowTo := window(override)
+		uid := entryUID(override.UID, override.Summary, override.Start)
+		if masters[uid] && (override.Start.Before(windowFrom) || !override.Start.Before(windowTo)) {
+			continue
+		}
+		imp.addEvent(override, key, override.Start, override.EndTime(), nil)
+	}
+}
+
+// parseImportWindow reads the from/to query parameters bounding recurring
+// events, defaulting to the next calendarImportDefaultDays days
+func (h *PlannerHandler) parseImportWindow(c *gin.Context, userID uint) (time.Time, time.Time, error) {
+	from := h.today(userID)
+	to := from.AddDate(0, 0, calendarImportDefaultDays)
+
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errInvalidDateRange
+		}
+		from = parsed
+	}
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errInvalidDateRange
+		}
+		to = parsed
+	}
+
+	if from.After(to) || to.Sub(from) > maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errInvalidDateRange
+	}
+	// Include the whole of the last day
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// ImportCalendar handles importing an .ics file. VTODOs become todos and
+// VEVENTs become time blocks or follow-up reminders (?events=auto, schedule
+// or reminders). Recurring events are expanded between ?from= and ?to=.
+// Entries are matched to earlier imports by UID, so importing the same file
+// again updates rather than duplicates. With ?dryRun=true nothing is saved.
+func (h *PlannerHandler) ImportCalendar(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	dryRun := c.Query("dryRun") == "true"
+
+	mode := c.DefaultQuery("events", eventsAuto)
+	if mode != eventsAuto && mode != eventsSchedule && mode != eventsReminders {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "events must be auto, schedule or reminders"})
+		return
+	}
+	from, to, err := h.parseImportWindow(c, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, err := readUpload(c, maxImportBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import calendar"})
+		return
+	}
+	loc := user.Location()
+
+	cal, err := ical.Parse(bytes.NewReader(data), loc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid iCalendar file: " + err.Error()})
+		return
+	}
+
+	imp, err := h.newCalendarImporter(user.ID, loc, mode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import calendar"})
+		return
+	}
+	for _, todo := range cal.Todos {
+		imp.addTodo(todo)
+	}
+	imp.addEvents(cal.Events, from, to)
+
+	if !dryRun {
+		if err := h.db.ImportCalendar(user.ID, imp.batch); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import calendar"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":  dryRun,
+		"from":    from.Format(dateLayout),
+		"to":      to.AddDate(0, 0, -1).Format(dateLayout),
+		"created": imp.counts[importCreate],
+		"updated": imp.counts[importUpdate],
+		"skipped": imp.counts[importSkip],
+		"results": imp.results,
+	})
+}
+
+// newCalendarImporter loads what earlier imports created and the contact
+// book that reminders are matched against
+func (h *PlannerHandler) newCalendarImporter(userID uint, loc *time.Location, mode string) (*calendarImporter, error) {
+	imp := &calendarImporter{
+		userID:   userID,
+		loc:      loc,
+		mode:     mode,
+		batch:    repository.CalendarImport{TodoTags: make(map[*models.TodoItem][]string)},
+		todos:    make(map[string]*models.TodoItem),
+		blocks:   make(map[string]*models.TimeBlock),
+		contacts: make(map[string]*models.Contact),
+		people:   make(map[string]*models.Person),
+		results:  make([]calendarImportResult, 0),
+		counts:   map[string]int{importCreate: 0, importUpdate: 0, importSkip: 0},
+	}
+
+	todos, err := h.db.FindImportedTodos(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range todos {
+		imp.todos[todos[i].ExternalUID] = &todos[i]
+	}
+
+	blocks, err := h.db.FindImportedTimeBlocks(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		imp.blocks[blocks[i].ExternalUID] = &blocks[i]
+	}
+
+	contacts, err := h.db.FindImportedContacts(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contacts {
+		imp.contacts[contacts[i].ExternalUID] = &contacts[i]
+	}
+
+	people, err := h.db.FindPeople(userID, repository.PersonFilter{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range people {
+		imp.people[strings.ToLower(people[i].Name)] = &people[i]
+	}
+
+	return imp, nil
+}
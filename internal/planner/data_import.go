@@ -0,0 +1,296 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/dataimport"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// maxDataImportBytes caps the size of an uploaded archive or export; they
+// can hold years of data, so they may be much bigger than a contacts file
+const maxDataImportBytes = 50 << 20
+
+// readDataImport parses the uploaded file in the requested format, or the
+// one its content suggests
+func readDataImport(c *gin.Context, data []byte) (*dataimport.Batch, error) {
+	format := c.Query("format")
+	if format == "" {
+		format = dataimport.Detect(data)
+	}
+
+	switch format {
+	case dataimport.FormatArchive:
+		return dataimport.ReadArchive(data)
+	case dataimport.FormatTodoist:
+		return dataimport.ReadTodoist(data)
+	case dataimport.FormatTrello:
+		return dataimport.ReadTrello(data)
+	case dataimport.FormatCSV:
+		raw := c.Query("mapping")
+		if raw == "" && strings.HasPrefix(c.ContentType(), "multipart/") {
+			raw = c.PostForm("mapping")
+		}
+		var mapping map[string]string
+		if raw != "" {
+			if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+				return nil, errors.New("mapping must be a JSON object of field to column name, e.g. {\"title\": \"Task\"}")
+			}
+		}
+		return dataimport.ReadCSV(bytes.NewReader(data), c.DefaultQuery("entity", dataimport.EntityTodos), mapping)
+	}
+	return nil, errors.New("format must be archive, csv, todoist or trello")
+}
+
+// dataImportPlan is what an import will save, and the report of it
+type dataImportPlan struct {
+	batch       repository.DataImport
+	created     map[string]int
+	skipped     map[string]int
+	newTags     []string
+	newProjects []string
+	limit       int                  // The user's daily priority limit
+	problems    []dataimport.Problem // Records the user's data rules out
+}
+
+// planDataImport turns a checked batch into planner records. Days that
+// already have a thought or a water count keep them; the import's are skipped.
+// Priorities that would take a day past the user's limit are problems.
+func (h *PlannerHandler) planDataImport(userID uint, b *dataimport.Batch) (*dataImportPlan, error) {
+	plan := &dataImportPlan{
+		batch: repository.DataImport{
+			TodoTags:      make(map[*models.TodoItem][]string),
+			TodoProjects:  make(map[*models.TodoItem]string),
+			PriorityTodos: make(map[*models.Priority]*models.TodoItem),
+		},
+		created:     map[string]int{"subtasks": 0},
+		skipped:     make(map[string]int),
+		newTags:     make([]string, 0),
+		newProjects: make([]string, 0),
+	}
+	for _, entity := range []string{dataimport.EntityTodos, dataimport.EntityPriorities, dataimport.EntityContacts, dataimport.EntityWaterIntake, dataimport.EntityThoughts} {
+		plan.created[entity] = 0
+		plan.skipped[entity] = 0
+	}
+
+	tags, err := h.db.FindTagsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	knownTags := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		knownTags[strings.ToLower(tag.Name)] = true
+	}
+	projects, err := h.db.FindProjectsByUserID(userID, true)
+	if err != nil {
+		return nil, err
+	}
+	knownProjects := make(map[string]bool, len(projects))
+	for _, project := range projects {
+		knownProjects[strings.ToLower(project.Name)] = true
+	}
+
+	today := h.today(userID)
+	todosByRef := make(map[string]*models.TodoItem, len(b.Todos))
+	for _, item := range b.Todos {
+		todo := &models.TodoItem{
+			Title:         strings.TrimSpace(item.Title),
+			Description:   item.Description,
+			DueDate:       today,
+			Completed:     item.Completed,
+			PriorityLevel: item.Priority,
+		}
+		if item.DueDate != nil {
+			todo.DueDate = *item.DueDate
+		}
+		if todo.PriorityLevel == 0 {
+			todo.PriorityLevel = models.TodoPriorityP4
+		}
+		for _, subtask := range item.Subtasks {
+			todo.Subtasks = append(todo.Subtasks, models.Subtask{Title: strings.TrimSpace(subtask.Title), Completed: subtask.Completed})
+		}
+
+		for _, name := range item.Tags {
+			if key := strings.ToLower(name); !knownTags[key] {
+				knownTags[key] = true
+				plan.newTags = append(plan.newTags, name)
+			}
+		}
+		if item.Project != "" {
+			if key := strings.ToLower(item.Project); !knownProjects[key] {
+				knownProjects[key] = true
+				plan.newProjects = append(plan.newProjects, item.Project)
+			}
+		}
+
+		plan.batch.Todos = append(plan.batch.Todos, todo)
+		plan.batch.TodoTags[todo] = item.Tags
+		plan.batch.TodoProjects[todo] = item.Project
+		if item.Ref != "" {
+			todosByRef[item.Ref] = todo
+		}
+		plan.created[dataimport.EntityTodos]++
+		plan.created["subtasks"] += len(todo.Subtasks)
+	}
+
+	var perDay map[string]int
+	if len(b.Priorities) > 0 {
+		if plan.limit, err = h.priorityLimit(userID); err != nil {
+			return nil, err
+		}
+		if perDay, err = h.db.CountPrioritiesByDay(userID); err != nil {
+			return nil, err
+		}
+	}
+	for i, item := range b.Priorities {
+		day := item.Date.Format(dateLayout)
+		if perDay[day] >= plan.limit {
+			plan.problems = append(plan.problems, dataimport.Problem{
+				Entity:  dataimport.EntityPriorities,
+				Record:  i + 1,
+				Field:   "date",
+				Message: fmt.Sprintf("%s would have more than %d priorities, your daily limit", day, plan.limit),
+			})
+		}
+		perDay[day]++
+
+		priority := &models.Priority{
+			Title:       strings.TrimSpace(item.Title),
+			Description: item.Description,
+			Date:        item.Date,
+			Completed:   item.Completed,
+		}
+		if item.TodoRef != "" {
+			// A promoted todo holds the title and completion
+			priority.Title, priority.Description, priority.Completed = "", "", false
+			plan.batch.PriorityTodos[priority] = todosByRef[item.TodoRef]
+		}
+		plan.batch.Priorities = append(plan.batch.Priorities, priority)
+		plan.created[dataimport.EntityPriorities]++
+	}
+
+	for _, item := range b.Contacts {
+		plan.batch.Contacts = append(plan.batch.Contacts, &models.Contact{
+			Name:        strings.TrimSpace(item.Name),
+			Type:        item.Type,
+			Description: item.Description,
+			Date:        item.Date,
+			Completed:   item.Completed,
+		})
+		plan.created[dataimport.EntityContacts]++
+	}
+
+	if len(b.Water) > 0 {
+		days, err := h.db.FindWaterDays(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range b.Water {
+			if days[item.Date.Format(dateLayout)] {
+				plan.skipped[dataimport.EntityWaterIntake]++
+				continue
+			}
+			plan.batch.Water = append(plan.batch.Water, models.HabitCheckIn{Date: item.Date, Value: item.Glasses})
+			plan.created[dataimport.EntityWaterIntake]++
+		}
+	}
+
+	if len(b.Thoughts) > 0 {
+		days, err := h.db.FindThoughtDays(userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range b.Thoughts {
+			if days[item.Date.Format(dateLayout)] {
+				plan.skipped[dataimport.EntityThoughts]++
+				continue
+			}
+			plan.batch.Thoughts = append(plan.batch.Thoughts, &models.Thought{Date: item.Date, Content: strings.TrimSpace(item.Content)})
+			plan.created[dataimport.EntityThoughts]++
+		}
+	}
+
+	sort.Strings(plan.newTags)
+	sort.Strings(plan.newProjects)
+	return plan, nil
+}
+
+// ImportData handles importing a "download my data" archive, a CSV file
+// (?entity= picks what its rows are; ?mapping= names the columns) or a
+// Todoist or Trello JSON export. The file is checked in full first: if any
+// record has a problem, nothing is imported. ?dryRun=true reports what
+// would be created without saving it.
+func (h *PlannerHandler) ImportData(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	dryRun := c.Query("dryRun") == "true"
+
+	data, err := readUpload(c, maxDataImportBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	batch, err := readDataImport(c, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Can't read the file: " + err.Error()})
+		return
+	}
+
+	if problems := batch.Validate(); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Nothing was imported; fix these problems and try again",
+			"format":   batch.Format,
+			"problems": problems,
+		})
+		return
+	}
+
+	plan, err := h.planDataImport(userID.(uint), batch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import data"})
+		return
+	}
+	if len(plan.problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":    "Nothing was imported; fix these problems and try again",
+			"format":   batch.Format,
+			"problems": plan.problems,
+		})
+		return
+	}
+
+	if !dryRun {
+		err := h.db.ImportData(userID.(uint), plan.batch, plan.limit)
+		if errors.Is(err, repository.ErrPriorityLimit) || errors.Is(err, repository.ErrAlreadyPriority) {
+			// Priorities were added since the plan was checked
+			c.JSON(http.StatusConflict, gin.H{"error": "Your priorities changed while importing; nothing was imported, try again"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import data; nothing was imported"})
+			return
+		}
+	}
+
+	warnings := batch.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":      dryRun,
+		"format":      batch.Format,
+		"created":     plan.created,
+		"skipped":     plan.skipped,
+		"newTags":     plan.newTags,
+		"newProjects": plan.newProjects,
+		"warnings":    warnings,
+	})
+}
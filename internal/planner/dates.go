@@ -0,0 +1,58 @@
+package planner
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxRangeDays caps how many days a single range query may span
+const maxRangeDays = 366
+
+var errInvalidDateRange = errors.New("Invalid date range. Use from/to as YYYY-MM-DD with from <= to")
+
+// today returns the day it currently is in the user's time zone, as
+// midnight UTC the way the planner stores dates. Every "today" in the
+// planner comes from here, so items are saved to and looked up on the same
+// day. It falls back to the day in UTC if the user's settings can't be read.
+func (h *PlannerHandler) today(userID uint) time.Time {
+	user, err := h.db.FindUserByID(userID)
+	if err != nil {
+		log.Printf("Error fetching time zone: %v", err)
+		user = &models.User{}
+	}
+	return user.Today(time.Now())
+}
+
+// parseDateRange reads the from/to query parameters, defaulting to the
+// defaultDays days ending the user's today when they are omitted
+func (h *PlannerHandler) parseDateRange(c *gin.Context, userID uint, defaultDays int) (time.Time, time.Time, error) {
+	to := h.today(userID)
+	from := to.AddDate(0, 0, -(defaultDays - 1))
+
+	if v := c.Query("from"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errInvalidDateRange
+		}
+		from = parsed
+	}
+	if v := c.Query("to"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			return time.Time{}, time.Time{}, errInvalidDateRange
+		}
+		to = parsed
+	}
+
+	if from.After(to) || to.Sub(from) > maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, errInvalidDateRange
+	}
+
+	return from, to, nil
+}
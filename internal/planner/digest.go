@@ -0,0 +1,147 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// digestTimeLayout is how send times are written, e.g. 07:30
+const digestTimeLayout = "15:04"
+
+// digestTime formats minutes after midnight as a time of day
+func digestTime(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseDigestTime reads a time of day as minutes after midnight
+func parseDigestTime(value string) (int, error) {
+	t, err := time.Parse(digestTimeLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time %q. Use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// GetDigestSettings handles retrieving which daily emails the user gets and
+// when, in their time zone
+func (h *PlannerHandler) GetDigestSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"morning":   user.DigestMorning,
+		"morningAt": digestTime(user.DigestMorningAt),
+		"evening":   user.DigestEvening,
+		"eveningAt": digestTime(user.DigestEveningAt),
+		"timezone":  user.Location().String(),
+	})
+}
+
+// UpdateDigestSettings handles turning the morning and evening emails on
+// or off and choosing when they are sent
+func (h *PlannerHandler) UpdateDigestSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var settingsData struct {
+		Morning   *bool   `json:"morning"`
+		MorningAt *string `json:"morningAt"`
+		Evening   *bool   `json:"evening"`
+		EveningAt *string `json:"eveningAt"`
+	}
+	if err := c.ShouldBindJSON(&settingsData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*string{"digest_morning_at": settingsData.MorningAt, "digest_evening_at": settingsData.EveningAt} {
+		if value == nil {
+			continue
+		}
+		minutes, err := parseDigestTime(*value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates[column] = minutes
+	}
+	if settingsData.Morning != nil {
+		updates["digest_morning"] = *settingsData.Morning
+	}
+	if settingsData.Evening != nil {
+		updates["digest_evening"] = *settingsData.Evening
+	}
+
+	// The unsubscribe link needs a token; it is made once and kept
+	if settingsData.Morning != nil && *settingsData.Morning || settingsData.Evening != nil && *settingsData.Evening {
+		user, err := h.db.FindUserByID(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+			return
+		}
+		if user.DigestToken == nil {
+			token, err := newSecretToken()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+				return
+			}
+			updates["digest_token"] = token
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+			return
+		}
+	}
+
+	h.GetDigestSettings(c)
+}
+
+// ShowDigestUnsubscribe renders the page an email's unsubscribe link opens,
+// which asks before turning the emails off so that link scanners don't
+func (h *PlannerHandler) ShowDigestUnsubscribe(c *gin.Context) {
+	user, err := h.db.FindUserByDigestToken(c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "unsubscribe.html", gin.H{"Title": "Unsubscribe", "NotFound": true})
+		return
+	}
+
+	c.HTML(http.StatusOK, "unsubscribe.html", gin.H{
+		"Title":      "Unsubscribe",
+		"Email":      user.Email,
+		"Subscribed": user.DigestMorning || user.DigestEvening,
+	})
+}
+
+// UnsubscribeDigest handles turning off a user's daily emails from the
+// unsubscribe page or a mail app's one-click unsubscribe. It is public; the
+// secret token in the URL identifies the user.
+func (h *PlannerHandler) UnsubscribeDigest(c *gin.Context) {
+	user, err := h.db.FindUserByDigestToken(c.Param("token"))
+	if err != nil {
+		c.HTML(http.StatusNotFound, "unsubscribe.html", gin.H{"Title": "Unsubscribe", "NotFound": true})
+		return
+	}
+
+	if err := h.db.UnsubscribeDigest(user.ID); err != nil {
+		c.HTML(http.StatusInternalServerError, "unsubscribe.html", gin.H{"Title": "Unsubscribe", "Email": user.Email, "Subscribed": true, "Failed": true})
+		return
+	}
+
+	c.HTML(http.StatusOK, "unsubscribe.html", gin.H{
+		"Title":        "Unsubscribe",
+		"Email":        user.Email,
+		"Unsubscribed": true,
+	})
+}
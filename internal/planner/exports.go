@@ -0,0 +1,118 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// dataExportJSON describes an export, with its download link once it is ready
+func dataExportJSON(c *gin.Context, export *models.DataExport) gin.H {
+	response := gin.H{
+		"id":        export.ID,
+		"status":    export.Status,
+		"createdAt": export.CreatedAt,
+	}
+	if export.Status == models.DataExportReady && export.ExpiresAt != nil {
+		response["size"] = export.Size
+		response["expiresAt"] = export.ExpiresAt
+		response["downloadUrl"] = fmt.Sprintf("%s/exports/%s", baseURL(c), export.Token)
+	}
+	if export.Error != "" {
+		response["error"] = export.Error
+	}
+	return response
+}
+
+// RequestDataExport handles asking for a "download my data" archive. The
+// archive is built in the background; poll GetDataExport until it is ready.
+// Asking again while an export is under way returns that export.
+func (h *PlannerHandler) RequestDataExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if existing, err := h.db.FindUnfinishedDataExport(userID.(uint)); err == nil {
+		c.JSON(http.StatusAccepted, dataExportJSON(c, existing))
+		return
+	}
+
+	token, err := newSecretToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request export"})
+		return
+	}
+
+	export := models.DataExport{
+		UserID: userID.(uint),
+		Status: models.DataExportPending,
+		Token:  token,
+	}
+	if err := h.db.CreateDataExport(&export); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request export"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, dataExportJSON(c, &export))
+}
+
+// GetDataExports handles listing the user's exports, newest first
+func (h *PlannerHandler) GetDataExports(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	exports, err := h.db.FindDataExportsByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exports"})
+		return
+	}
+
+	response := make([]gin.H, 0, len(exports))
+	for i := range exports {
+		response = append(response, dataExportJSON(c, &exports[i]))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDataExport handles checking on one export
+func (h *PlannerHandler) GetDataExport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	export, err := h.db.FindDataExportByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dataExportJSON(c, export))
+}
+
+// DownloadDataExport serves a finished archive. It is public; the secret
+// token in the link identifies the export, and the link stops working
+// after models.DataExportTTL.
+func (h *PlannerHandler) DownloadDataExport(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".zip")
+
+	export, err := h.db.FindDataExportByToken(token)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if export.ExpiresAt == nil || time.Now().After(*export.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "This download link has expired"})
+		return
+	}
+
+	filename := fmt.Sprintf("daily-planner-export-%s.zip", export.CreatedAt.Format(dateLayout))
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/zip", export.Archive)
+}
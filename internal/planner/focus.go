@@ -0,0 +1,368 @@
+package planner
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// defaultFocusMinutes is the length of a classic pomodoro
+const defaultFocusMinutes = 25
+
+// focusSessionView is a focus session with its live elapsed time
+type focusSessionView struct {
+	models.FocusSession
+	ElapsedSeconds int `json:"elapsedSeconds"`
+}
+
+// focusTaskTotal is one row of the time-per-task report
+type focusTaskTotal struct {
+	TodoItemID *uint  `json:"todoItemId,omitempty"`
+	PriorityID *uint  `json:"priorityId,omitempty"`
+	Title      string `json:"title"`
+	Sessions   int    `json:"sessions"`
+	Seconds    int    `json:"seconds"`
+}
+
+// elapsedSeconds returns the time a session has spent running as of now
+func elapsedSeconds(session models.FocusSession, now time.Time) int {
+	elapsed := session.DurationSeconds
+	if session.Status == models.FocusStatusRunning && session.ResumedAt != nil {
+		elapsed += int(now.Sub(*session.ResumedAt).Seconds())
+	}
+	return elapsed
+}
+
+func viewFocusSession(session models.FocusSession, now time.Time) focusSessionView {
+	return focusSessionView{FocusSession: session, ElapsedSeconds: elapsedSeconds(session, now)}
+}
+
+// totalFocusSeconds sums the focus time of the given sessions
+func totalFocusSeconds(sessions []models.FocusSession, now time.Time) int {
+	total := 0
+	for _, session := range sessions {
+		total += elapsedSeconds(session, now)
+	}
+	return total
+}
+
+func (h *PlannerHandler) findUserFocusSession(c *gin.Context) (*models.FocusSession, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Focus session not found"})
+		return nil, false
+	}
+
+	session, err := h.db.FindFocusSessionByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Focus session not found"})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// StartFocusSession handles starting a new focus timer
+func (h *PlannerHandler) StartFocusSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var sessionData struct {
+		TodoItemID     *uint  `json:"todoId"`
+		PriorityID     *uint  `json:"priorityId"`
+		Label          string `json:"label"`
+		PlannedMinutes int    `json:"plannedMinutes" binding:"min=0,max=480"`
+	}
+	if err := c.ShouldBindJSON(&sessionData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if sessionData.TodoItemID != nil && sessionData.PriorityID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link a session to a todo or a priority, not both"})
+		return
+	}
+
+	// Only one timer may run at a time
+	if active, err := h.db.FindActiveFocusSession(userID.(uint)); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another focus session is already active", "session": viewFocusSession(*active, time.Now())})
+		return
+	}
+
+	label := sessionData.Label
+	if sessionData.TodoItemID != nil {
+		var todo models.TodoItem
+		if err := h.db.DB.Where("id = ? AND user_id = ?", *sessionData.TodoItemID, userID).First(&todo).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+			return
+		}
+		if label == "" {
+			label = todo.Title
+		}
+	}
+	if sessionData.PriorityID != nil {
+		var priority models.Priority
+		if err := h.db.DB.Preload("TodoItem").Where("id = ? AND user_id = ?", *sessionData.PriorityID, userID).First(&priority).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Priority not found"})
+			return
+		}
+		if label == "" {
+			label = priority.Title
+			if priority.TodoItem != nil {
+				label = priority.TodoItem.Title
+			}
+		}
+	}
+
+	planned := sessionData.PlannedMinutes
+	if planned == 0 {
+		planned = defaultFocusMinutes
+	}
+
+	now := time.Now()
+	session := models.FocusSession{
+		UserID:         userID.(uint),
+		TodoItemID:     sessionData.TodoItemID,
+		PriorityID:     sessionData.PriorityID,
+		Label:          label,
+		Status:         models.FocusStatusRunning,
+		PlannedMinutes: planned,
+		StartedAt:      now,
+		ResumedAt:      &now,
+	}
+
+	if err := h.db.CreateFocusSession(&session); err != nil {
+		// Another start got in between the check above and here
+		if errors.Is(err, repository.ErrFocusSessionActive) {
+			response := gin.H{"error": "Another focus session is already active"}
+			if active, err := h.db.FindActiveFocusSession(userID.(uint)); err == nil {
+				response["session"] = viewFocusSession(*active, time.Now())
+			}
+			c.JSON(http.StatusConflict, response)
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start focus session"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, viewFocusSession(session, now))
+}
+
+// PauseFocusSession handles pausing a running focus timer
+func (h *PlannerHandler) PauseFocusSession(c *gin.Context) {
+	session, ok := h.findUserFocusSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.FocusStatusRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only a running session can be paused"})
+		return
+	}
+
+	now := time.Now()
+	session.DurationSeconds = elapsedSeconds(*session, now)
+	session.ResumedAt = nil
+	session.Status = models.FocusStatusPaused
+
+	if err := h.db.UpdateFocusSession(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause focus session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, viewFocusSession(*session, now))
+}
+
+// ResumeFocusSession handles resuming a paused focus timer
+func (h *PlannerHandler) ResumeFocusSession(c *gin.Context) {
+	session, ok := h.findUserFocusSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status != models.FocusStatusPaused {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only a paused session can be resumed"})
+		return
+	}
+
+	now := time.Now()
+	session.ResumedAt = &now
+	session.Status = models.FocusStatusRunning
+
+	if err := h.db.UpdateFocusSession(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume focus session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, viewFocusSession(*session, now))
+}
+
+// StopFocusSession handles stopping a focus timer and recording its duration
+func (h *PlannerHandler) StopFocusSession(c *gin.Context) {
+	session, ok := h.findUserFocusSession(c)
+	if !ok {
+		return
+	}
+
+	if session.Status == models.FocusStatusStopped {
+		c.JSON(http.StatusConflict, gin.H{"error": "Focus session is already stopped"})
+		return
+	}
+
+	now := time.Now()
+	session.DurationSeconds = elapsedSeconds(*session, now)
+	session.ResumedAt = nil
+	session.EndedAt = &now
+	session.Status = models.FocusStatusStopped
+
+	if err := h.db.UpdateFocusSession(session); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop focus session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, viewFocusSession(*session, now))
+}
+
+// GetActiveFocusSession handles retrieving the currently running or paused session
+func (h *PlannerHandler) GetActiveFocusSession(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	session, err := h.db.FindActiveFocusSession(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No active focus session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, viewFocusSession(*session, time.Now()))
+}
+
+// GetFocusSessions handles retrieving session history over a date range
+func (h *PlannerHandler) GetFocusSessions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	from, to, err := h.parseDateRange(c, userID.(uint), 7)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, err := h.db.FindFocusSessionsByDateRange(userID.(uint), from, to.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch focus sessions"})
+		return
+	}
+
+	now := time.Now()
+	views := make([]focusSessionView, 0, len(sessions))
+	dailyTotals := make(map[string]int)
+	for _, session := range sessions {
+		view := viewFocusSession(session, now)
+		views = append(views, view)
+		dailyTotals[session.StartedAt.Format(dateLayout)] += view.ElapsedSeconds
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":        from.Format(dateLayout),
+		"to":          to.Format(dateLayout),
+		"sessions":    views,
+		"dailyTotals": dailyTotals,
+	})
+}
+
+// GetFocusReport handles reporting time spent per todo and priority over a date range
+func (h *PlannerHandler) GetFocusReport(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	from, to, err := h.parseDateRange(c, userID.(uint), 7)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	end := to.AddDate(0, 0, 1)
+
+	totals, err := h.db.SumFocusSecondsByTask(userID.(uint), from, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build focus report"})
+		return
+	}
+
+	// The running segment of an active session is not stored yet
+	now := time.Now()
+	active, activeErr := h.db.FindActiveFocusSession(userID.(uint))
+	hasLiveTime := activeErr == nil && active.Status == models.FocusStatusRunning &&
+		!active.StartedAt.Before(from) && active.StartedAt.Before(end)
+
+	report := make([]focusTaskTotal, 0, len(totals))
+	grandTotal := 0
+	for _, total := range totals {
+		row := focusTaskTotal{
+			TodoItemID: total.TodoItemID,
+			PriorityID: total.PriorityID,
+			Title:      "Unlinked sessions",
+			Sessions:   total.Sessions,
+			Seconds:    total.Seconds,
+		}
+
+		if hasLiveTime && sameTask(active.TodoItemID, total.TodoItemID) && sameTask(active.PriorityID, total.PriorityID) {
+			row.Seconds = total.Seconds - active.DurationSeconds + elapsedSeconds(*active, now)
+		}
+
+		switch {
+		case total.TodoItemID != nil:
+			var todo models.TodoItem
+			if err := h.db.DB.Unscoped().Select("title").First(&todo, *total.TodoItemID).Error; err == nil {
+				row.Title = todo.Title
+			}
+		case total.PriorityID != nil:
+			var priority models.Priority
+			if err := h.db.DB.Unscoped().Preload("TodoItem", func(tx *gorm.DB) *gorm.DB {
+				return tx.Unscoped()
+			}).First(&priority, *total.PriorityID).Error; err == nil {
+				row.Title = priority.Title
+				if priority.TodoItem != nil {
+					row.Title = priority.TodoItem.Title
+				}
+			}
+		}
+
+		grandTotal += row.Seconds
+		report = append(report, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":         from.Format(dateLayout),
+		"to":           to.Format(dateLayout),
+		"totalSeconds": grandTotal,
+		"tasks":        report,
+	})
+}
+
+// DeleteFocusSession handles deleting a focus session from the history
+func (h *PlannerHandler) DeleteFocusSession(c *gin.Context) {
+	session, ok := h.findUserFocusSession(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteFocusSession(session.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete focus session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Focus session deleted successfully"})
+}
+
+// sameTask reports whether two optional task IDs refer to the same task
+func sameTask(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
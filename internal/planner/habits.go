@@ -0,0 +1,536 @@
+package planner
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// maxHabitSlots is the largest target that is still rendered as clickable icons
+const maxHabitSlots = 20
+
+type habitData struct {
+	Name     string `json:"name"`
+	Icon     string `json:"icon"`
+	Unit     string `json:"unit"`
+	Kind     string `json:"kind"`
+	Period   string `json:"period"`
+	Target   int    `json:"target"`
+	Archived bool   `json:"archived"`
+}
+
+// habitSummary is a habit together with its progress for the current period
+type habitSummary struct {
+	models.Habit
+	Today         int  `json:"today"`
+	Progress      int  `json:"progress"`
+	Completed     bool `json:"completed"`
+	CurrentStreak int  `json:"currentStreak"` // Counted over the last streakDays
+	LongestStreak int  `json:"longestStreak"` // Counted over the last streakDays
+}
+
+// streakDays bounds how far back streaks are counted, so summaries read about
+// a year of check-ins however long a habit has been kept
+const streakDays = 366
+
+// streakWindow returns the first and last day, as YYYY-MM-DD, of the
+// check-ins streaks up to today are counted from. It starts on a Monday so
+// weekly habits only see whole weeks.
+func streakWindow(today time.Time) (string, string) {
+	return weekStart(today.AddDate(0, 0, -streakDays)).Format(dateLayout), today.Format(dateLayout)
+}
+
+// habitWidget is what the dashboard needs to render a habit like the water glasses
+type habitWidget struct {
+	habitSummary
+	Slots []int
+}
+
+type heatmapCell struct {
+	Date      string  `json:"date"`
+	Value     int     `json:"value"`
+	Ratio     float64 `json:"ratio"`
+	Completed bool    `json:"completed"`
+}
+
+func validateHabit(data *habitData) string {
+	if data.Name == "" {
+		return "Habit name is required"
+	}
+	if data.Kind == "" {
+		data.Kind = models.HabitKindCounter
+	}
+	if data.Period == "" {
+		data.Period = models.HabitPeriodDaily
+	}
+	if data.Kind != models.HabitKindCounter && data.Kind != models.HabitKindBoolean {
+		return "Kind must be counter or boolean"
+	}
+	if data.Period != models.HabitPeriodDaily && data.Period != models.HabitPeriodWeekly {
+		return "Period must be daily or weekly"
+	}
+	if data.Kind == models.HabitKindBoolean && data.Period == models.HabitPeriodDaily {
+		data.Target = 1
+	}
+	if data.Target < 1 {
+		return "Target must be at least 1"
+	}
+	if data.Kind == models.HabitKindBoolean && data.Target > 7 {
+		return "A weekly yes/no habit can target at most 7 days"
+	}
+	return ""
+}
+
+// weekStart returns the Monday of the week containing d
+func weekStart(d time.Time) time.Time {
+	offset := (int(d.Weekday()) + 6) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// periodStart returns the first day of the habit period containing d
+func periodStart(habit models.Habit, d time.Time) time.Time {
+	if habit.Period == models.HabitPeriodWeekly {
+		return weekStart(d)
+	}
+	return d
+}
+
+func periodDays(habit models.Habit) int {
+	if habit.Period == models.HabitPeriodWeekly {
+		return 7
+	}
+	return 1
+}
+
+// dayValue normalizes a check-in so yes/no habits count each day at most once
+func dayValue(habit models.Habit, value int) int {
+	if habit.Kind == models.HabitKindBoolean && value > 0 {
+		return 1
+	}
+	return value
+}
+
+// periodProgress sums the check-ins for the period starting at start
+func periodProgress(habit models.Habit, values map[string]int, start time.Time) int {
+	total := 0
+	for i := 0; i < periodDays(habit); i++ {
+		total += dayValue(habit, values[start.AddDate(0, 0, i).Format(dateLayout)])
+	}
+	return total
+}
+
+// habitStreaks returns the current and longest run of completed periods up to
+// asOf. The period containing asOf only extends the run once it is complete,
+// so an unfinished day or week does not break the current streak.
+func habitStreaks(habit models.Habit, values map[string]int, asOf time.Time) (int, int) {
+	var earliest time.Time
+	for key := range values {
+		d, err := time.Parse(dateLayout, key)
+		if err != nil {
+			continue
+		}
+		if earliest.IsZero() || d.Before(earliest) {
+			earliest = d
+		}
+	}
+	if earliest.IsZero() {
+		return 0, 0
+	}
+
+	end := periodStart(habit, asOf)
+	run, longest := 0, 0
+	for p := periodStart(habit, earliest); !p.After(end); p = p.AddDate(0, 0, periodDays(habit)) {
+		if periodProgress(habit, values, p) >= habit.Target {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else if !p.Equal(end) {
+			run = 0
+		}
+	}
+
+	return run, longest
+}
+
+// summarizeHabit computes progress and streaks for a habit from its check-ins
+func summarizeHabit(habit models.Habit, checkIns []models.HabitCheckIn, asOf time.Time) habitSummary {
+	values := make(map[string]int, len(checkIns))
+	for _, checkIn := range checkIns {
+		if checkIn.HabitID == habit.ID {
+			values[checkIn.Date.Format(dateLayout)] = checkIn.Value
+		}
+	}
+
+	progress := periodProgress(habit, values, periodStart(habit, asOf))
+	current, longest := habitStreaks(habit, values, asOf)
+
+	return habitSummary{
+		Habit:         habit,
+		Today:         dayValue(habit, values[asOf.Format(dateLayout)]),
+		Progress:      progress,
+		Completed:     progress >= habit.Target,
+		CurrentStreak: current,
+		LongestStreak: longest,
+	}
+}
+
+// loadHabitSummaries fetches the user's active habits with their current progress
+func (h *PlannerHandler) loadHabitSummaries(userID uint) ([]habitSummary, error) {
+	if _, err := h.db.FindOrCreateWaterHabit(userID); err != nil {
+		return nil, err
+	}
+
+	habits, err := h.db.FindHabitsByUserID(userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(habits))
+	for i, habit := range habits {
+		ids[i] = habit.ID
+	}
+	today := h.today(userID)
+	from, to := streakWindow(today)
+	checkIns, err := h.db.FindHabitCheckInsByDateRange(ids, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	byHabit := make(map[uint][]models.HabitCheckIn, len(habits))
+	for _, checkIn := range checkIns {
+		byHabit[checkIn.HabitID] = append(byHabit[checkIn.HabitID], checkIn)
+	}
+
+	summaries := make([]habitSummary, 0, len(habits))
+	for _, habit := range habits {
+		summaries = append(summaries, summarizeHabit(habit, byHabit[habit.ID], today))
+	}
+
+	return summaries, nil
+}
+
+// habitWidgets turns habit summaries into dashboard widgets
+func habitWidgets(summaries []habitSummary) []habitWidget {
+	widgets := make([]habitWidget, 0, len(summaries))
+	for _, summary := range summaries {
+		widget := habitWidget{habitSummary: summary}
+		if summary.Target <= maxHabitSlots {
+			widget.Slots = make([]int, summary.Target)
+			for i := range widget.Slots {
+				widget.Slots[i] = i
+			}
+		}
+		widgets = append(widgets, widget)
+	}
+	return widgets
+}
+
+func (h *PlannerHandler) findUserHabit(c *gin.Context) (*models.Habit, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return nil, false
+	}
+
+	habit, err := h.db.FindHabitByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Habit not found"})
+		return nil, false
+	}
+
+	return habit, true
+}
+
+// GetHabits handles retrieving the user's habits with today's progress
+func (h *PlannerHandler) GetHabits(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	summaries, err := h.loadHabitSummaries(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habits"})
+		return
+	}
+
+	if c.Query("archived") == "true" {
+		habits, err := h.db.FindHabitsByUserID(userID.(uint), true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch habits"})
+			return
+		}
+		for _, habit := range habits {
+			if habit.Archived {
+				summaries = append(summaries, habitSummary{Habit: habit})
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// CreateHabit handles creating a new user-defined habit
+func (h *PlannerHandler) CreateHabit(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var data habitData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateHabit(&data); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	habit := models.Habit{
+		UserID: userID.(uint),
+		Name:   data.Name,
+		Icon:   data.Icon,
+		Unit:   data.Unit,
+		Kind:   data.Kind,
+		Period: data.Period,
+		Target: data.Target,
+	}
+
+	if err := h.db.CreateHabit(&habit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create habit"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, habit)
+}
+
+// UpdateHabit handles updating a habit's definition
+func (h *PlannerHandler) UpdateHabit(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	data := habitData{
+		Name:     habit.Name,
+		Icon:     habit.Icon,
+		Unit:     habit.Unit,
+		Kind:     habit.Kind,
+		Period:   habit.Period,
+		Target:   habit.Target,
+		Archived: habit.Archived,
+	}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg := validateHabit(&data); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	habit.Name = data.Name
+	habit.Icon = data.Icon
+	habit.Unit = data.Unit
+	habit.Kind = data.Kind
+	habit.Period = data.Period
+	habit.Target = data.Target
+	habit.Archived = data.Archived
+
+	if err := h.db.UpdateHabit(habit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update habit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, habit)
+}
+
+// DeleteHabit handles deleting a user-defined habit
+func (h *PlannerHandler) DeleteHabit(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	if habit.SystemKey != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Built-in habits can be archived but not deleted"})
+		return
+	}
+
+	if err := h.db.DeleteHabit(habit.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete habit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Habit deleted successfully"})
+}
+
+// CheckInHabit handles recording a habit's value for a day
+func (h *PlannerHandler) CheckInHabit(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	var checkInData struct {
+		Date  string `json:"date"`
+		Value int    `json:"value" binding:"min=0"`
+	}
+	if err := c.ShouldBindJSON(&checkInData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := h.today(habit.UserID)
+	if checkInData.Date != "" {
+		parsed, err := time.Parse(dateLayout, checkInData.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	checkIn, err := h.saveHabitCheckIn(habit, date, dayValue(*habit, checkInData.Value))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save check-in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, checkIn)
+}
+
+// saveHabitCheckIn sets the habit's value for a day, creating the check-in if needed
+func (h *PlannerHandler) saveHabitCheckIn(habit *models.Habit, date time.Time, value int) (*models.HabitCheckIn, error) {
+	checkIn, err := h.db.FindHabitCheckInByDate(habit.ID, date.Format(dateLayout))
+	if err != nil {
+		checkIn = &models.HabitCheckIn{
+			UserID:  habit.UserID,
+			HabitID: habit.ID,
+			Date:    date,
+		}
+	}
+
+	checkIn.Value = value
+	if err := h.db.SaveHabitCheckIn(checkIn); err != nil {
+		return nil, err
+	}
+	return checkIn, nil
+}
+
+// GetHabitCheckIns handles retrieving a habit's check-ins over a date range
+func (h *PlannerHandler) GetHabitCheckIns(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := h.parseDateRange(c, habit.UserID, 30)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkIns, err := h.db.FindHabitCheckInsByDateRange([]uint{habit.ID}, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check-ins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, checkIns)
+}
+
+// GetHabitStats handles retrieving a habit's progress and streaks
+func (h *PlannerHandler) GetHabitStats(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	today := h.today(habit.UserID)
+	from, to := streakWindow(today)
+	checkIns, err := h.db.FindHabitCheckInsByDateRange([]uint{habit.ID}, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check-ins"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summarizeHabit(*habit, checkIns, today))
+}
+
+// GetHabitHeatmap handles retrieving per-day completion data for a calendar heatmap
+func (h *PlannerHandler) GetHabitHeatmap(c *gin.Context) {
+	habit, ok := h.findUserHabit(c)
+	if !ok {
+		return
+	}
+
+	from, to, err := h.parseDateRange(c, habit.UserID, 365)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	checkIns, err := h.db.FindHabitCheckInsByDateRange([]uint{habit.ID}, from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch check-ins"})
+		return
+	}
+
+	values := make(map[string]int, len(checkIns))
+	for _, checkIn := range checkIns {
+		values[checkIn.Date.Format(dateLayout)] = dayValue(*habit, checkIn.Value)
+	}
+
+	// A weekly target is spread evenly over the week for per-day shading
+	dailyTarget := float64(habit.Target)
+	if habit.Period == models.HabitPeriodWeekly {
+		dailyTarget = float64(habit.Target) / 7
+		if habit.Kind == models.HabitKindBoolean {
+			dailyTarget = 1
+		}
+	}
+
+	var cells []heatmapCell
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := d.Format(dateLayout)
+		value := values[key]
+		ratio := float64(value) / dailyTarget
+		if ratio > 1 {
+			ratio = 1
+		}
+		cells = append(cells, heatmapCell{
+			Date:      key,
+			Value:     value,
+			Ratio:     ratio,
+			Completed: ratio >= 1,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"habit": habit,
+		"from":  from.Format(dateLayout),
+		"to":    to.Format(dateLayout),
+		"cells": cells,
+	})
+}
+
+// waterIntakeFromHabit presents the water habit in the legacy WaterIntake shape
+func (h *PlannerHandler) waterIntakeFromHabit(userID uint, date time.Time) (models.WaterIntake, error) {
+	habit, err := h.db.FindOrCreateWaterHabit(userID)
+	if err != nil {
+		return models.WaterIntake{}, err
+	}
+
+	intake := models.WaterIntake{
+		UserID: userID,
+		Date:   date,
+		Target: habit.Target,
+	}
+	if checkIn, err := h.db.FindHabitCheckInByDate(habit.ID, date.Format(dateLayout)); err == nil {
+		intake.Glasses = checkIn.Value
+	}
+
+	return intake, nil
+}
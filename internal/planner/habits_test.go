@@ -0,0 +1,97 @@
+package planner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+func TestHabitStreaks(t *testing.T) {
+	daily := models.Habit{Kind: models.HabitKindBoolean, Period: models.HabitPeriodDaily, Target: 1}
+	counter := models.Habit{Kind: models.HabitKindCounter, Period: models.HabitPeriodDaily, Target: 8}
+	weekly := models.Habit{Kind: models.HabitKindBoolean, Period: models.HabitPeriodWeekly, Target: 3}
+	weeklyTwice := models.Habit{Kind: models.HabitKindBoolean, Period: models.HabitPeriodWeekly, Target: 2}
+
+	// A Wednesday, in the week starting Monday 11 March
+	asOf := time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		habit            models.Habit
+		values           map[string]int
+		current, longest int
+	}{
+		{
+			name:  "no check-ins",
+			habit: daily,
+		},
+		{
+			name:   "unparseable dates are ignored",
+			habit:  daily,
+			values: map[string]int{"yesterday": 1},
+		},
+		{
+			name:    "run through today",
+			habit:   daily,
+			values:  map[string]int{"2024-03-11": 1, "2024-03-12": 1, "2024-03-13": 1},
+			current: 3,
+			longest: 3,
+		},
+		{
+			name:    "today not done yet keeps the run",
+			habit:   daily,
+			values:  map[string]int{"2024-03-11": 1, "2024-03-12": 1},
+			current: 2,
+			longest: 2,
+		},
+		{
+			name:    "missed yesterday breaks the run",
+			habit:   daily,
+			values:  map[string]int{"2024-03-10": 1, "2024-03-11": 1},
+			current: 0,
+			longest: 2,
+		},
+		{
+			name:    "longest run is kept after a gap",
+			habit:   daily,
+			values:  map[string]int{"2024-03-05": 1, "2024-03-06": 1, "2024-03-07": 1, "2024-03-12": 1, "2024-03-13": 1},
+			current: 2,
+			longest: 3,
+		},
+		{
+			name:    "counter days below target do not count",
+			habit:   counter,
+			values:  map[string]int{"2024-03-11": 7, "2024-03-12": 8, "2024-03-13": 5},
+			current: 1,
+			longest: 1,
+		},
+		{
+			name:  "weekly target across the week",
+			habit: weekly,
+			values: map[string]int{
+				"2024-02-27": 1, "2024-02-29": 1, // Two of three, incomplete
+				"2024-03-04": 1, "2024-03-06": 1, "2024-03-08": 1,
+				"2024-03-11": 1, // Current week, not finished yet
+			},
+			current: 1,
+			longest: 1,
+		},
+		{
+			name:    "a yes/no day counts once however high its value",
+			habit:   weeklyTwice,
+			values:  map[string]int{"2024-03-04": 5},
+			current: 0,
+			longest: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := habitStreaks(tt.habit, tt.values, asOf)
+			if current != tt.current || longest != tt.longest {
+				t.Errorf("habitStreaks() = %d, %d, want %d, %d", current, longest, tt.current, tt.longest)
+			}
+		})
+	}
+}
@@ -1,22 +1,28 @@
 package planner
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/occasions"
 	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
 )
 
 type PlannerHandler struct {
-	db *repository.Database
+	db             *repository.Database
+	trashRetention time.Duration // How long deleted items can be restored
 }
 
-func NewPlannerHandler(db *repository.Database) *PlannerHandler {
-	return &PlannerHandler{db: db}
+func NewPlannerHandler(db *repository.Database, trashRetention time.Duration) *PlannerHandler {
+	return &PlannerHandler{db: db, trashRetention: trashRetention}
 }
 
 // ShowDashboard renders the dashboard page
@@ -28,43 +34,97 @@ func (h *PlannerHandler) ShowDashboard(c *gin.Context) {
 	log.Printf("ShowDashboard: userID=%v", userID)
 
 	// Get today's data
-	var todos []models.TodoItem
-	var priorities []models.Priority
 	var contacts []models.Contact
-	var waterIntake models.WaterIntake
 	var thought models.Thought
+	var mood models.MoodEntry
 
 	// Get today's date
-	today := time.Now().Truncate(24 * time.Hour)
+	today := h.today(userID.(uint))
 	log.Printf("ShowDashboard: today=%v", today.Format("2006-01-02"))
 
 	// Fetch all data for today
-	if err := h.db.DB.Where("user_id = ? AND DATE(due_date) = ?", userID, today.Format("2006-01-02")).Find(&todos).Error; err != nil {
+	todos, err := h.db.FindTodos(userID.(uint), repository.TodoFilter{DueFrom: &today, DueTo: &today, Sort: "manual"})
+	if err != nil {
 		log.Printf("Error fetching todos: %v", err)
 	}
+	applyProgress(todos)
 	log.Printf("Fetched todos: %v", todos)
 
-	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).Find(&priorities).Error; err != nil {
+	priorities, err := h.db.FindPrioritiesByUserIDAndDate(userID.(uint), today)
+	if err != nil {
 		log.Printf("Error fetching priorities: %v", err)
 	}
+	rankPriorities(priorities)
+	promoted := make(map[uint]bool, len(priorities))
+	for _, priority := range priorities {
+		if priority.TodoItemID != nil {
+			promoted[*priority.TodoItemID] = true
+		}
+	}
+
+	priorityLimit, err := h.priorityLimit(userID.(uint))
+	if err != nil {
+		log.Printf("Error fetching priority limit: %v", err)
+		priorityLimit = models.DefaultMaxDailyPriorities
+	}
 	log.Printf("Fetched priorities: %v", priorities)
 
-	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).Find(&contacts).Error; err != nil {
+	if err := h.db.DB.Preload("Person").Where("user_id = ? AND date = ?", userID, today).Order("position").Order("id").Find(&contacts).Error; err != nil {
 		log.Printf("Error fetching contacts: %v", err)
 	}
 	log.Printf("Fetched contacts: %v", contacts)
 
-	// Fetch water intake, create default if not found
-	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&waterIntake).Error; err != nil {
-		log.Printf("Error fetching water intake: %v", err)
-		waterIntake = models.WaterIntake{
-			UserID:  userID.(uint),
-			Date:    today,
-			Glasses: 0,
-			Target:  10,
+	tags, err := h.db.FindTagsByUserID(userID.(uint))
+	if err != nil {
+		log.Printf("Error fetching tags: %v", err)
+	}
+
+	people, err := h.db.FindPeople(userID.(uint), repository.PersonFilter{})
+	if err != nil {
+		log.Printf("Error fetching people: %v", err)
+	}
+
+	overdue, err := h.loadOverduePeople(userID.(uint), today)
+	if err != nil {
+		log.Printf("Error fetching overdue people: %v", err)
+	}
+
+	var upcoming []occasions.Occasion
+	if user, err := h.db.FindUserByID(userID.(uint)); err == nil {
+		upcoming, err = h.loadUpcomingOccasions(user, occasionLeadDays(user))
+		if err != nil {
+			log.Printf("Error fetching occasions: %v", err)
 		}
 	}
-	log.Printf("Fetched water intake: %v", waterIntake)
+
+	projects, err := h.loadProjectSummaries(userID.(uint), false)
+	if err != nil {
+		log.Printf("Error fetching projects: %v", err)
+	}
+
+	// Fetch habits (water intake is the built-in water habit)
+	habits, err := h.loadHabitSummaries(userID.(uint))
+	if err != nil {
+		log.Printf("Error fetching habits: %v", err)
+	}
+	log.Printf("Fetched habits: %v", len(habits))
+
+	// Fetch today's focus sessions and the active timer
+	focusSessions, err := h.db.FindFocusSessionsByDateRange(userID.(uint), today, today.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error fetching focus sessions: %v", err)
+	}
+	var activeFocus *focusSessionView
+	if session, err := h.db.FindActiveFocusSession(userID.(uint)); err == nil {
+		view := viewFocusSession(*session, time.Now())
+		activeFocus = &view
+	}
+
+	// Fetch today's schedule
+	timeBlocks, err := h.db.FindTimeBlocksByUserIDAndDate(userID.(uint), today.Format("2006-01-02"))
+	if err != nil {
+		log.Printf("Error fetching time blocks: %v", err)
+	}
 
 	// Fetch thought, create default if not found
 	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&thought).Error; err != nil {
@@ -77,22 +137,35 @@ func (h *PlannerHandler) ShowDashboard(c *gin.Context) {
 	}
 	log.Printf("Fetched thought: %v", thought)
 
-	// Create water glasses array for the template
-	waterGlasses := make([]int, waterIntake.Target)
-	for i := 0; i < waterIntake.Target; i++ {
-		waterGlasses[i] = i
+	// Fetch mood, leave empty if not recorded yet
+	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&mood).Error; err != nil {
+		log.Printf("Error fetching mood: %v", err)
 	}
 
 	// Prepare data for the template
 	data := gin.H{
-		"Title":        "Daily Planner",
-		"Todos":        todos,
-		"Priorities":   priorities,
-		"Contacts":     contacts,
-		"WaterIntake":  waterIntake,
-		"WaterGlasses": waterGlasses,
-		"Thought":      thought,
-		"ShowForms":    false,
+		"Title":         "Daily Planner",
+		"Todos":         todos,
+		"Tags":          tags,
+		"Projects":      projects,
+		"Priorities":    priorities,
+		"PriorityLimit": priorityLimit,
+		"PromotedTodos": promoted,
+		"Contacts":      contacts,
+		"People":        people,
+		"Overdue":       overdue,
+		"Occasions":     upcoming,
+		"ContactTypes":  models.ContactTypes,
+		"Habits":        habitWidgets(habits),
+		"Schedule":      buildSchedule(today, timeBlocks),
+		"Thought":       thought,
+		"Mood":          mood,
+		"Focus": gin.H{
+			"Active":   activeFocus,
+			"Sessions": len(focusSessions),
+			"Minutes":  totalFocusSeconds(focusSessions, time.Now()) / 60,
+		},
+		"ShowForms": false,
 	}
 
 	// Check if any data is missing
@@ -111,6 +184,9 @@ func (h *PlannerHandler) CreateTodo(c *gin.Context) {
 		Title       string `json:"title"`
 		Description string `json:"description"`
 		DueDate     string `json:"dueDate"`
+		Priority    int    `json:"priority" binding:"min=0,max=4"`
+		TagIDs      []uint `json:"tagIds"`
+		ProjectID   *uint  `json:"projectId"`
 	}
 	if err := c.ShouldBindJSON(&todo); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -123,11 +199,38 @@ func (h *PlannerHandler) CreateTodo(c *gin.Context) {
 		return
 	}
 
+	priorityLevel := todo.Priority
+	if priorityLevel == 0 {
+		priorityLevel = models.TodoPriorityP4
+	}
+
+	tags, ok := h.resolveTags(userID.(uint), todo.TagIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag"})
+		return
+	}
+
+	projectID, ok := h.resolveProject(userID.(uint), todo.ProjectID)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown project"})
+		return
+	}
+
+	position, err := h.db.NextTodoPosition(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
+		return
+	}
+
 	newTodo := models.TodoItem{
-		UserID:      userID.(uint),
-		Title:       todo.Title,
-		Description: todo.Description,
-		DueDate:     dueDate,
+		UserID:        userID.(uint),
+		Title:         todo.Title,
+		Description:   todo.Description,
+		DueDate:       dueDate,
+		PriorityLevel: priorityLevel,
+		Position:      position,
+		ProjectID:     projectID,
+		Tags:          tags,
 	}
 
 	if err := h.db.DB.Create(&newTodo).Error; err != nil {
@@ -138,15 +241,108 @@ func (h *PlannerHandler) CreateTodo(c *gin.Context) {
 	c.JSON(http.StatusCreated, newTodo)
 }
 
-// GetTodos handles retrieving all todo items
+// parseTodoFilter reads the GetTodos query parameters
+func parseTodoFilter(c *gin.Context) (repository.TodoFilter, error) {
+	var filter repository.TodoFilter
+
+	switch project := c.Query("project"); project {
+	case "":
+	case "none", "inbox":
+		filter.Inbox = true
+	default:
+		id, err := strconv.ParseUint(project, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid project ID")
+		}
+		projectID := uint(id)
+		filter.ProjectID = &projectID
+	}
+
+	for _, value := range splitQuery(c.QueryArray("tag")) {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, errors.New("Invalid tag ID")
+		}
+		filter.TagIDs = append(filter.TagIDs, uint(id))
+	}
+
+	for _, value := range splitQuery(c.QueryArray("priority")) {
+		level, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(value), "P"))
+		if err != nil || level < models.TodoPriorityP1 || level > models.TodoPriorityP4 {
+			return filter, errors.New("Invalid priority. Use 1-4 or P1-P4")
+		}
+		filter.Priorities = append(filter.Priorities, level)
+	}
+
+	switch c.DefaultQuery("status", "all") {
+	case "open":
+		completed := false
+		filter.Completed = &completed
+	case "completed":
+		completed := true
+		filter.Completed = &completed
+	case "all":
+	default:
+		return filter, errors.New("Invalid status. Use open, completed or all")
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, errors.New("Invalid date format. Use YYYY-MM-DD")
+		}
+		filter.DueFrom = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return filter, errors.New("Invalid date format. Use YYYY-MM-DD")
+		}
+		filter.DueTo = &to
+	}
+
+	filter.Sort = c.DefaultQuery("sort", "created")
+	switch filter.Sort {
+	case "due", "priority", "manual", "created":
+	default:
+		return filter, errors.New("Invalid sort. Use due, priority, manual or created")
+	}
+	filter.Descending = c.Query("order") == "desc"
+
+	return filter, nil
+}
+
+// splitQuery flattens repeated and comma-separated query values
+func splitQuery(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// GetTodos handles retrieving todo items, optionally filtered by tag,
+// priority, status and due date range and sorted by due date, priority,
+// manual order or creation time
 func (h *PlannerHandler) GetTodos(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
-	var todos []models.TodoItem
-	if err := h.db.DB.Where("user_id = ?", userID).Find(&todos).Error; err != nil {
+	filter, err := parseTodoFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	todos, err := h.db.FindTodos(userID.(uint), filter)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
 		return
 	}
+	applyProgress(todos)
 
 	c.JSON(http.StatusOK, todos)
 }
@@ -163,19 +359,61 @@ func (h *PlannerHandler) UpdateTodo(c *gin.Context) {
 	}
 
 	var updateData struct {
-		Completed bool `json:"completed"`
+		Completed        *bool   `json:"completed"`
+		Priority         *int    `json:"priority"`
+		TagIDs           *[]uint `json:"tagIds"`
+		ManualCompletion *bool   `json:"manualCompletion"`
+		ProjectID        *uint   `json:"projectId"` // 0 moves the todo back to the inbox
 	}
 	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	todo.Completed = updateData.Completed
+	if updateData.Completed != nil {
+		todo.Completed = *updateData.Completed
+	}
+	if updateData.Priority != nil {
+		if *updateData.Priority < models.TodoPriorityP1 || *updateData.Priority > models.TodoPriorityP4 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Priority must be between 1 and 4"})
+			return
+		}
+		todo.PriorityLevel = *updateData.Priority
+	}
+
+	if updateData.ManualCompletion != nil {
+		todo.ManualCompletion = *updateData.ManualCompletion
+	}
+	if updateData.ProjectID != nil {
+		projectID, ok := h.resolveProject(userID.(uint), updateData.ProjectID)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown project"})
+			return
+		}
+		todo.ProjectID = projectID
+	}
+
+	var tags []models.Tag
+	if updateData.TagIDs != nil {
+		var ok bool
+		if tags, ok = h.resolveTags(userID.(uint), *updateData.TagIDs); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag"})
+			return
+		}
+	}
+
 	if err := h.db.DB.Save(&todo).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
 		return
 	}
 
+	if updateData.TagIDs != nil {
+		if err := h.db.ReplaceTodoTags(&todo, tags); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo tags"})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, todo)
 }
 
@@ -184,7 +422,13 @@ func (h *PlannerHandler) DeleteTodo(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	todoID := c.Param("id")
 
-	if err := h.db.DB.Where("id = ? AND user_id = ?", todoID, userID).Delete(&models.TodoItem{}).Error; err != nil {
+	var todo models.TodoItem
+	if err := h.db.DB.Where("id = ? AND user_id = ?", todoID, userID).First(&todo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+
+	if err := h.db.DeleteTodoAndPriorities(&todo); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
 		return
 	}
@@ -205,30 +449,35 @@ func (h *PlannerHandler) CreatePriority(c *gin.Context) {
 		return
 	}
 
-	priority := models.Priority{
+	h.createPriority(c, &models.Priority{
 		UserID:      userID.(uint),
 		Title:       priorityData.Title,
 		Description: priorityData.Description,
-		Date:        time.Now().Truncate(24 * time.Hour),
-	}
-
-	if err := h.db.DB.Create(&priority).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create priority"})
-		return
-	}
-
-	c.JSON(http.StatusCreated, priority)
+		Date:        h.today(userID.(uint)),
+	})
 }
 
-// GetPriorities handles retrieving all priorities
+// GetPriorities handles retrieving all priorities, or one day's with ?date=, ranked within each day
 func (h *PlannerHandler) GetPriorities(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
+	date, err := parsePriorityDate(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.db.DB.Preload("TodoItem").Where("user_id = ?", userID)
+	if date != nil {
+		query = query.Where("date = ?", *date)
+	}
+
 	var priorities []models.Priority
-	if err := h.db.DB.Where("user_id = ?", userID).Find(&priorities).Error; err != nil {
+	if err := query.Order("date").Order("position").Order("id").Find(&priorities).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch priorities"})
 		return
 	}
+	rankPriorities(priorities)
 
 	c.JSON(http.StatusOK, priorities)
 }
@@ -244,12 +493,49 @@ func (h *PlannerHandler) UpdatePriority(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&priority); err != nil {
+	// Only these can change: the day and the promoted todo stay fixed so an
+	// update cannot get around the daily limit
+	var updateData struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+		Completed   *bool   `json:"completed"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.db.DB.Save(&priority).Error; err != nil {
+	updates := make(map[string]interface{})
+	if updateData.Title != nil {
+		title := strings.TrimSpace(*updateData.Title)
+		if title == "" && priority.TodoItemID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+			return
+		}
+		priority.Title = title
+		updates["title"] = title
+	}
+	if updateData.Description != nil {
+		priority.Description = *updateData.Description
+		updates["description"] = priority.Description
+	}
+	if updateData.Completed != nil {
+		priority.Completed = *updateData.Completed
+		updates["completed"] = priority.Completed
+	}
+
+	if err := h.db.DB.Transaction(func(tx *gorm.DB) error {
+		// A promoted todo keeps its own completion
+		if priority.TodoItemID != nil && updateData.Completed != nil {
+			if err := tx.Model(&models.TodoItem{}).Where("id = ? AND user_id = ?", *priority.TodoItemID, priority.UserID).Update("completed", priority.Completed).Error; err != nil {
+				return err
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(&priority).Where("user_id = ?", priority.UserID).Updates(updates).Error
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update priority"})
 		return
 	}
@@ -270,11 +556,13 @@ func (h *PlannerHandler) DeletePriority(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Priority deleted successfully"})
 }
 
-// CreateContact handles creating a new contact
+// CreateContact handles creating a follow-up reminder for today. The person is
+// given by personId or by name; unknown names are added to the contact book.
 func (h *PlannerHandler) CreateContact(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	var contactData struct {
+		PersonID    *uint  `json:"personId"`
 		Name        string `json:"name"`
 		Type        string `json:"type"`
 		Description string `json:"description"`
@@ -284,28 +572,54 @@ func (h *PlannerHandler) CreateContact(c *gin.Context) {
 		return
 	}
 
+	kind, err := contactType(contactData.Type)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	person, err := h.resolvePerson(userID.(uint), contactData.PersonID, contactData.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown person"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	today := h.today(userID.(uint))
+	position, err := h.db.NextContactPosition(userID.(uint), today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
+		return
+	}
+
 	contact := models.Contact{
 		UserID:      userID.(uint),
-		Name:        contactData.Name,
-		Type:        contactData.Type,
+		PersonID:    &person.ID,
+		Name:        person.Name,
+		Type:        kind,
 		Description: contactData.Description,
-		Date:        time.Now().Truncate(24 * time.Hour),
+		Date:        today,
+		Position:    position,
 	}
 
-	if err := h.db.DB.Create(&contact).Error; err != nil {
+	if err := h.db.DB.Omit("Person").Create(&contact).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create contact"})
 		return
 	}
+	contact.Person = person
 
 	c.JSON(http.StatusCreated, contact)
 }
 
-// GetContacts handles retrieving all contacts
+// GetContacts handles retrieving all follow-up reminders
 func (h *PlannerHandler) GetContacts(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	var contacts []models.Contact
-	if err := h.db.DB.Where("user_id = ?", userID).Find(&contacts).Error; err != nil {
+	if err := h.db.DB.Preload("Person").Where("user_id = ?", userID).Order("date").Order("position").Order("id").Find(&contacts).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
 		return
 	}
@@ -313,7 +627,8 @@ func (h *PlannerHandler) GetContacts(c *gin.Context) {
 	c.JSON(http.StatusOK, contacts)
 }
 
-// UpdateContact handles updating a contact
+// UpdateContact handles updating a follow-up reminder. Completing a reminder
+// records an interaction with the person; reopening it removes that record.
 func (h *PlannerHandler) UpdateContact(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	contactID := c.Param("id")
@@ -324,12 +639,64 @@ func (h *PlannerHandler) UpdateContact(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&contact); err != nil {
+	var updateData struct {
+		Type        *string `json:"type"`
+		Description *string `json:"description"`
+		Completed   *bool   `json:"completed"`
+		PersonID    *uint   `json:"personId"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.db.DB.Save(&contact).Error; err != nil {
+	if updateData.Type != nil {
+		kind, err := contactType(*updateData.Type)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		contact.Type = kind
+	}
+	if updateData.Description != nil {
+		contact.Description = *updateData.Description
+	}
+	if updateData.PersonID != nil {
+		person, err := h.db.FindPersonByIDAndUserID(*updateData.PersonID, userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown person"})
+			return
+		}
+		contact.PersonID = &person.ID
+		contact.Name = person.Name
+	}
+
+	wasCompleted := contact.Completed
+	if updateData.Completed != nil {
+		contact.Completed = *updateData.Completed
+	}
+
+	today := h.today(contact.UserID)
+	if err := h.db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Person").Save(&contact).Error; err != nil {
+			return err
+		}
+
+		switch {
+		case contact.Completed && !wasCompleted && contact.PersonID != nil:
+			return tx.Create(&models.Interaction{
+				UserID:    contact.UserID,
+				PersonID:  *contact.PersonID,
+				ContactID: &contact.ID,
+				Type:      contact.Type,
+				Date:      today,
+				Notes:     contact.Description,
+			}).Error
+		case !contact.Completed && wasCompleted:
+			return tx.Where("contact_id = ?", contact.ID).Delete(&models.Interaction{}).Error
+		}
+		return nil
+	}); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact"})
 		return
 	}
@@ -350,10 +717,11 @@ func (h *PlannerHandler) DeleteContact(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
 }
 
-// UpdateWaterIntake handles updating water intake
+// UpdateWaterIntake handles updating water intake, which is stored as
+// check-ins of the built-in water habit
 func (h *PlannerHandler) UpdateWaterIntake(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	today := time.Now().Truncate(24 * time.Hour)
+	today := h.today(userID.(uint))
 
 	var intakeData struct {
 		Glasses int `json:"glasses"`
@@ -363,46 +731,34 @@ func (h *PlannerHandler) UpdateWaterIntake(c *gin.Context) {
 		return
 	}
 
-	// Try to find existing record for today
-	var waterIntake models.WaterIntake
-	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&waterIntake).Error; err != nil {
-		// Create new record if not found
-		waterIntake = models.WaterIntake{
-			UserID:  userID.(uint),
-			Date:    today,
-			Glasses: intakeData.Glasses,
-			Target:  10,
-		}
-		if err := h.db.DB.Create(&waterIntake).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create water intake record"})
-			return
-		}
-	} else {
-		// Update existing record
-		waterIntake.Glasses = intakeData.Glasses
-		if err := h.db.DB.Save(&waterIntake).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update water intake"})
-			return
-		}
+	habit, err := h.db.FindOrCreateWaterHabit(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update water intake"})
+		return
 	}
 
-	c.JSON(http.StatusOK, waterIntake)
+	if _, err := h.saveHabitCheckIn(habit, today, intakeData.Glasses); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update water intake"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WaterIntake{
+		UserID:  userID.(uint),
+		Date:    today,
+		Glasses: intakeData.Glasses,
+		Target:  habit.Target,
+	})
 }
 
 // GetWaterIntake handles retrieving water intake
 func (h *PlannerHandler) GetWaterIntake(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	today := time.Now().Truncate(24 * time.Hour)
+	today := h.today(userID.(uint))
 
-	var intake models.WaterIntake
-	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&intake).Error; err != nil {
-		// If no record exists, return default values
-		intake = models.WaterIntake{
-			UserID:  userID.(uint),
-			Date:    today,
-			Glasses: 0,
-			Target:  10,
-		}
+	intake, err := h.waterIntakeFromHabit(userID.(uint), today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch water intake"})
+		return
 	}
 
 	c.JSON(http.StatusOK, intake)
@@ -423,7 +779,7 @@ func (h *PlannerHandler) CreateThought(c *gin.Context) {
 	thought := models.Thought{
 		UserID:  userID.(uint),
 		Content: thoughtData.Content,
-		Date:    time.Now().Truncate(24 * time.Hour),
+		Date:    h.today(userID.(uint)),
 	}
 
 	if err := h.db.DB.Create(&thought).Error; err != nil {
@@ -437,7 +793,7 @@ func (h *PlannerHandler) CreateThought(c *gin.Context) {
 // GetTodayThought handles retrieving today's thought
 func (h *PlannerHandler) GetTodayThought(c *gin.Context) {
 	userID, _ := c.Get("user_id")
-	today := time.Now().Truncate(24 * time.Hour)
+	today := h.today(userID.(uint))
 
 	var thought models.Thought
 	if err := h.db.DB.Where("user_id = ? AND date = ?", userID, today).First(&thought).Error; err != nil {
@@ -450,11 +806,13 @@ func (h *PlannerHandler) GetTodayThought(c *gin.Context) {
 
 // GenerateThought handles generating a new thought
 func (h *PlannerHandler) GenerateThought(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
 	// For now, return a simple placeholder thought
 	// In a real application, you might want to integrate with an AI service
 	thought := models.Thought{
 		Content: "Today is a new opportunity to make a difference. Focus on what matters most.",
-		Date:    time.Now().Truncate(24 * time.Hour),
+		Date:    h.today(userID.(uint)),
 	}
 
 	c.JSON(http.StatusOK, thought)
@@ -0,0 +1,167 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// moodTrendPoint is a single day in the mood time series. Mood and Energy are
+// nil for days without an entry so charts can render gaps.
+type moodTrendPoint struct {
+	Date   string `json:"date"`
+	Mood   *int   `json:"mood"`
+	Energy *int   `json:"energy"`
+	Emoji  string `json:"emoji,omitempty"`
+}
+
+type moodData struct {
+	Date   string `json:"date"`
+	Mood   int    `json:"mood"`
+	Energy int    `json:"energy"`
+	Emoji  string `json:"emoji"`
+	Notes  string `json:"notes"`
+}
+
+// maxMoodEmoji is how many characters a mood's emoji may have, enough for
+// emoji joined from several code points
+const maxMoodEmoji = 32
+
+// validateMood returns what is wrong with a mood entry, or "" if nothing is
+func validateMood(data *moodData) string {
+	if data.Mood < 1 || data.Mood > 5 {
+		return "Mood must be between 1 and 5"
+	}
+	if data.Energy < 1 || data.Energy > 5 {
+		return "Energy must be between 1 and 5"
+	}
+	if utf8.RuneCountInString(data.Emoji) > maxMoodEmoji {
+		return fmt.Sprintf("Emoji can be at most %d characters", maxMoodEmoji)
+	}
+	return ""
+}
+
+// SaveMood handles creating or updating the mood entry for a day
+func (h *PlannerHandler) SaveMood(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var data moodData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mood entry. Send JSON with mood, energy and optionally date, emoji and notes"})
+		return
+	}
+	if msg := validateMood(&data); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	date := h.today(userID.(uint))
+	if data.Date != "" {
+		parsed, err := time.Parse(dateLayout, data.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	// Try to find existing entry for the day
+	entry, err := h.db.FindMoodEntryByUserIDAndDate(userID.(uint), date.Format(dateLayout))
+	if err != nil {
+		entry = &models.MoodEntry{
+			UserID: userID.(uint),
+			Date:   date,
+		}
+	}
+
+	entry.Mood = data.Mood
+	entry.Energy = data.Energy
+	entry.Emoji = data.Emoji
+	entry.Notes = data.Notes
+
+	if err := h.db.SaveMoodEntry(entry); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mood"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetMood handles retrieving the mood entry for a day (defaults to today)
+func (h *PlannerHandler) GetMood(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	date := h.today(userID.(uint)).Format(dateLayout)
+	if v := c.Query("date"); v != "" {
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = v
+	}
+
+	entry, err := h.db.FindMoodEntryByUserIDAndDate(userID.(uint), date)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No mood recorded for this day"})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetMoodTrends handles retrieving mood and energy trends over a date range
+func (h *PlannerHandler) GetMoodTrends(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	from, to, err := h.parseDateRange(c, userID.(uint), 30)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entries, err := h.db.FindMoodEntriesByUserIDAndDateRange(userID.(uint), from.Format(dateLayout), to.Format(dateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mood entries"})
+		return
+	}
+
+	byDate := make(map[string]models.MoodEntry, len(entries))
+	for _, entry := range entries {
+		byDate[entry.Date.Format(dateLayout)] = entry
+	}
+
+	// Build one point per day so the series lines up with a date axis
+	var series []moodTrendPoint
+	var moodTotal, energyTotal int
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		point := moodTrendPoint{Date: d.Format(dateLayout)}
+		if entry, ok := byDate[point.Date]; ok {
+			mood, energy := entry.Mood, entry.Energy
+			point.Mood = &mood
+			point.Energy = &energy
+			point.Emoji = entry.Emoji
+			moodTotal += mood
+			energyTotal += energy
+		}
+		series = append(series, point)
+	}
+
+	var averageMood, averageEnergy float64
+	if len(entries) > 0 {
+		averageMood = float64(moodTotal) / float64(len(entries))
+		averageEnergy = float64(energyTotal) / float64(len(entries))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":          from.Format(dateLayout),
+		"to":            to.Format(dateLayout),
+		"daysRecorded":  len(entries),
+		"averageMood":   averageMood,
+		"averageEnergy": averageEnergy,
+		"series":        series,
+	})
+}
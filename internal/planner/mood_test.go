@@ -0,0 +1,29 @@
+package planner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMood(t *testing.T) {
+	tests := []struct {
+		name string
+		data moodData
+		want string
+	}{
+		{name: "valid", data: moodData{Mood: 3, Energy: 5, Emoji: "🙂"}},
+		{name: "mood missing", data: moodData{Energy: 3}, want: "Mood must be between 1 and 5"},
+		{name: "mood too high", data: moodData{Mood: 6, Energy: 3}, want: "Mood must be between 1 and 5"},
+		{name: "energy too low", data: moodData{Mood: 3, Energy: -1}, want: "Energy must be between 1 and 5"},
+		{name: "emoji at the limit", data: moodData{Mood: 3, Energy: 3, Emoji: strings.Repeat("🙂", 32)}},
+		{name: "emoji too long", data: moodData{Mood: 3, Energy: 3, Emoji: strings.Repeat("🙂", 33)}, want: "Emoji can be at most 32 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateMood(&tt.data); got != tt.want {
+				t.Errorf("validateMood() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/occasions"
+)
+
+// maxOccasionDays bounds how far ahead upcoming occasions can be listed
+const maxOccasionDays = 366
+
+// occasionLeadDays returns how many days ahead the user wants to see occasions
+func occasionLeadDays(user *models.User) int {
+	if user.OccasionLeadDays <= 0 {
+		return models.DefaultOccasionLeadDays
+	}
+	return user.OccasionLeadDays
+}
+
+// loadUpcomingOccasions lists the user's birthdays and significant dates
+// within days of today in the user's time zone
+func (h *PlannerHandler) loadUpcomingOccasions(user *models.User, days int) ([]occasions.Occasion, error) {
+	people, err := h.db.FindOccasionPeople(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	dates, err := h.db.FindSignificantDatesByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	day := user.Today(time.Now())
+	upcoming := occasions.Upcoming(people, dates, day, days)
+	if upcoming == nil {
+		upcoming = make([]occasions.Occasion, 0)
+	}
+	return upcoming, nil
+}
+
+// GetOccasions handles listing upcoming birthdays, anniversaries and custom
+// dates. ?days= overrides the user's lead time.
+func (h *PlannerHandler) GetOccasions(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch occasions"})
+		return
+	}
+
+	days := occasionLeadDays(user)
+	if v := c.Query("days"); v != "" {
+		days, err = strconv.Atoi(v)
+		if err != nil || days < 0 || days > maxOccasionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be between 0 and %d", maxOccasionDays)})
+			return
+		}
+	}
+
+	upcoming, err := h.loadUpcomingOccasions(user, days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch occasions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, upcoming)
+}
+
+// GetOccasionSettings handles retrieving the user's time zone and occasion lead time
+func (h *PlannerHandler) GetOccasionSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timezone":         user.Location().String(),
+		"occasionLeadDays": occasionLeadDays(user),
+	})
+}
+
+// UpdateOccasionSettings handles changing the user's time zone and how far
+// ahead upcoming occasions are shown
+func (h *PlannerHandler) UpdateOccasionSettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var settingsData struct {
+		Timezone         *string `json:"timezone"`
+		OccasionLeadDays *int    `json:"occasionLeadDays"`
+	}
+	if err := c.ShouldBindJSON(&settingsData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if settingsData.Timezone != nil {
+		name := strings.TrimSpace(*settingsData.Timezone)
+		if _, err := time.LoadLocation(name); err != nil || name == "" || name == "Local" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone. Use an IANA name such as Europe/Berlin"})
+			return
+		}
+		updates["timezone"] = name
+	}
+	if settingsData.OccasionLeadDays != nil {
+		if *settingsData.OccasionLeadDays < 0 || *settingsData.OccasionLeadDays > maxOccasionDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("occasionLeadDays must be between 0 and %d", maxOccasionDays)})
+			return
+		}
+		updates["occasion_lead_days"] = *settingsData.OccasionLeadDays
+	}
+
+	if len(updates) > 0 {
+		if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+			return
+		}
+	}
+
+	h.GetOccasionSettings(c)
+}
+
+// significantDateData is the request body for adding or editing a significant date
+type significantDateData struct {
+	Kind  string `json:"kind"`
+	Label string `json:"label" binding:"max=255"`
+	Date  string `json:"date"`
+}
+
+// apply validates the data and copies it onto date
+func (d significantDateData) apply(date *models.SignificantDate) error {
+	kind := strings.ToLower(strings.TrimSpace(d.Kind))
+	if kind != models.OccasionAnniversary && kind != models.OccasionCustom {
+		return errors.New("Kind must be anniversary or custom; birthdays are set on the person")
+	}
+	label := strings.TrimSpace(d.Label)
+	if kind == models.OccasionCustom && label == "" {
+		return errors.New("A label is required for custom dates")
+	}
+	value, err := time.Parse(dateLayout, d.Date)
+	if err != nil {
+		return errors.New("Invalid date. Use YYYY-MM-DD")
+	}
+
+	date.Kind = kind
+	date.Label = label
+	date.Date = value
+	return nil
+}
+
+func (h *PlannerHandler) findPersonDate(c *gin.Context, person *models.Person) (*models.SignificantDate, bool) {
+	id, err := strconv.ParseUint(c.Param("dateId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date not found"})
+		return nil, false
+	}
+
+	date, err := h.db.FindSignificantDateByIDAndPersonID(uint(id), person.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Date not found"})
+		return nil, false
+	}
+
+	return date, true
+}
+
+// GetSignificantDates handles listing a person's anniversaries and custom dates
+func (h *PlannerHandler) GetSignificantDates(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	dates, err := h.db.FindSignificantDatesByPersonID(person.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dates)
+}
+
+// CreateSignificantDate handles adding an anniversary or custom date to a person
+func (h *PlannerHandler) CreateSignificantDate(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	var dateData significantDateData
+	if err := c.ShouldBindJSON(&dateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := models.SignificantDate{UserID: person.UserID, PersonID: person.ID}
+	if err := dateData.apply(&date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.CreateSignificantDate(&date); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create date"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, date)
+}
+
+// UpdateSignificantDate handles editing one of a person's significant dates
+func (h *PlannerHandler) UpdateSignificantDate(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+	date, ok := h.findPersonDate(c, person)
+	if !ok {
+		return
+	}
+
+	var dateData significantDateData
+	if err := c.ShouldBindJSON(&dateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := dateData.apply(date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.db.UpdateSignificantDate(date); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, date)
+}
+
+// DeleteSignificantDate handles removing one of a person's significant dates
+func (h *PlannerHandler) DeleteSignificantDate(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+	date, ok := h.findPersonDate(c, person)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteSignificantDate(date.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Date deleted successfully"})
+}
@@ -0,0 +1,90 @@
+package planner
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// moveRequest names the items a moved item should land between. Only one
+// neighbour is needed when the item is dropped at either end of a list.
+type moveRequest struct {
+	AfterID  *uint `json:"afterId"`
+	BeforeID *uint `json:"beforeId"`
+}
+
+// move repositions the item with the given ID inside the list selected by
+// scope and writes the new position, or an error, to the response
+func (h *PlannerHandler) move(c *gin.Context, model interface{}, scope repository.Scope, id uint, notFound string) {
+	var moveData moveRequest
+	if err := c.ShouldBindJSON(&moveData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (moveData.AfterID != nil && *moveData.AfterID == id) || (moveData.BeforeID != nil && *moveData.BeforeID == id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An item cannot be moved next to itself"})
+		return
+	}
+
+	position, err := h.db.Reposition(model, scope, id, moveData.AfterID, moveData.BeforeID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": notFound})
+	case errors.Is(err, repository.ErrNoNeighbour), errors.Is(err, repository.ErrNeighbourOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move item"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"id": id, "position": position})
+	}
+}
+
+// MoveTodo handles moving a todo between two of the user's other todos
+func (h *PlannerHandler) MoveTodo(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+
+	h.move(c, &models.TodoItem{}, repository.UserScope(todo.UserID), todo.ID, "Todo not found")
+}
+
+// MovePriority handles moving a priority within its day's list
+func (h *PlannerHandler) MovePriority(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var priority models.Priority
+	if err := h.db.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&priority).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Priority not found"})
+		return
+	}
+
+	h.move(c, &models.Priority{}, repository.UserDateScope(priority.UserID, priority.Date), priority.ID, "Priority not found")
+}
+
+// MoveContact handles moving a contact reminder within its day's list
+func (h *PlannerHandler) MoveContact(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var contact models.Contact
+	if err := h.db.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&contact).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Contact not found"})
+		return
+	}
+
+	h.move(c, &models.Contact{}, repository.UserDateScope(contact.UserID, contact.Date), contact.ID, "Contact not found")
+}
+
+// MoveProject handles moving a project between two of the user's other projects
+func (h *PlannerHandler) MoveProject(c *gin.Context) {
+	project, ok := h.findUserProject(c)
+	if !ok {
+		return
+	}
+
+	h.move(c, &models.Project{}, repository.UserScope(project.UserID), project.ID, "Project not found")
+}
@@ -0,0 +1,491 @@
+package planner
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+var errContactType = errors.New("Type must be one of " + strings.Join(models.ContactTypes, ", "))
+
+// contactType returns the canonical spelling of a follow-up channel
+func contactType(value string) (string, error) {
+	for _, t := range models.ContactTypes {
+		if strings.EqualFold(strings.TrimSpace(value), t) {
+			return t, nil
+		}
+	}
+	return "", errContactType
+}
+
+// maxCadenceDays bounds the keep-in-touch cadence
+const maxCadenceDays = 366
+
+// validateCadence checks a keep-in-touch cadence and returns the canonical channel
+func validateCadence(days int, kind string) (string, error) {
+	if days < 0 || days > maxCadenceDays {
+		return "", errors.New("cadenceDays must be between 0 and 366")
+	}
+	if kind == "" {
+		return "", nil
+	}
+	return contactType(kind)
+}
+
+type emailData struct {
+	Label   string `json:"label"`
+	Address string `json:"address"`
+}
+
+type phoneData struct {
+	Label  string `json:"label"`
+	Number string `json:"number"`
+}
+
+// personEmails validates and normalizes email addresses
+func personEmails(data []emailData) ([]models.PersonEmail, error) {
+	emails := make([]models.PersonEmail, 0, len(data))
+	for _, d := range data {
+		address, err := mail.ParseAddress(strings.TrimSpace(d.Address))
+		if err != nil {
+			return nil, errors.New("Invalid email address: " + d.Address)
+		}
+		emails = append(emails, models.PersonEmail{Label: strings.TrimSpace(d.Label), Address: address.Address})
+	}
+	return emails, nil
+}
+
+// personPhones validates phone numbers; formatting is kept as entered
+func personPhones(data []phoneData) ([]models.PersonPhone, error) {
+	phones := make([]models.PersonPhone, 0, len(data))
+	for _, d := range data {
+		number := strings.TrimSpace(d.Number)
+		if !strings.ContainsAny(number, "0123456789") || len(number) > 64 {
+			return nil, errors.New("Invalid phone number: " + d.Number)
+		}
+		phones = append(phones, models.PersonPhone{Label: strings.TrimSpace(d.Label), Number: number})
+	}
+	return phones, nil
+}
+
+// parseBirthday reads an optional YYYY-MM-DD date; an empty string clears it
+func parseBirthday(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	birthday, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return nil, errors.New("Invalid birthday. Use YYYY-MM-DD")
+	}
+	return &birthday, nil
+}
+
+func (h *PlannerHandler) findUserPerson(c *gin.Context) (*models.Person, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return nil, false
+	}
+
+	person, err := h.db.FindPersonByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+		return nil, false
+	}
+
+	return person, true
+}
+
+// resolvePerson finds the person a follow-up is for: by ID when given,
+// otherwise by name, adding the name to the contact book if it is new
+func (h *PlannerHandler) resolvePerson(userID uint, id *uint, name string) (*models.Person, error) {
+	if id != nil {
+		return h.db.FindPersonByIDAndUserID(*id, userID)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("A person or name is required")
+	}
+	if person, err := h.db.FindPersonByName(userID, name); err == nil {
+		return person, nil
+	}
+
+	person := models.Person{UserID: userID, Name: name}
+	if err := h.db.CreatePerson(&person); err != nil {
+		return nil, err
+	}
+	return &person, nil
+}
+
+// GetPeople handles listing the contact book (?q= searches names, emails and phones; ?tag= filters)
+func (h *PlannerHandler) GetPeople(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	filter := repository.PersonFilter{Query: strings.TrimSpace(c.Query("q"))}
+	if v := c.Query("tag"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag"})
+			return
+		}
+		tagID := uint(id)
+		filter.TagID = &tagID
+	}
+
+	people, err := h.db.FindPeople(userID.(uint), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch people"})
+		return
+	}
+
+	c.JSON(http.StatusOK, people)
+}
+
+// CreatePerson handles adding someone to the contact book
+func (h *PlannerHandler) CreatePerson(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var personData struct {
+		Name        string      `json:"name" binding:"required,max=255"`
+		Notes       string      `json:"notes"`
+		Birthday    string      `json:"birthday"`
+		Emails      []emailData `json:"emails"`
+		Phones      []phoneData `json:"phones"`
+		TagIDs      []uint      `json:"tagIds"`
+		CadenceDays int         `json:"cadenceDays"` // Keep in touch every this many days
+		CadenceType string      `json:"cadenceType"`
+	}
+	if err := c.ShouldBindJSON(&personData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	cadenceType, err := validateCadence(personData.CadenceDays, personData.CadenceType)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	birthday, err := parseBirthday(personData.Birthday)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	emails, err := personEmails(personData.Emails)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phones, err := personPhones(personData.Phones)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tags, ok := h.resolveTags(userID.(uint), personData.TagIDs)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag"})
+		return
+	}
+
+	person := models.Person{
+		UserID:      userID.(uint),
+		Name:        strings.TrimSpace(personData.Name),
+		Notes:       personData.Notes,
+		Birthday:    birthday,
+		CadenceDays: personData.CadenceDays,
+		CadenceType: cadenceType,
+		Emails:      emails,
+		Phones:      phones,
+		Tags:        tags,
+	}
+
+	if err := h.db.CreatePerson(&person); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create person"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, person)
+}
+
+// GetPerson handles retrieving a person with their pending follow-ups and interaction history
+func (h *PlannerHandler) GetPerson(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	followUps, err := h.db.FindFollowUpsByPersonID(person.ID, true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch follow-ups"})
+		return
+	}
+
+	interactions, err := h.db.FindInteractionsByPersonID(person.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch interactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"person":       person,
+		"followUps":    followUps,
+		"interactions": interactions,
+	})
+}
+
+// UpdatePerson handles editing a person; emails, phones and tagIds replace the existing lists when present
+func (h *PlannerHandler) UpdatePerson(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name        *string      `json:"name"`
+		Notes       *string      `json:"notes"`
+		Birthday    *string      `json:"birthday"` // "" clears the birthday
+		Emails      *[]emailData `json:"emails"`
+		Phones      *[]phoneData `json:"phones"`
+		TagIDs      *[]uint      `json:"tagIds"`
+		CadenceDays *int         `json:"cadenceDays"` // 0 turns keep-in-touch off
+		CadenceType *string      `json:"cadenceType"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updateData.Name != nil {
+		name := strings.TrimSpace(*updateData.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+			return
+		}
+		person.Name = name
+	}
+	if updateData.Notes != nil {
+		person.Notes = *updateData.Notes
+	}
+	if updateData.Birthday != nil {
+		birthday, err := parseBirthday(*updateData.Birthday)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		person.Birthday = birthday
+	}
+
+	if updateData.CadenceDays != nil || updateData.CadenceType != nil {
+		days, kind := person.CadenceDays, person.CadenceType
+		if updateData.CadenceDays != nil {
+			days = *updateData.CadenceDays
+		}
+		if updateData.CadenceType != nil {
+			kind = *updateData.CadenceType
+		}
+		kind, err := validateCadence(days, kind)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		person.CadenceDays, person.CadenceType = days, kind
+	}
+
+	var emails []models.PersonEmail
+	if updateData.Emails != nil {
+		var err error
+		if emails, err = personEmails(*updateData.Emails); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var phones []models.PersonPhone
+	if updateData.Phones != nil {
+		var err error
+		if phones, err = personPhones(*updateData.Phones); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	var tags []models.Tag
+	if updateData.TagIDs != nil {
+		if tags, ok = h.resolveTags(userID.(uint), *updateData.TagIDs); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown tag"})
+			return
+		}
+	}
+
+	if err := h.db.UpdatePerson(person, emails, phones, tags); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update person"})
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// DeletePerson handles removing a person from the contact book
+func (h *PlannerHandler) DeletePerson(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeletePerson(person); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete person"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person deleted successfully"})
+}
+
+// GetInteractions handles retrieving a person's interaction history
+func (h *PlannerHandler) GetInteractions(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	interactions, err := h.db.FindInteractionsByPersonID(person.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch interactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, interactions)
+}
+
+// CreateInteraction handles logging a conversation that had no reminder
+func (h *PlannerHandler) CreateInteraction(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	var interactionData struct {
+		Type  string `json:"type" binding:"required"`
+		Date  string `json:"date"` // Defaults to today
+		Notes string `json:"notes"`
+	}
+	if err := c.ShouldBindJSON(&interactionData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errContactType.Error()})
+		return
+	}
+
+	kind, err := contactType(interactionData.Type)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := h.today(person.UserID)
+	if interactionData.Date != "" {
+		if date, err = time.Parse(dateLayout, interactionData.Date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+	}
+
+	interaction := models.Interaction{
+		UserID:   person.UserID,
+		PersonID: person.ID,
+		Type:     kind,
+		Date:     date,
+		Notes:    interactionData.Notes,
+	}
+
+	if err := h.db.CreateInteraction(&interaction); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log interaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, interaction)
+}
+
+// DeleteInteraction handles removing an entry from a person's history
+func (h *PlannerHandler) DeleteInteraction(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("interactionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+
+	interaction, err := h.db.FindInteractionByIDAndPersonID(uint(id), person.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Interaction not found"})
+		return
+	}
+
+	if err := h.db.DeleteInteraction(interaction.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete interaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Interaction deleted successfully"})
+}
+
+// overduePerson is a person the user is due to get back in touch with
+type overduePerson struct {
+	models.Person
+	LastContacted string `json:"lastContacted"`
+	DueDate       string `json:"dueDate"`
+	DaysOverdue   int    `json:"daysOverdue"` // 0 when due today
+}
+
+// loadOverduePeople returns the people whose keep-in-touch cadence has run
+// out as of day, most overdue first
+func (h *PlannerHandler) loadOverduePeople(userID uint, day time.Time) ([]overduePerson, error) {
+	people, err := h.db.FindCadencePeople(&userID)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := make([]overduePerson, 0)
+	for _, person := range people {
+		due := person.DueDate()
+		if due.After(day) {
+			continue
+		}
+		overdue = append(overdue, overduePerson{
+			Person:        person.Person,
+			LastContacted: person.LastContacted.Format(dateLayout),
+			DueDate:       due.Format(dateLayout),
+			DaysOverdue:   int(day.Sub(due).Hours() / 24),
+		})
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool {
+		return overdue[i].DaysOverdue > overdue[j].DaysOverdue
+	})
+	return overdue, nil
+}
+
+// GetOverduePeople handles listing the people the user is due or overdue to contact
+func (h *PlannerHandler) GetOverduePeople(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	overdue, err := h.loadOverduePeople(userID.(uint), h.today(userID.(uint)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch overdue people"})
+		return
+	}
+
+	c.JSON(http.StatusOK, overdue)
+}
@@ -0,0 +1,184 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// maxPriorityLimit bounds the configurable daily priority limit
+const maxPriorityLimit = 10
+
+// rankPriorities numbers priorities from 1 within each day, assuming they
+// are sorted by date and position, and shows promoted todos through their
+// todo's title and completion
+func rankPriorities(priorities []models.Priority) {
+	rank := 0
+	for i := range priorities {
+		if i == 0 || !priorities[i].Date.Equal(priorities[i-1].Date) {
+			rank = 0
+		}
+		rank++
+		priorities[i].Rank = rank
+
+		if todo := priorities[i].TodoItem; todo != nil {
+			priorities[i].Title = todo.Title
+			priorities[i].Description = todo.Description
+			priorities[i].Completed = todo.Completed
+		}
+	}
+}
+
+// priorityLimit returns how many priorities the user may set per day
+func (h *PlannerHandler) priorityLimit(userID uint) (int, error) {
+	user, err := h.db.FindUserByID(userID)
+	if err != nil {
+		return 0, err
+	}
+	if user.MaxDailyPriorities <= 0 {
+		return models.DefaultMaxDailyPriorities, nil
+	}
+	return user.MaxDailyPriorities, nil
+}
+
+// createPriority adds a priority for today within the user's limit and
+// writes the result, or the reason it was refused, to the response
+func (h *PlannerHandler) createPriority(c *gin.Context, priority *models.Priority) {
+	limit, err := h.priorityLimit(priority.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create priority"})
+		return
+	}
+
+	err = h.db.CreatePriorityWithinLimit(priority, limit)
+	if errors.Is(err, repository.ErrPriorityLimit) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("You already have %d priorities for today. Finish or remove one first", limit),
+			"limit": limit,
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create priority"})
+		return
+	}
+
+	priorities, err := h.db.FindPrioritiesByUserIDAndDate(priority.UserID, priority.Date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch priorities"})
+		return
+	}
+	rankPriorities(priorities)
+	for _, p := range priorities {
+		if p.ID == priority.ID {
+			c.JSON(http.StatusCreated, p)
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, priority)
+}
+
+// PromoteTodo handles adding a todo to today's priorities. The priority
+// links to the todo instead of copying it, so both stay in sync.
+func (h *PlannerHandler) PromoteTodo(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+
+	date := h.today(todo.UserID)
+	if _, err := h.db.FindPriorityByTodoID(todo.UserID, todo.ID, date); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Todo is already one of today's priorities"})
+		return
+	}
+
+	h.createPriority(c, &models.Priority{
+		UserID:     todo.UserID,
+		Date:       date,
+		TodoItemID: &todo.ID,
+	})
+}
+
+// DemoteTodo handles removing a promoted todo from today's priorities; the todo itself is kept
+func (h *PlannerHandler) DemoteTodo(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+
+	priority, err := h.db.FindPriorityByTodoID(todo.UserID, todo.ID, h.today(todo.UserID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo is not one of today's priorities"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote todo"})
+		return
+	}
+
+	if err := h.db.DeletePriority(priority.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to demote todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Todo removed from today's priorities"})
+}
+
+// GetPrioritySettings handles retrieving the user's daily priority limit and today's usage
+func (h *PlannerHandler) GetPrioritySettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	limit, err := h.priorityLimit(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	priorities, err := h.db.FindPrioritiesByUserIDAndDate(userID.(uint), h.today(userID.(uint)))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"maxDailyPriorities": limit, "todayCount": len(priorities)})
+}
+
+// UpdatePrioritySettings handles changing the user's daily priority limit.
+// Lowering it keeps existing priorities but blocks new ones until the day is under the limit.
+func (h *PlannerHandler) UpdatePrioritySettings(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var settingsData struct {
+		MaxDailyPriorities int `json:"maxDailyPriorities" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&settingsData); err != nil || settingsData.MaxDailyPriorities < 1 || settingsData.MaxDailyPriorities > maxPriorityLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxDailyPriorities must be between 1 and %d", maxPriorityLimit)})
+		return
+	}
+
+	if err := h.db.DB.Model(&models.User{}).Where("id = ?", userID).Update("max_daily_priorities", settingsData.MaxDailyPriorities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"maxDailyPriorities": settingsData.MaxDailyPriorities})
+}
+
+// parsePriorityDate reads the optional date query parameter
+func parsePriorityDate(c *gin.Context) (*time.Time, error) {
+	v := c.Query("date")
+	if v == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(dateLayout, v)
+	if err != nil {
+		return nil, errors.New("Invalid date. Use YYYY-MM-DD")
+	}
+	return &date, nil
+}
@@ -0,0 +1,109 @@
+package planner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/testdb"
+)
+
+func TestUpdatePriority(t *testing.T) {
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		body      string // %d is the other user's priority
+		status    int
+		wantTitle string
+	}{
+		{
+			name:      "title and completion change",
+			body:      `{"title": "  Ship it  ", "completed": true}`,
+			status:    http.StatusOK,
+			wantTitle: "Ship it",
+		},
+		{
+			name:      "another user's priority ID is ignored",
+			body:      `{"ID": %d, "UserID": 1, "title": "Taken over"}`,
+			status:    http.StatusOK,
+			wantTitle: "Taken over",
+		},
+		{
+			name:      "an unknown ID inserts nothing",
+			body:      `{"ID": 999999999, "title": "Inserted"}`,
+			status:    http.StatusOK,
+			wantTitle: "Inserted",
+		},
+		{
+			name:      "an empty title is refused",
+			body:      `{"title": "  "}`,
+			status:    http.StatusBadRequest,
+			wantTitle: "Exercise",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			db := testdb.Open(t)
+			user := testdb.User(t, db, "secret")
+			other := testdb.User(t, db, "secret")
+
+			own := models.Priority{UserID: user.ID, Date: day, Title: "Exercise", Position: 1}
+			foreign := models.Priority{UserID: other.ID, Date: day, Title: "Read", Position: 1}
+			for _, priority := range []*models.Priority{&own, &foreign} {
+				if err := db.CreatePriority(priority); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var before int64
+			if err := db.DB.Model(&models.Priority{}).Count(&before).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			h := NewPlannerHandler(db, time.Hour)
+			router := gin.New()
+			router.PUT("/priorities/:id", func(c *gin.Context) { c.Set("user_id", user.ID) }, h.UpdatePriority)
+
+			body := tt.body
+			if strings.Contains(body, "%d") {
+				body = fmt.Sprintf(body, foreign.ID)
+			}
+			req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/priorities/%d", own.ID), strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+
+			got, err := db.FindPriorityByID(own.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Title != tt.wantTitle || got.UserID != user.ID {
+				t.Errorf("priority = %q of user %d, want %q of user %d", got.Title, got.UserID, tt.wantTitle, user.ID)
+			}
+			untouched, err := db.FindPriorityByID(foreign.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if untouched.Title != "Read" || untouched.UserID != other.ID {
+				t.Errorf("other user's priority = %q of user %d, want it unchanged", untouched.Title, untouched.UserID)
+			}
+			var after int64
+			if err := db.DB.Model(&models.Priority{}).Count(&after).Error; err != nil {
+				t.Fatal(err)
+			}
+			if after != before {
+				t.Errorf("priorities = %d after the update, want %d", after, before)
+			}
+		})
+	}
+}
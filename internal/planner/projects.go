@@ -0,0 +1,258 @@
+package planner
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// projectSummary is a project with its todo completion statistics
+type projectSummary struct {
+	models.Project
+	TotalTodos     int `json:"totalTodos"`
+	CompletedTodos int `json:"completedTodos"`
+	OpenTodos      int `json:"openTodos"`
+	CompletionRate int `json:"completionRate"` // Percentage of todos completed
+}
+
+func summarizeProject(project models.Project, stats repository.ProjectStats) projectSummary {
+	summary := projectSummary{
+		Project:        project,
+		TotalTodos:     stats.Total,
+		CompletedTodos: stats.Completed,
+		OpenTodos:      stats.Total - stats.Completed,
+	}
+	if stats.Total > 0 {
+		summary.CompletionRate = stats.Completed * 100 / stats.Total
+	}
+	return summary
+}
+
+// loadProjectSummaries fetches the user's projects together with their statistics
+func (h *PlannerHandler) loadProjectSummaries(userID uint, includeArchived bool) ([]projectSummary, error) {
+	projects, err := h.db.FindProjectsByUserID(userID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := h.db.CountTodosByProject(userID)
+	if err != nil {
+		return nil, err
+	}
+	byProject := make(map[uint]repository.ProjectStats, len(stats))
+	for _, s := range stats {
+		if s.ProjectID != nil {
+			byProject[*s.ProjectID] = s
+		}
+	}
+
+	summaries := make([]projectSummary, 0, len(projects))
+	for _, project := range projects {
+		summaries = append(summaries, summarizeProject(project, byProject[project.ID]))
+	}
+	return summaries, nil
+}
+
+func (h *PlannerHandler) findUserProject(c *gin.Context) (*models.Project, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+
+	project, err := h.db.FindProjectByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return nil, false
+	}
+
+	return project, true
+}
+
+// resolveProject checks that an optional project ID belongs to the user. A
+// zero ID means "no project".
+func (h *PlannerHandler) resolveProject(userID uint, id *uint) (*uint, bool) {
+	if id == nil || *id == 0 {
+		return nil, true
+	}
+	if _, err := h.db.FindProjectByIDAndUserID(*id, userID); err != nil {
+		return nil, false
+	}
+	return id, true
+}
+
+// GetProjects handles retrieving the user's projects with completion statistics
+func (h *PlannerHandler) GetProjects(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	summaries, err := h.loadProjectSummaries(userID.(uint), c.Query("archived") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summaries)
+}
+
+// CreateProject handles creating a new project
+func (h *PlannerHandler) CreateProject(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var projectData struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color" binding:"max=16"`
+	}
+	if err := c.ShouldBindJSON(&projectData); err != nil || strings.TrimSpace(projectData.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project name is required"})
+		return
+	}
+
+	position, err := h.db.NextProjectPosition(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	project := models.Project{
+		UserID:   userID.(uint),
+		Name:     strings.TrimSpace(projectData.Name),
+		Color:    projectData.Color,
+		Position: position,
+	}
+
+	err = h.db.CreateProject(&project)
+	if errors.Is(err, repository.ErrProjectExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A project with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// UpdateProject handles renaming, recoloring, archiving or moving a project
+func (h *PlannerHandler) UpdateProject(c *gin.Context) {
+	project, ok := h.findUserProject(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name     *string  `json:"name"`
+		Color    *string  `json:"color"`
+		Archived *bool    `json:"archived"`
+		Position *float64 `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updateData.Name != nil {
+		name := strings.TrimSpace(*updateData.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Project name is required"})
+			return
+		}
+		project.Name = name
+	}
+	if updateData.Color != nil {
+		project.Color = *updateData.Color
+	}
+	if updateData.Archived != nil {
+		project.Archived = *updateData.Archived
+	}
+	if updateData.Position != nil {
+		project.Position = *updateData.Position
+	}
+
+	err := h.db.UpdateProject(project)
+	if errors.Is(err, repository.ErrProjectExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A project with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject handles deleting a project; its todos move back to the inbox
+func (h *PlannerHandler) DeleteProject(c *gin.Context) {
+	project, ok := h.findUserProject(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteProject(project); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project deleted successfully"})
+}
+
+// GetProjectTodos handles retrieving a project's todos; it accepts the same
+// filters and sorting as GetTodos
+func (h *PlannerHandler) GetProjectTodos(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	project, ok := h.findUserProject(c)
+	if !ok {
+		return
+	}
+
+	filter, err := parseTodoFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.ProjectID = &project.ID
+	filter.Inbox = false
+
+	todos, err := h.db.FindTodos(userID.(uint), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+		return
+	}
+	applyProgress(todos)
+
+	c.JSON(http.StatusOK, todos)
+}
+
+// GetProjectStats handles retrieving a project's completion statistics
+func (h *PlannerHandler) GetProjectStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	project, ok := h.findUserProject(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.db.CountTodosByProject(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch project statistics"})
+		return
+	}
+
+	for _, s := range stats {
+		if s.ProjectID != nil && *s.ProjectID == project.ID {
+			c.JSON(http.StatusOK, summarizeProject(*project, s))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, summarizeProject(*project, repository.ProjectStats{}))
+}
@@ -0,0 +1,48 @@
+package planner
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/testdb"
+)
+
+func TestCreateProject(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "new name", body: `{"name": " Home "}`, status: http.StatusCreated},
+		{name: "blank name", body: `{"name": "   "}`, status: http.StatusBadRequest},
+		{name: "name taken, ignoring case", body: `{"name": "WORK"}`, status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			db := testdb.Open(t)
+			user := testdb.User(t, db, "secret")
+			if err := db.CreateProject(&models.Project{UserID: user.ID, Name: "Work", Position: 1}); err != nil {
+				t.Fatal(err)
+			}
+
+			h := NewPlannerHandler(db, time.Hour)
+			router := gin.New()
+			router.POST("/projects", func(c *gin.Context) { c.Set("user_id", user.ID) }, h.CreateProject)
+
+			req := httptest.NewRequest(http.MethodPost, "/projects", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Ways to reschedule an unfinished item when reviewing a day
+const (
+	reviewTomorrow = "tomorrow"
+	reviewLater    = "later" // To a chosen day, a week on by default
+	reviewDrop     = "drop"  // Delete it, to the trash
+)
+
+// reviewItems are a day's todos and priorities. Todos promoted that day
+// are only listed as priorities.
+type reviewItems struct {
+	Todos      []models.TodoItem `json:"todos"`
+	Priorities []models.Priority `json:"priorities"`
+}
+
+// dailyReview is a day's review with what got done and what didn't
+type dailyReview struct {
+	Date       string      `json:"date"`
+	Reflection string      `json:"reflection"`
+	ReviewedAt *time.Time  `json:"reviewedAt"` // Nil until the day is reviewed
+	Completed  reviewItems `json:"completed"`
+	Unfinished reviewItems `json:"unfinished"`
+	Tomorrow   string      `json:"tomorrow"` // The day "tomorrow" moves items to
+	Later      string      `json:"later"`    // The day "later" moves items to by default
+}
+
+// reviewDates returns the day a review is for, from the :date parameter or
+// ?date=, defaulting to the user's today, and the day after it that items
+// move to, which is never before today
+func (h *PlannerHandler) reviewDates(c *gin.Context, userID uint) (time.Time, time.Time, bool) {
+	user, err := h.db.FindUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return time.Time{}, time.Time{}, false
+	}
+	today := user.Today(time.Now())
+
+	day := today
+	value := c.Param("date")
+	if value == "" {
+		value = c.Query("date")
+	}
+	if value != "" {
+		if day, err = time.Parse(dateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+
+	tomorrow := day.AddDate(0, 0, 1)
+	if tomorrow.Before(today) {
+		tomorrow = today
+	}
+	return day, tomorrow, true
+}
+
+// loadDailyReview gathers a day's review and its todos and priorities
+func (h *PlannerHandler) loadDailyReview(userID uint, day, tomorrow time.Time) (*dailyReview, error) {
+	review := &dailyReview{
+		Date:       day.Format(dateLayout),
+		Completed:  reviewItems{Todos: []models.TodoItem{}, Priorities: []models.Priority{}},
+		Unfinished: reviewItems{Todos: []models.TodoItem{}, Priorities: []models.Priority{}},
+		Tomorrow:   tomorrow.Format(dateLayout),
+		Later:      tomorrow.AddDate(0, 0, 7).Format(dateLayout),
+	}
+
+	saved, err := h.db.FindDailyReview(userID, day)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		review.Reflection = saved.Reflection
+		review.ReviewedAt = saved.ReviewedAt
+	}
+
+	priorities, err := h.db.FindPrioritiesByUserIDAndDate(userID, day)
+	if err != nil {
+		return nil, err
+	}
+	rankPriorities(priorities)
+	promoted := make(map[uint]bool, len(priorities))
+	for _, priority := range priorities {
+		if priority.TodoItemID != nil {
+			promoted[*priority.TodoItemID] = true
+		}
+		if priority.Completed {
+			review.Completed.Priorities = append(review.Completed.Priorities, priority)
+		} else {
+			review.Unfinished.Priorities = append(review.Unfinished.Priorities, priority)
+		}
+	}
+
+	var todos []models.TodoItem
+	if err := h.db.DB.Where("user_id = ? AND DATE(due_date) = ?", userID, day.Format(dateLayout)).Order("position").Order("id").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	for _, todo := range todos {
+		switch {
+		case promoted[todo.ID]:
+		case todo.Completed:
+			review.Completed.Todos = append(review.Completed.Todos, todo)
+		default:
+			review.Unfinished.Todos = append(review.Unfinished.Todos, todo)
+		}
+	}
+	return review, nil
+}
+
+// GetDailyReview handles a day's review: what was completed and what
+// wasn't among its todos and priorities, and the reflection written for it
+func (h *PlannerHandler) GetDailyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	day, tomorrow, ok := h.reviewDates(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	review, err := h.loadDailyReview(userID.(uint), day, tomorrow)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// SaveDailyReview handles reviewing a day: it saves the reflection, moves
+// each listed unfinished item to tomorrow or a later day or drops it, and
+// marks the day as reviewed. Nothing changes if any item can't be moved.
+func (h *PlannerHandler) SaveDailyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	day, tomorrow, ok := h.reviewDates(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var reviewData struct {
+		Reflection string `json:"reflection"`
+		Items      []struct {
+			Type   string `json:"type"` // todo or priority
+			ID     uint   `json:"id"`
+			Action string `json:"action"`
+			Date   string `json:"date"` // For later; a week after tomorrow by default
+		} `json:"items"`
+	}
+	if err := c.ShouldBindJSON(&reviewData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moves := make([]repository.ReviewMove, 0, len(reviewData.Items))
+	for _, item := range reviewData.Items {
+		var move repository.ReviewMove
+		switch item.Type {
+		case "todo":
+			move.TodoID = item.ID
+		case "priority":
+			move.PriorityID = item.ID
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item type must be todo or priority"})
+			return
+		}
+
+		switch item.Action {
+		case reviewTomorrow:
+			move.Date = tomorrow
+		case reviewLater:
+			move.Date = tomorrow.AddDate(0, 0, 7)
+			if item.Date != "" {
+				date, err := time.Parse(dateLayout, item.Date)
+				if err != nil || !date.After(day) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "A later date must be YYYY-MM-DD after the reviewed day"})
+					return
+				}
+				move.Date = date
+			}
+		case reviewDrop:
+			move.Drop = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Action must be tomorrow, later or drop"})
+			return
+		}
+		moves = append(moves, move)
+	}
+
+	review, err := h.db.FindDailyReview(userID.(uint), day)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		review, err = &models.DailyReview{UserID: userID.(uint), Date: day}, nil
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+	review.Reflection = reviewData.Reflection
+
+	limit, err := h.priorityLimit(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+
+	err = h.db.SaveDailyReview(review, moves, limit)
+	if errors.Is(err, repository.ErrPriorityLimit) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("A day you moved a priority to already has %d priorities. Move it elsewhere or drop it", limit),
+			"limit": limit,
+		})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "An item isn't among the day's unfinished todos and priorities"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+		return
+	}
+
+	result, err := h.loadDailyReview(userID.(uint), day, tomorrow)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review"})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ShowDailyReview renders the end-of-day review page, for ?date= or today
+func (h *PlannerHandler) ShowDailyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	day, tomorrow, ok := h.reviewDates(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	review, err := h.loadDailyReview(userID.(uint), day, tomorrow)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review"})
+		return
+	}
+
+	c.HTML(http.StatusOK, "review.html", gin.H{
+		"Title":    "Daily Review",
+		"Review":   review,
+		"Day":      day,
+		"Previous": day.AddDate(0, 0, -1).Format(dateLayout),
+		"Next":     day.AddDate(0, 0, 1).Format(dateLayout),
+	})
+}
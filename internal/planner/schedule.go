@@ -0,0 +1,468 @@
+package planner
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+const (
+	timeLayout = "15:04"
+
+	// Default hour range of the dashboard schedule grid
+	scheduleStartHour = 6
+	scheduleEndHour   = 22
+
+	// Height of one hour row in the schedule grid, in pixels
+	pixelsPerHour = 48
+)
+
+type timeBlockData struct {
+	Date         string `json:"date"`
+	Start        string `json:"start"` // HH:MM
+	End          string `json:"end"`   // HH:MM, 24:00 for midnight
+	Title        string `json:"title"`
+	Color        string `json:"color"`
+	TodoItemID   linkID `json:"todoId"`
+	PriorityID   linkID `json:"priorityId"`
+	ContactID    linkID `json:"contactId"`
+	AllowOverlap bool   `json:"allowOverlap"`
+}
+
+// linkID is the item a time block is linked to. When editing a block, an
+// explicit null or 0 removes the link, while leaving the field out keeps it.
+type linkID struct {
+	Set bool  // The field was in the request
+	ID  *uint // Nil for no link
+}
+
+func (l *linkID) UnmarshalJSON(b []byte) error {
+	l.Set = true
+	l.ID = nil
+	if string(b) == "null" {
+		return nil
+	}
+	var id uint
+	if err := json.Unmarshal(b, &id); err != nil {
+		return err
+	}
+	if id != 0 {
+		l.ID = &id
+	}
+	return nil
+}
+
+// timeBlockView is a time block annotated with whether it clashes with another block
+type timeBlockView struct {
+	models.TimeBlock
+	Overlaps bool `json:"overlaps"`
+}
+
+// scheduleBlock is a time block positioned on the dashboard hour grid
+type scheduleBlock struct {
+	timeBlockView
+	Top    int
+	Height int
+	Lane   int
+	Left   int // Horizontal offset in percent
+	Width  int // Width in percent
+}
+
+type scheduleView struct {
+	Date   string
+	Hours  []int
+	Height int
+	Blocks []scheduleBlock
+}
+
+// parseClock combines a day with an HH:MM time of day
+func parseClock(date time.Time, clock string) (time.Time, error) {
+	if clock == "24:00" {
+		return date.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(timeLayout, clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.Add(time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute), nil
+}
+
+// markOverlaps flags every block that intersects another block in the list
+func markOverlaps(blocks []models.TimeBlock) []timeBlockView {
+	views := make([]timeBlockView, len(blocks))
+	for i, block := range blocks {
+		views[i].TimeBlock = block
+		for j, other := range blocks {
+			if i != j && block.StartAt.Before(other.EndAt) && other.StartAt.Before(block.EndAt) {
+				views[i].Overlaps = true
+				break
+			}
+		}
+	}
+	return views
+}
+
+// buildSchedule lays out a day's blocks on an hour grid, widening the grid
+// when blocks fall outside the default hours and placing clashing blocks in
+// separate lanes
+func buildSchedule(date time.Time, blocks []models.TimeBlock) scheduleView {
+	startHour, endHour := scheduleStartHour, scheduleEndHour
+	for _, block := range blocks {
+		if h := block.StartAt.Sub(date).Hours(); int(h) < startHour {
+			startHour = int(h)
+		}
+		if h := block.EndAt.Sub(date).Hours(); int(h+0.99) > endHour {
+			endHour = int(h + 0.99)
+		}
+	}
+	if startHour < 0 {
+		startHour = 0
+	}
+	if endHour > 24 {
+		endHour = 24
+	}
+
+	view := scheduleView{
+		Date:   date.Format(dateLayout),
+		Height: (endHour - startHour) * pixelsPerHour,
+	}
+	for h := startHour; h < endHour; h++ {
+		view.Hours = append(view.Hours, h)
+	}
+
+	gridStart := date.Add(time.Duration(startHour) * time.Hour)
+	var laneEnds []time.Time
+	var clusterEnd time.Time
+	clusterStart := 0
+	for _, block := range markOverlaps(blocks) {
+		// A block that starts after everything so far has ended begins a new
+		// cluster, and clashing blocks share the width of their cluster
+		if !block.StartAt.Before(clusterEnd) {
+			spreadLanes(view.Blocks[clusterStart:], len(laneEnds))
+			clusterStart = len(view.Blocks)
+			laneEnds = nil
+		}
+		if block.EndAt.After(clusterEnd) {
+			clusterEnd = block.EndAt
+		}
+
+		// Reuse the first lane whose last block has already ended
+		lane := len(laneEnds)
+		for i, end := range laneEnds {
+			if !block.StartAt.Before(end) {
+				lane = i
+				break
+			}
+		}
+		if lane == len(laneEnds) {
+			laneEnds = append(laneEnds, block.EndAt)
+		} else {
+			laneEnds[lane] = block.EndAt
+		}
+
+		view.Blocks = append(view.Blocks, scheduleBlock{
+			timeBlockView: block,
+			Top:           int(block.StartAt.Sub(gridStart).Minutes()) * pixelsPerHour / 60,
+			Height:        int(block.EndAt.Sub(block.StartAt).Minutes()) * pixelsPerHour / 60,
+			Lane:          lane,
+		})
+	}
+	spreadLanes(view.Blocks[clusterStart:], len(laneEnds))
+
+	return view
+}
+
+// spreadLanes places the blocks of one cluster side by side across the row
+func spreadLanes(blocks []scheduleBlock, lanes int) {
+	if lanes == 0 {
+		return
+	}
+	for i := range blocks {
+		blocks[i].Width = 100 / lanes
+		blocks[i].Left = blocks[i].Lane * blocks[i].Width
+	}
+}
+
+// linkedTitle validates the optional todo/priority/contact link and returns its title
+func (h *PlannerHandler) linkedTitle(userID interface{}, data timeBlockData) (string, string) {
+	switch {
+	case data.TodoItemID.ID != nil:
+		var todo models.TodoItem
+		if err := h.db.DB.Where("id = ? AND user_id = ?", *data.TodoItemID.ID, userID).First(&todo).Error; err != nil {
+			return "", "Todo not found"
+		}
+		return todo.Title, ""
+	case data.PriorityID.ID != nil:
+		var priority models.Priority
+		if err := h.db.DB.Where("id = ? AND user_id = ?", *data.PriorityID.ID, userID).First(&priority).Error; err != nil {
+			return "", "Priority not found"
+		}
+		return priority.Title, ""
+	case data.ContactID.ID != nil:
+		var contact models.Contact
+		if err := h.db.DB.Where("id = ? AND user_id = ?", *data.ContactID.ID, userID).First(&contact).Error; err != nil {
+			return "", "Contact not found"
+		}
+		return contact.Type + " " + contact.Name, ""
+	}
+	return "", ""
+}
+
+func linkCount(data timeBlockData) int {
+	count := 0
+	for _, link := range []linkID{data.TodoItemID, data.PriorityID, data.ContactID} {
+		if link.ID != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func (h *PlannerHandler) findUserTimeBlock(c *gin.Context) (*models.TimeBlock, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time block not found"})
+		return nil, false
+	}
+
+	block, err := h.db.FindTimeBlockByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Time block not found"})
+		return nil, false
+	}
+
+	return block, true
+}
+
+// checkOverlaps responds with 409 and the clashing blocks unless overlaps are allowed
+func (h *PlannerHandler) checkOverlaps(c *gin.Context, block *models.TimeBlock, allow bool) bool {
+	conflicts, err := h.db.FindOverlappingTimeBlocks(block.UserID, block.StartAt, block.EndAt, block.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check schedule"})
+		return false
+	}
+	if len(conflicts) > 0 && !allow {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     "Time block overlaps existing blocks",
+			"conflicts": conflicts,
+		})
+		return false
+	}
+	return true
+}
+
+// GetTimeBlocks handles retrieving the time blocks for a day (defaults to today)
+func (h *PlannerHandler) GetTimeBlocks(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	date := h.today(userID.(uint)).Format(dateLayout)
+	if v := c.Query("date"); v != "" {
+		if _, err := time.Parse(dateLayout, v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = v
+	}
+
+	blocks, err := h.db.FindTimeBlocksByUserIDAndDate(userID.(uint), date)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch time blocks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, markOverlaps(blocks))
+}
+
+// CreateTimeBlock handles scheduling a new time block
+func (h *PlannerHandler) CreateTimeBlock(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var data timeBlockData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := h.today(userID.(uint))
+	if data.Date != "" {
+		parsed, err := time.Parse(dateLayout, data.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	start, err := parseClock(date, data.Start)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time. Use HH:MM"})
+		return
+	}
+	end, err := parseClock(date, data.End)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time. Use HH:MM"})
+		return
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
+		return
+	}
+
+	if linkCount(data) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link a time block to at most one todo, priority or contact"})
+		return
+	}
+	title, msg := h.linkedTitle(userID, data)
+	if msg != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+	if data.Title != "" {
+		title = data.Title
+	}
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title is required"})
+		return
+	}
+
+	block := models.TimeBlock{
+		UserID:     userID.(uint),
+		Date:       date,
+		StartAt:    start,
+		EndAt:      end,
+		Title:      title,
+		Color:      data.Color,
+		TodoItemID: data.TodoItemID.ID,
+		PriorityID: data.PriorityID.ID,
+		ContactID:  data.ContactID.ID,
+	}
+
+	if !h.checkOverlaps(c, &block, data.AllowOverlap) {
+		return
+	}
+
+	if err := h.db.CreateTimeBlock(&block); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create time block"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, block)
+}
+
+// UpdateTimeBlock handles editing or moving a time block. Sending only a new
+// start (and optionally date) moves the block and keeps its duration.
+func (h *PlannerHandler) UpdateTimeBlock(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	block, ok := h.findUserTimeBlock(c)
+	if !ok {
+		return
+	}
+
+	var data timeBlockData
+	if err := c.ShouldBindJSON(&data); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date := block.Date
+	if data.Date != "" {
+		parsed, err := time.Parse(dateLayout, data.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	duration := block.EndAt.Sub(block.StartAt)
+	start := date.Add(block.StartAt.Sub(block.Date))
+	if data.Start != "" {
+		parsed, err := parseClock(date, data.Start)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start time. Use HH:MM"})
+			return
+		}
+		start = parsed
+	}
+	end := start.Add(duration)
+	if data.End != "" {
+		parsed, err := parseClock(date, data.End)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end time. Use HH:MM"})
+			return
+		}
+		end = parsed
+	}
+	if !end.After(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End time must be after start time"})
+		return
+	}
+
+	if linkCount(data) > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Link a time block to at most one todo, priority or contact"})
+		return
+	}
+	if linkCount(data) == 1 {
+		if _, msg := h.linkedTitle(userID, data); msg != "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": msg})
+			return
+		}
+		block.TodoItemID = data.TodoItemID.ID
+		block.PriorityID = data.PriorityID.ID
+		block.ContactID = data.ContactID.ID
+	} else {
+		// Unlink what was sent as null or 0
+		if data.TodoItemID.Set {
+			block.TodoItemID = nil
+		}
+		if data.PriorityID.Set {
+			block.PriorityID = nil
+		}
+		if data.ContactID.Set {
+			block.ContactID = nil
+		}
+	}
+
+	block.Date = date
+	block.StartAt = start
+	block.EndAt = end
+	if data.Title != "" {
+		block.Title = data.Title
+	}
+	if data.Color != "" {
+		block.Color = data.Color
+	}
+
+	if !h.checkOverlaps(c, block, data.AllowOverlap) {
+		return
+	}
+
+	if err := h.db.UpdateTimeBlock(block); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update time block"})
+		return
+	}
+
+	c.JSON(http.StatusOK, block)
+}
+
+// DeleteTimeBlock handles removing a time block from the schedule
+func (h *PlannerHandler) DeleteTimeBlock(c *gin.Context) {
+	block, ok := h.findUserTimeBlock(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteTimeBlock(block.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete time block"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Time block deleted successfully"})
+}
@@ -0,0 +1,151 @@
+package planner
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+func TestBuildSchedule(t *testing.T) {
+	date := time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return date.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	block := func(title string, start, end time.Time) models.TimeBlock {
+		return models.TimeBlock{Title: title, StartAt: start, EndAt: end}
+	}
+
+	type placed struct {
+		title                          string
+		top, height, lane, left, width int
+		overlaps                       bool
+	}
+	tests := []struct {
+		name                string
+		blocks              []models.TimeBlock
+		firstHour, lastHour int
+		placements          []placed
+	}{
+		{
+			name:      "empty day uses the default hours",
+			firstHour: 6,
+			lastHour:  21,
+		},
+		{
+			name:       "single block",
+			blocks:     []models.TimeBlock{block("A", at(9, 0), at(10, 30))},
+			firstHour:  6,
+			lastHour:   21,
+			placements: []placed{{"A", 144, 72, 0, 0, 100, false}},
+		},
+		{
+			name:       "early block widens the start",
+			blocks:     []models.TimeBlock{block("A", at(5, 30), at(7, 0))},
+			firstHour:  5,
+			lastHour:   21,
+			placements: []placed{{"A", 24, 72, 0, 0, 100, false}},
+		},
+		{
+			name:       "block ending part way through an hour widens the end",
+			blocks:     []models.TimeBlock{block("A", at(21, 0), at(22, 15))},
+			firstHour:  6,
+			lastHour:   22,
+			placements: []placed{{"A", 720, 60, 0, 0, 100, false}},
+		},
+		{
+			name:       "block running to midnight",
+			blocks:     []models.TimeBlock{block("A", at(22, 0), at(24, 0))},
+			firstHour:  6,
+			lastHour:   23,
+			placements: []placed{{"A", 768, 96, 0, 0, 100, false}},
+		},
+		{
+			name: "clashing blocks share their cluster",
+			blocks: []models.TimeBlock{
+				block("A", at(9, 0), at(11, 0)),
+				block("B", at(10, 0), at(12, 0)),
+				block("C", at(13, 0), at(14, 0)),
+			},
+			firstHour: 6,
+			lastHour:  21,
+			placements: []placed{
+				{"A", 144, 96, 0, 0, 50, true},
+				{"B", 192, 96, 1, 50, 50, true},
+				{"C", 336, 48, 0, 0, 100, false},
+			},
+		},
+		{
+			name: "a lane is reused once its block has ended",
+			blocks: []models.TimeBlock{
+				block("A", at(9, 0), at(12, 0)),
+				block("B", at(9, 0), at(10, 0)),
+				block("C", at(10, 0), at(11, 0)),
+			},
+			firstHour: 6,
+			lastHour:  21,
+			placements: []placed{
+				{"A", 144, 144, 0, 0, 50, true},
+				{"B", 144, 48, 1, 50, 50, true},
+				{"C", 192, 48, 1, 50, 50, true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			view := buildSchedule(date, tt.blocks)
+
+			if view.Date != "2024-03-13" {
+				t.Errorf("Date = %q, want 2024-03-13", view.Date)
+			}
+			if first, last := view.Hours[0], view.Hours[len(view.Hours)-1]; first != tt.firstHour || last != tt.lastHour {
+				t.Errorf("Hours = %d..%d, want %d..%d", first, last, tt.firstHour, tt.lastHour)
+			}
+			if want := len(view.Hours) * pixelsPerHour; view.Height != want {
+				t.Errorf("Height = %d, want %d", view.Height, want)
+			}
+
+			if len(view.Blocks) != len(tt.placements) {
+				t.Fatalf("got %d blocks, want %d", len(view.Blocks), len(tt.placements))
+			}
+			for i, want := range tt.placements {
+				b := view.Blocks[i]
+				got := placed{b.Title, b.Top, b.Height, b.Lane, b.Left, b.Width, b.Overlaps}
+				if got != want {
+					t.Errorf("block %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTimeBlockDataLinks(t *testing.T) {
+	tests := []struct {
+		body string
+		set  bool
+		id   uint // 0 for no link
+	}{
+		{body: `{}`},
+		{body: `{"todoId": null}`, set: true},
+		{body: `{"todoId": 0}`, set: true},
+		{body: `{"todoId": 5}`, set: true, id: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			var data timeBlockData
+			if err := json.Unmarshal([]byte(tt.body), &data); err != nil {
+				t.Fatal(err)
+			}
+			var id uint
+			if data.TodoItemID.ID != nil {
+				id = *data.TodoItemID.ID
+			}
+			if data.TodoItemID.Set != tt.set || id != tt.id {
+				t.Errorf("TodoItemID = {Set: %v, ID: %d}, want {Set: %v, ID: %d}", data.TodoItemID.Set, id, tt.set, tt.id)
+			}
+		})
+	}
+}
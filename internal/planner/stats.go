@@ -0,0 +1,177 @@
+package planner
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+// todoFlowPoint is the todos created and completed in one day or week
+type todoFlowPoint struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+// ratePoint is how many things were due in one day or week and how many
+// of them were done
+type ratePoint struct {
+	Date  string `json:"date"`
+	Total int    `json:"total"`
+	Done  int    `json:"done"`
+	Rate  int    `json:"rate"` // Percentage done
+}
+
+type rateSummary struct {
+	Total  int         `json:"total"`
+	Done   int         `json:"done"`
+	Rate   int         `json:"rate"` // Percentage done
+	Series []ratePoint `json:"series"`
+}
+
+func percentage(done, total int) int {
+	if total == 0 {
+		return 0
+	}
+	return done * 100 / total
+}
+
+// statsBuckets lists the days, or the Mondays of the weeks, from from to to
+func statsBuckets(from, to time.Time, interval string) []string {
+	step := 1
+	if interval == repository.StatsWeekly {
+		from, to, step = weekStart(from), weekStart(to), 7
+	}
+	var buckets []string
+	for d := from; !d.After(to); d = d.AddDate(0, 0, step) {
+		buckets = append(buckets, d.Format(dateLayout))
+	}
+	return buckets
+}
+
+// summarizeRates totals the counts and fills in the buckets that had none
+func summarizeRates(rates []repository.StatsRate, buckets []string) rateSummary {
+	byBucket := make(map[string]repository.StatsRate, len(rates))
+	for _, rate := range rates {
+		byBucket[rate.Bucket.Format(dateLayout)] = rate
+	}
+
+	summary := rateSummary{Series: make([]ratePoint, 0, len(buckets))}
+	for _, bucket := range buckets {
+		rate := byBucket[bucket]
+		summary.Total += rate.Total
+		summary.Done += rate.Hit
+		summary.Series = append(summary.Series, ratePoint{
+			Date:  bucket,
+			Total: rate.Total,
+			Done:  rate.Hit,
+			Rate:  percentage(rate.Hit, rate.Total),
+		})
+	}
+	summary.Rate = percentage(summary.Done, summary.Total)
+	return summary
+}
+
+// GetStats handles the user's productivity trends over a date range: todos
+// created and completed, how long todos take to complete, and how often
+// priorities are completed, follow-ups are done and the water target is
+// met. ?interval=week groups the series by week instead of by day. Rates
+// only count days up to today.
+func (h *PlannerHandler) GetStats(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	interval := c.DefaultQuery("interval", repository.StatsDaily)
+	if interval != repository.StatsDaily && interval != repository.StatsWeekly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interval. Use day or week"})
+		return
+	}
+	defaultDays := 30
+	if interval == repository.StatsWeekly {
+		defaultDays = 12 * 7
+	}
+	from, to, err := h.parseDateRange(c, userID.(uint), defaultDays)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.db.FindUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+	loc := user.Location()
+	period := repository.StatsPeriod{
+		From:     from,
+		To:       to,
+		Today:    user.Today(time.Now()),
+		Location: loc,
+		Interval: interval,
+	}
+
+	flow, err := h.db.CountTodoFlow(user.ID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo stats"})
+		return
+	}
+	priorities, err := h.db.CountPriorityCompletion(user.ID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch priority stats"})
+		return
+	}
+	contacts, err := h.db.CountContactFollowUps(user.ID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact stats"})
+		return
+	}
+	water, err := h.db.CountWaterTargetDays(user.ID, period)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch water stats"})
+		return
+	}
+
+	buckets := statsBuckets(from, to, interval)
+	byBucket := make(map[string]repository.TodoFlow, len(flow))
+	for _, f := range flow {
+		byBucket[f.Bucket.Format(dateLayout)] = f
+	}
+	series := make([]todoFlowPoint, 0, len(buckets))
+	var created, completed int
+	var completeSeconds float64
+	for _, bucket := range buckets {
+		f := byBucket[bucket]
+		created += f.Created
+		completed += f.Completed
+		completeSeconds += f.CompleteSeconds
+		series = append(series, todoFlowPoint{Date: bucket, Created: f.Created, Completed: f.Completed})
+	}
+	var averageHours float64
+	if completed > 0 {
+		averageHours = math.Round(completeSeconds/float64(completed)/360) / 10
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format(dateLayout),
+		"to":       to.Format(dateLayout),
+		"interval": interval,
+		"todos": gin.H{
+			"created":                created,
+			"completed":              completed,
+			"averageHoursToComplete": averageHours,
+			"series":                 series,
+		},
+		"priorities": summarizeRates(priorities, buckets),
+		"contacts":   summarizeRates(contacts, buckets),
+		"water":      summarizeRates(water, buckets),
+	})
+}
+
+// ShowStats renders the page charting the stats
+func (h *PlannerHandler) ShowStats(c *gin.Context) {
+	c.HTML(http.StatusOK, "stats.html", gin.H{
+		"Title": "Stats",
+	})
+}
@@ -0,0 +1,209 @@
+package planner
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// subtaskProgress returns the percentage of completed subtasks, or 0 when there are none
+func subtaskProgress(subtasks []models.Subtask) int {
+	if len(subtasks) == 0 {
+		return 0
+	}
+	done := 0
+	for _, subtask := range subtasks {
+		if subtask.Completed {
+			done++
+		}
+	}
+	return done * 100 / len(subtasks)
+}
+
+// applyProgress fills the computed Progress field of todos with loaded subtasks
+func applyProgress(todos []models.TodoItem) {
+	for i := range todos {
+		todos[i].Progress = subtaskProgress(todos[i].Subtasks)
+	}
+}
+
+func (h *PlannerHandler) findUserTodo(c *gin.Context) (*models.TodoItem, bool) {
+	userID, _ := c.Get("user_id")
+
+	var todo models.TodoItem
+	if err := h.db.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&todo).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return nil, false
+	}
+
+	return &todo, true
+}
+
+func (h *PlannerHandler) findTodoSubtask(c *gin.Context, todo *models.TodoItem) (*models.Subtask, bool) {
+	id, err := strconv.ParseUint(c.Param("subtaskId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subtask not found"})
+		return nil, false
+	}
+
+	subtask, err := h.db.FindSubtaskByID(todo.ID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subtask not found"})
+		return nil, false
+	}
+
+	return subtask, true
+}
+
+// syncTodoCompletion reloads the todo's subtasks and, unless the todo is
+// completed manually, marks it complete exactly when every subtask is done
+func (h *PlannerHandler) syncTodoCompletion(todo *models.TodoItem) error {
+	subtasks, err := h.db.FindSubtasksByTodoID(todo.ID)
+	if err != nil {
+		return err
+	}
+	todo.Subtasks = subtasks
+	todo.Progress = subtaskProgress(subtasks)
+
+	if todo.ManualCompletion || len(subtasks) == 0 {
+		return nil
+	}
+
+	allDone := todo.Progress == 100
+	if todo.Completed == allDone {
+		return nil
+	}
+	todo.Completed = allDone
+	return h.db.DB.Model(todo).Update("completed", allDone).Error
+}
+
+// GetSubtasks handles retrieving a todo's checklist
+func (h *PlannerHandler) GetSubtasks(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+
+	subtasks, err := h.db.FindSubtasksByTodoID(todo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subtasks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, subtasks)
+}
+
+// CreateSubtask handles adding a checklist item to a todo
+func (h *PlannerHandler) CreateSubtask(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+
+	var subtaskData struct {
+		Title string `json:"title" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&subtaskData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subtask title is required"})
+		return
+	}
+
+	position, err := h.db.NextSubtaskPosition(todo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subtask"})
+		return
+	}
+
+	subtask := models.Subtask{
+		UserID:     todo.UserID,
+		TodoItemID: todo.ID,
+		Title:      subtaskData.Title,
+		Position:   position,
+	}
+
+	if err := h.db.CreateSubtask(&subtask); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subtask"})
+		return
+	}
+
+	if err := h.syncTodoCompletion(todo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"subtask": subtask, "todo": todo})
+}
+
+// UpdateSubtask handles renaming, completing or moving a checklist item
+func (h *PlannerHandler) UpdateSubtask(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+	subtask, ok := h.findTodoSubtask(c, todo)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Title     *string  `json:"title"`
+		Completed *bool    `json:"completed"`
+		Position  *float64 `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if updateData.Title != nil {
+		if *updateData.Title == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Subtask title is required"})
+			return
+		}
+		subtask.Title = *updateData.Title
+	}
+	if updateData.Completed != nil {
+		subtask.Completed = *updateData.Completed
+	}
+	if updateData.Position != nil {
+		subtask.Position = *updateData.Position
+	}
+
+	if err := h.db.UpdateSubtask(subtask); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subtask"})
+		return
+	}
+
+	if err := h.syncTodoCompletion(todo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subtask": subtask, "todo": todo})
+}
+
+// DeleteSubtask handles removing a checklist item from a todo
+func (h *PlannerHandler) DeleteSubtask(c *gin.Context) {
+	todo, ok := h.findUserTodo(c)
+	if !ok {
+		return
+	}
+	subtask, ok := h.findTodoSubtask(c, todo)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteSubtask(subtask.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete subtask"})
+		return
+	}
+
+	if err := h.syncTodoCompletion(todo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subtask deleted successfully", "todo": todo})
+}
@@ -0,0 +1,140 @@
+package planner
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+)
+
+type tagData struct {
+	Name  string `json:"name" binding:"required,max=64"`
+	Color string `json:"color" binding:"max=16"`
+}
+
+// resolveTags loads the user's tags for the given IDs. It reports false if any
+// ID is unknown or belongs to another user.
+func (h *PlannerHandler) resolveTags(userID uint, ids []uint) ([]models.Tag, bool) {
+	unique := make([]uint, 0, len(ids))
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	tags, err := h.db.FindTagsByIDs(userID, unique)
+	if err != nil || len(tags) != len(unique) {
+		return nil, false
+	}
+	return tags, true
+}
+
+func (h *PlannerHandler) findUserTag(c *gin.Context) (*models.Tag, bool) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return nil, false
+	}
+
+	tag, err := h.db.FindTagByIDAndUserID(uint(id), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return nil, false
+	}
+
+	return tag, true
+}
+
+// GetTags handles retrieving the user's tags
+func (h *PlannerHandler) GetTags(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	tags, err := h.db.FindTagsByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateTag handles creating a new tag
+func (h *PlannerHandler) CreateTag(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var data tagData
+	if err := c.ShouldBindJSON(&data); err != nil || strings.TrimSpace(data.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required (max 64 characters)"})
+		return
+	}
+
+	tag := models.Tag{
+		UserID: userID.(uint),
+		Name:   strings.TrimSpace(data.Name),
+		Color:  data.Color,
+	}
+
+	err := h.db.CreateTag(&tag)
+	if errors.Is(err, repository.ErrTagExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag handles renaming or recoloring a tag
+func (h *PlannerHandler) UpdateTag(c *gin.Context) {
+	tag, ok := h.findUserTag(c)
+	if !ok {
+		return
+	}
+
+	var data tagData
+	if err := c.ShouldBindJSON(&data); err != nil || strings.TrimSpace(data.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name is required (max 64 characters)"})
+		return
+	}
+
+	tag.Name = strings.TrimSpace(data.Name)
+	tag.Color = data.Color
+
+	err := h.db.UpdateTag(tag)
+	if errors.Is(err, repository.ErrTagExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A tag with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag handles deleting a tag and removing it from all todos
+func (h *PlannerHandler) DeleteTag(c *gin.Context) {
+	tag, ok := h.findUserTag(c)
+	if !ok {
+		return
+	}
+
+	if err := h.db.DeleteTag(tag); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
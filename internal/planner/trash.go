@@ -0,0 +1,183 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// Kinds of item in the trash, as named in its URLs
+const (
+	trashTodos      = "todos"
+	trashPriorities = "priorities"
+	trashContacts   = "contacts"
+)
+
+// trashItem is one deleted item as the trash lists it
+type trashItem struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	Date      time.Time `json:"date"` // Due date of a todo, or the day of a priority or reminder
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"` // When it is deleted for good
+}
+
+// trashModel returns the model a trash URL's type refers to
+func trashModel(kind string) (interface{}, bool) {
+	switch kind {
+	case trashTodos:
+		return &models.TodoItem{}, true
+	case trashPriorities:
+		return &models.Priority{}, true
+	case trashContacts:
+		return &models.Contact{}, true
+	}
+	return nil, false
+}
+
+// trashParams reads the type and ID of an item in the trash, writing a 404
+// when either is unknown
+func trashParams(c *gin.Context) (string, uint, bool) {
+	kind := c.Param("type")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if _, ok := trashModel(kind); !ok || err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in the trash"})
+		return "", 0, false
+	}
+	return kind, uint(id), true
+}
+
+// GetTrash handles listing the user's deleted todos, priorities and
+// follow-up reminders, most recently deleted first. Items are deleted for
+// good once they have been in the trash for the retention period.
+func (h *PlannerHandler) GetTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	trash, err := h.db.FindTrash(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	items := make([]trashItem, 0, len(trash.Todos)+len(trash.Priorities)+len(trash.Contacts))
+	add := func(kind string, id uint, title string, date time.Time, deletedAt gorm.DeletedAt) {
+		items = append(items, trashItem{
+			Type:      kind,
+			ID:        id,
+			Title:     title,
+			Date:      date,
+			DeletedAt: deletedAt.Time,
+			PurgeAt:   deletedAt.Time.Add(h.trashRetention),
+		})
+	}
+	for _, todo := range trash.Todos {
+		add(trashTodos, todo.ID, todo.Title, todo.DueDate, todo.DeletedAt)
+	}
+	rankPriorities(trash.Priorities) // For the titles of promoted todos
+	for _, priority := range trash.Priorities {
+		add(trashPriorities, priority.ID, priority.Title, priority.Date, priority.DeletedAt)
+	}
+	for _, contact := range trash.Contacts {
+		add(trashContacts, contact.ID, contact.Name, contact.Date, contact.DeletedAt)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":         items,
+		"retentionDays": int(h.trashRetention.Hours() / 24),
+	})
+}
+
+// RestoreFromTrash handles bringing a deleted item back. It goes to the end
+// of its list. A todo brings back the priorities it was promoted to; a
+// priority can't come back to a day that is already full.
+func (h *PlannerHandler) RestoreFromTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	kind, id, ok := trashParams(c)
+	if !ok {
+		return
+	}
+
+	response := gin.H{"message": "Restored", "type": kind, "id": id}
+	var err error
+	switch kind {
+	case trashTodos:
+		var limit, priorities int
+		if limit, err = h.priorityLimit(userID.(uint)); err == nil {
+			priorities, err = h.db.RestoreTodo(userID.(uint), id, limit)
+			response["priorities"] = priorities
+		}
+	case trashPriorities:
+		var limit int
+		if limit, err = h.priorityLimit(userID.(uint)); err == nil {
+			err = h.db.RestorePriority(userID.(uint), id, limit)
+		}
+		if errors.Is(err, repository.ErrPriorityLimit) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("That day already has %d priorities. Remove one first", limit),
+				"limit": limit,
+			})
+			return
+		}
+	case trashContacts:
+		err = h.db.RestoreContact(userID.(uint), id)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in the trash"})
+		return
+	}
+	if errors.Is(err, repository.ErrAlreadyPriority) {
+		c.JSON(http.StatusConflict, gin.H{"error": "That todo is already a priority for the day"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteFromTrash handles deleting an item in the trash for good
+func (h *PlannerHandler) DeleteFromTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	kind, id, ok := trashParams(c)
+	if !ok {
+		return
+	}
+
+	model, _ := trashModel(kind)
+	err := h.db.DeleteFromTrash(model, userID.(uint), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in the trash"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deleted for good"})
+}
+
+// EmptyTrash handles deleting everything in the trash for good
+func (h *PlannerHandler) EmptyTrash(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	if err := h.db.EmptyTrash(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to empty trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash emptied"})
+}
@@ -0,0 +1,354 @@
+package planner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"github.com/himanshu/daily-planner/internal/vcard"
+)
+
+// maxImportBytes caps the size of an uploaded contacts or calendar file
+const maxImportBytes = 5 << 20
+
+// vcardImportResult says what an import did, or would do in a dry run, with one card
+type vcardImportResult struct {
+	Name        string   `json:"name"`
+	Action      string   `json:"action"`              // create, merge or skip
+	PersonID    uint     `json:"personId,omitempty"`  // The existing person a card was merged into
+	MatchedBy   string   `json:"matchedBy,omitempty"` // email or phone
+	AddedEmails []string `json:"addedEmails,omitempty"`
+	AddedPhones []string `json:"addedPhones,omitempty"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// Import actions
+const (
+	importCreate = "create"
+	importMerge  = "merge"
+	importSkip   = "skip"
+)
+
+var nonDigits = regexp.MustCompile(`\D`)
+
+// phoneKey reduces a phone number to the digits used to spot duplicates.
+// Long numbers are compared on their last ten digits so that a number with
+// and without its country code match.
+func phoneKey(number string) string {
+	digits := nonDigits.ReplaceAllString(number, "")
+	if len(digits) > 10 {
+		digits = digits[len(digits)-10:]
+	}
+	return digits
+}
+
+// readUpload returns the request's file upload, or its raw body when it is
+// not multipart, refusing requests over limit bytes
+func readUpload(c *gin.Context, limit int64) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("Upload the file in a form field named file")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+
+	return io.ReadAll(c.Request.Body)
+}
+
+// peopleIndex finds people by email address or phone number
+type peopleIndex struct {
+	emails map[string]*models.Person
+	phones map[string]*models.Person
+}
+
+func newPeopleIndex() *peopleIndex {
+	return &peopleIndex{emails: make(map[string]*models.Person), phones: make(map[string]*models.Person)}
+}
+
+func (idx *peopleIndex) add(person *models.Person, emails []models.PersonEmail, phones []models.PersonPhone) {
+	for _, email := range emails {
+		idx.emails[strings.ToLower(email.Address)] = person
+	}
+	for _, phone := range phones {
+		if key := phoneKey(phone.Number); key != "" {
+			idx.phones[key] = person
+		}
+	}
+}
+
+func (idx *peopleIndex) match(emails []models.PersonEmail, phones []models.PersonPhone) (*models.Person, string) {
+	for _, email := range emails {
+		if person, ok := idx.emails[strings.ToLower(email.Address)]; ok {
+			return person, "email"
+		}
+	}
+	for _, phone := range phones {
+		if person, ok := idx.phones[phoneKey(phone.Number)]; ok {
+			return person, "phone"
+		}
+	}
+	return nil, ""
+}
+
+// cardDetails converts a card's emails and phones, reporting values that are not usable
+func cardDetails(card vcard.Card) ([]models.PersonEmail, []models.PersonPhone, []string) {
+	var warnings []string
+
+	emails := make([]models.PersonEmail, 0, len(card.Emails))
+	for _, field := range card.Emails {
+		address, err := mail.ParseAddress(field.Value)
+		if err != nil {
+			warnings = append(warnings, "Skipped invalid email address "+field.Value)
+			continue
+		}
+		emails = append(emails, models.PersonEmail{Label: field.Label, Address: address.Address})
+	}
+
+	phones := make([]models.PersonPhone, 0, len(card.Phones))
+	for _, field := range card.Phones {
+		if phoneKey(field.Value) == "" || len(field.Value) > 64 {
+			warnings = append(warnings, "Skipped invalid phone number "+field.Value)
+			continue
+		}
+		phones = append(phones, models.PersonPhone{Label: field.Label, Number: field.Value})
+	}
+
+	return emails, phones, warnings
+}
+
+// ImportVCards handles importing a .vcf file into the contact book. Cards
+// whose email or phone matches an existing person are merged into that person
+// instead of creating a duplicate. With ?dryRun=true nothing is saved and the
+// response previews what would happen.
+func (h *PlannerHandler) ImportVCards(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	dryRun := c.Query("dryRun") == "true"
+
+	data, err := readUpload(c, maxImportBytes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cards, err := vcard.Parse(bytes.NewReader(data))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vCard file: " + err.Error()})
+		return
+	}
+
+	people, err := h.db.FindPeople(userID.(uint), repository.PersonFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import contacts"})
+		return
+	}
+
+	index := newPeopleIndex()
+	for i := range people {
+		index.add(&people[i], people[i].Emails, people[i].Phones)
+	}
+
+	var imports []repository.PersonImport
+	pending := make(map[*models.Person]int) // Person -> its entry in imports
+	results := make([]vcardImportResult, 0, len(cards))
+	counts := map[string]int{importCreate: 0, importMerge: 0, importSkip: 0}
+
+	for _, card := range cards {
+		emails, phones, warnings := cardDetails(card)
+		result := vcardImportResult{Name: card.Name, Warnings: warnings}
+
+		person, matchedBy := index.match(emails, phones)
+		if person == nil {
+			person = &models.Person{
+				UserID:   userID.(uint),
+				Name:     card.Name,
+				Notes:    card.Notes,
+				Birthday: card.Birthday,
+				Emails:   emails,
+				Phones:   phones,
+			}
+			tagNames := addTagNames(person, card.Categories)
+			pending[person] = len(imports)
+			imports = append(imports, repository.PersonImport{Person: person, Emails: emails, Phones: phones, TagNames: tagNames})
+			index.add(person, emails, phones)
+
+			result.Action = importCreate
+			counts[importCreate]++
+			results = append(results, result)
+			continue
+		}
+
+		// Only add the details the person does not have yet
+		known := newPeopleIndex()
+		known.add(person, person.Emails, person.Phones)
+		var newEmails []models.PersonEmail
+		for _, email := range emails {
+			if _, ok := known.emails[strings.ToLower(email.Address)]; !ok {
+				newEmails = append(newEmails, email)
+				result.AddedEmails = append(result.AddedEmails, email.Address)
+			}
+		}
+		var newPhones []models.PersonPhone
+		for _, phone := range phones {
+			if _, ok := known.phones[phoneKey(phone.Number)]; !ok {
+				newPhones = append(newPhones, phone)
+				result.AddedPhones = append(result.AddedPhones, phone.Number)
+			}
+		}
+		person.Emails = append(person.Emails, newEmails...)
+		person.Phones = append(person.Phones, newPhones...)
+		index.add(person, newEmails, newPhones)
+
+		tagNames := addTagNames(person, card.Categories)
+
+		changed := len(newEmails) > 0 || len(newPhones) > 0 || len(tagNames) > 0
+		if person.Notes == "" && card.Notes != "" {
+			person.Notes = card.Notes
+			changed = true
+		}
+		if person.Birthday == nil && card.Birthday != nil {
+			person.Birthday = card.Birthday
+			changed = true
+		}
+
+		result.PersonID = person.ID
+		result.MatchedBy = matchedBy
+		if !changed {
+			result.Action = importSkip
+			counts[importSkip]++
+			results = append(results, result)
+			continue
+		}
+
+		result.Action = importMerge
+		counts[importMerge]++
+		results = append(results, result)
+
+		if i, ok := pending[person]; ok {
+			imports[i].Emails = append(imports[i].Emails, newEmails...)
+			imports[i].Phones = append(imports[i].Phones, newPhones...)
+			imports[i].TagNames = append(imports[i].TagNames, tagNames...)
+			continue
+		}
+		pending[person] = len(imports)
+		imports = append(imports, repository.PersonImport{Person: person, Emails: newEmails, Phones: newPhones, TagNames: tagNames})
+	}
+
+	if !dryRun {
+		if err := h.db.ImportPeople(userID.(uint), imports); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import contacts"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":  dryRun,
+		"created": counts[importCreate],
+		"merged":  counts[importMerge],
+		"skipped": counts[importSkip],
+		"results": results,
+	})
+}
+
+// addTagNames adds the categories a person is not tagged with yet to their
+// tags and returns them
+func addTagNames(person *models.Person, categories []string) []string {
+	var added []string
+	for _, name := range categories {
+		tagged := false
+		for _, tag := range person.Tags {
+			if strings.EqualFold(tag.Name, name) {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			person.Tags = append(person.Tags, models.Tag{Name: name})
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+func cardFromPerson(person models.Person) vcard.Card {
+	card := vcard.Card{
+		UID:      fmt.Sprintf("person-%d@daily-planner", person.ID),
+		Name:     person.Name,
+		Notes:    person.Notes,
+		Birthday: person.Birthday,
+	}
+	for _, email := range person.Emails {
+		card.Emails = append(card.Emails, vcard.Field{Label: email.Label, Value: email.Address})
+	}
+	for _, phone := range person.Phones {
+		card.Phones = append(card.Phones, vcard.Field{Label: phone.Label, Value: phone.Number})
+	}
+	for _, tag := range person.Tags {
+		card.Categories = append(card.Categories, tag.Name)
+	}
+	return card
+}
+
+var unsafeFilename = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeVCards sends cards as a .vcf download in the version named by ?version= (3.0 by default)
+func writeVCards(c *gin.Context, cards []vcard.Card, filename string) {
+	version := c.DefaultQuery("version", vcard.Version3)
+
+	var buf bytes.Buffer
+	if err := vcard.Write(&buf, cards, version); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version must be 3.0 or 4.0"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "text/vcard; charset=utf-8", buf.Bytes())
+}
+
+// ExportVCards handles downloading the whole contact book as one .vcf file
+func (h *PlannerHandler) ExportVCards(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	people, err := h.db.FindPeople(userID.(uint), repository.PersonFilter{})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export contacts"})
+		return
+	}
+
+	cards := make([]vcard.Card, 0, len(people))
+	for _, person := range people {
+		cards = append(cards, cardFromPerson(person))
+	}
+
+	writeVCards(c, cards, "contacts.vcf")
+}
+
+// ExportVCard handles downloading one person as a .vcf file
+func (h *PlannerHandler) ExportVCard(c *gin.Context) {
+	person, ok := h.findUserPerson(c)
+	if !ok {
+		return
+	}
+
+	filename := strings.Trim(unsafeFilename.ReplaceAllString(person.Name, "-"), "-")
+	if filename == "" {
+		filename = "contact"
+	}
+
+	writeVCards(c, []vcard.Card{cardFromPerson(*person)}, filename+".vcf")
+}
@@ -0,0 +1,228 @@
+package planner
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// XML namespaces used by WebDAV and CalDAV
+const (
+	nsDAV       = "DAV:"
+	nsCalDAV    = "urn:ietf:params:xml:ns:caldav"
+	nsCalServer = "http://calendarserver.org/ns/"
+)
+
+// davPrefixes are the prefixes written for the namespaces the server uses
+var davPrefixes = map[string]string{nsDAV: "d", nsCalDAV: "c", nsCalServer: "cs"}
+
+// maxDAVBodyBytes caps the size of a PROPFIND or REPORT request body
+const maxDAVBodyBytes = 1 << 20
+
+// xmlNode is an element of a parsed request body
+type xmlNode struct {
+	Name     xml.Name
+	Attrs    []xml.Attr
+	Children []*xmlNode
+	Text     string
+}
+
+// child returns the first child element with the given name
+func (n *xmlNode) child(space, local string) *xmlNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name.Space == space && c.Name.Local == local {
+			return c
+		}
+	}
+	return nil
+}
+
+// attr returns the value of an attribute without a namespace
+func (n *xmlNode) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Space == "" && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// walk calls fn for n and every element below it
+func (n *xmlNode) walk(fn func(*xmlNode)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, c := range n.Children {
+		c.walk(fn)
+	}
+}
+
+// parseXMLBody reads a request body into a tree; an empty body gives nil
+func parseXMLBody(r io.Reader) (*xmlNode, error) {
+	decoder := xml.NewDecoder(io.LimitReader(r, maxDAVBodyBytes))
+	var root *xmlNode
+	var stack []*xmlNode
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: t.Name, Attrs: t.Attr}
+			if len(stack) == 0 {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, node)
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].Text += string(t)
+			}
+		}
+	}
+}
+
+// davProp is a property value; Inner is already-encoded XML
+type davProp struct {
+	Name  xml.Name
+	Inner string
+}
+
+// davResponse is one resource in a multistatus reply
+type davResponse struct {
+	Href   string
+	Props  []davProp
+	Status int // Set instead of Props when the resource itself failed, e.g. 404
+}
+
+// davElement writes an element in its namespace's usual prefix
+func davElement(b *strings.Builder, name xml.Name, inner string) {
+	prefix, ok := davPrefixes[name.Space]
+	tag := prefix + ":" + name.Local
+	attrs := ""
+	if !ok {
+		tag = "x:" + name.Local
+		attrs = ` xmlns:x="` + xmlEscape(name.Space) + `"`
+	}
+	if inner == "" {
+		b.WriteString("<" + tag + attrs + "/>")
+		return
+	}
+	b.WriteString("<" + tag + attrs + ">" + inner + "</" + tag + ">")
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// hrefXML encodes an href property value
+func hrefXML(href string) string {
+	return "<d:href>" + xmlEscape(href) + "</d:href>"
+}
+
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+// writeMultistatus sends a 207 reply. With requested set, each response
+// lists the properties it has under 200 and the rest under 404; otherwise
+// every property is sent.
+func writeMultistatus(c *gin.Context, responses []davResponse, requested []xml.Name) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+
+	for _, response := range responses {
+		b.WriteString("<d:response>")
+		b.WriteString(hrefXML(response.Href))
+
+		if response.Status != 0 {
+			b.WriteString("<d:status>" + statusLine(response.Status) + "</d:status></d:response>")
+			continue
+		}
+
+		found := response.Props
+		var missing []xml.Name
+		if requested != nil {
+			have := make(map[xml.Name]davProp, len(response.Props))
+			for _, prop := range response.Props {
+				have[prop.Name] = prop
+			}
+			found = nil
+			for _, name := range requested {
+				if prop, ok := have[name]; ok {
+					found = append(found, prop)
+				} else {
+					missing = append(missing, name)
+				}
+			}
+		}
+
+		if len(found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, prop := range found {
+				davElement(&b, prop.Name, prop.Inner)
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range missing {
+				davElement(&b, name, "")
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+
+	b.WriteString("</d:multistatus>")
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+// writeDAVError sends an error with a precondition element, e.g. CalDAV's
+// supported-calendar-component
+func writeDAVError(c *gin.Context, code int, condition xml.Name) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	b.WriteString(`<d:error xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	davElement(&b, condition, "")
+	b.WriteString("</d:error>")
+	c.Data(code, "application/xml; charset=utf-8", []byte(b.String()))
+}
+
+// requestedProps returns the property names a PROPFIND or REPORT asks for,
+// or nil for allprop and empty requests
+func requestedProps(body *xmlNode) []xml.Name {
+	prop := body.child(nsDAV, "prop")
+	if prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, 0, len(prop.Children))
+	for _, c := range prop.Children {
+		names = append(names, c.Name)
+	}
+	return names
+}
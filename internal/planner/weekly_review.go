@@ -0,0 +1,381 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
+)
+
+// weeklyTally is how many things were planned and how many got done
+type weeklyTally struct {
+	Planned   int `json:"planned"`
+	Completed int `json:"completed"`
+	Rate      int `json:"rate"` // Percentage completed
+}
+
+func (t *weeklyTally) add(completed bool) {
+	t.Planned++
+	if completed {
+		t.Completed++
+	}
+	t.Rate = percentage(t.Completed, t.Planned)
+}
+
+// weeklyDay is one day of a weekly review
+type weeklyDay struct {
+	Date       string      `json:"date"`
+	Weekday    string      `json:"weekday"`
+	Priorities weeklyTally `json:"priorities"`
+	Todos      weeklyTally `json:"todos"`
+	Reviewed   bool        `json:"reviewed"`
+	Reflection string      `json:"reflection,omitempty"`
+}
+
+// weeklyHabit is a habit's check-ins over a week
+type weeklyHabit struct {
+	models.Habit
+	Total   int  `json:"total"`   // Sum of the week's check-ins
+	DaysMet int  `json:"daysMet"` // Days a daily habit met its target
+	Days    int  `json:"days"`    // Days of the week so far
+	Met     bool `json:"met"`     // A weekly habit met its target, or a daily one did every day so far
+}
+
+// weeklyPlanDay is a day of the following week with its priorities so far
+type weeklyPlanDay struct {
+	Date       string            `json:"date"`
+	Weekday    string            `json:"weekday"`
+	Priorities []models.Priority `json:"priorities"`
+}
+
+// weeklyReview is a week's plan against what got done, and the next week's plan
+type weeklyReview struct {
+	From          string           `json:"from"` // The Monday
+	To            string           `json:"to"`   // The Sunday
+	Priorities    weeklyTally      `json:"priorities"`
+	Todos         weeklyTally      `json:"todos"` // Not counting todos promoted that week
+	Days          []weeklyDay      `json:"days"`
+	CarriedOver   reviewItems      `json:"carriedOver"` // What was left unfinished
+	Habits        []weeklyHabit    `json:"habits"`
+	Water         *weeklyHabit     `json:"water"`
+	Thoughts      []models.Thought `json:"thoughts"`
+	NextWeek      []weeklyPlanDay  `json:"nextWeek"`
+	PriorityLimit int              `json:"priorityLimit"`
+}
+
+// reviewWeek returns the Monday of the week a weekly review is for, from
+// the :week parameter or ?week=, which may be any day of it, defaulting to
+// the user's current week. It also returns the user's today.
+func (h *PlannerHandler) reviewWeek(c *gin.Context, userID uint) (time.Time, time.Time, bool) {
+	user, err := h.db.FindUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return time.Time{}, time.Time{}, false
+	}
+	today := user.Today(time.Now())
+
+	day := today
+	value := c.Param("week")
+	if value == "" {
+		value = c.Query("week")
+	}
+	if value != "" {
+		if day, err = time.Parse(dateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid week. Use any date in it as YYYY-MM-DD"})
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	return weekStart(day), today, true
+}
+
+// loadWeeklyReview gathers the review of the week starting on monday
+func (h *PlannerHandler) loadWeeklyReview(userID uint, monday, today time.Time) (*weeklyReview, error) {
+	sunday := monday.AddDate(0, 0, 6)
+	review := &weeklyReview{
+		From:        monday.Format(dateLayout),
+		To:          sunday.Format(dateLayout),
+		CarriedOver: reviewItems{Todos: []models.TodoItem{}, Priorities: []models.Priority{}},
+		Habits:      []weeklyHabit{},
+	}
+
+	days := make(map[string]*weeklyDay, 7)
+	for d := monday; !d.After(sunday); d = d.AddDate(0, 0, 1) {
+		review.Days = append(review.Days, weeklyDay{Date: d.Format(dateLayout), Weekday: d.Weekday().String()})
+	}
+	for i := range review.Days {
+		days[review.Days[i].Date] = &review.Days[i]
+	}
+
+	priorities, err := h.db.FindPrioritiesByUserIDAndDateRange(userID, monday, sunday)
+	if err != nil {
+		return nil, err
+	}
+	rankPriorities(priorities)
+	promoted := make(map[uint]bool, len(priorities))
+	for _, priority := range priorities {
+		if priority.TodoItemID != nil {
+			promoted[*priority.TodoItemID] = true
+		}
+		review.Priorities.add(priority.Completed)
+		if day := days[priority.Date.Format(dateLayout)]; day != nil {
+			day.Priorities.add(priority.Completed)
+		}
+		if !priority.Completed {
+			review.CarriedOver.Priorities = append(review.CarriedOver.Priorities, priority)
+		}
+	}
+
+	var todos []models.TodoItem
+	if err := h.db.DB.Where("user_id = ? AND DATE(due_date) BETWEEN ? AND ?", userID, review.From, review.To).Order("due_date").Order("position").Order("id").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	for _, todo := range todos {
+		if promoted[todo.ID] {
+			continue
+		}
+		review.Todos.add(todo.Completed)
+		if day := days[todo.DueDate.Format(dateLayout)]; day != nil {
+			day.Todos.add(todo.Completed)
+		}
+		if !todo.Completed {
+			review.CarriedOver.Todos = append(review.CarriedOver.Todos, todo)
+		}
+	}
+
+	reviews, err := h.db.FindDailyReviews(userID, monday, sunday)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		if day := days[r.Date.Format(dateLayout)]; day != nil {
+			day.Reviewed = r.ReviewedAt != nil
+			day.Reflection = r.Reflection
+		}
+	}
+
+	if err := h.loadWeeklyHabits(review, userID, monday, today); err != nil {
+		return nil, err
+	}
+
+	thoughts, err := h.db.FindThoughtsByUserIDAndDateRange(userID, review.From, review.To)
+	if err != nil {
+		return nil, err
+	}
+	review.Thoughts = append([]models.Thought{}, thoughts...)
+
+	next, err := h.db.FindPrioritiesByUserIDAndDateRange(userID, monday.AddDate(0, 0, 7), sunday.AddDate(0, 0, 7))
+	if err != nil {
+		return nil, err
+	}
+	rankPriorities(next)
+	for d := monday.AddDate(0, 0, 7); !d.After(sunday.AddDate(0, 0, 7)); d = d.AddDate(0, 0, 1) {
+		planDay := weeklyPlanDay{Date: d.Format(dateLayout), Weekday: d.Weekday().String(), Priorities: []models.Priority{}}
+		for _, priority := range next {
+			if priority.Date.Equal(d) {
+				planDay.Priorities = append(planDay.Priorities, priority)
+			}
+		}
+		review.NextWeek = append(review.NextWeek, planDay)
+	}
+
+	if review.PriorityLimit, err = h.priorityLimit(userID); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// loadWeeklyHabits sums up the week of each active habit. Days after today
+// don't count against daily habits.
+func (h *PlannerHandler) loadWeeklyHabits(review *weeklyReview, userID uint, monday, today time.Time) error {
+	habits, err := h.db.FindHabitsByUserID(userID, false)
+	if err != nil || len(habits) == 0 {
+		return err
+	}
+	ids := make([]uint, len(habits))
+	for i, habit := range habits {
+		ids[i] = habit.ID
+	}
+	checkIns, err := h.db.FindHabitCheckInsByDateRange(ids, review.From, review.To)
+	if err != nil {
+		return err
+	}
+
+	days := 7
+	if !today.After(monday.AddDate(0, 0, 6)) {
+		days = max(int(today.Sub(monday).Hours()/24)+1, 0)
+	}
+
+	for _, habit := range habits {
+		summary := weeklyHabit{Habit: habit, Days: days}
+		for _, checkIn := range checkIns {
+			if checkIn.HabitID != habit.ID {
+				continue
+			}
+			value := dayValue(habit, checkIn.Value)
+			summary.Total += value
+			if value >= habit.Target {
+				summary.DaysMet++
+			}
+		}
+		if habit.Period == models.HabitPeriodWeekly {
+			summary.DaysMet = 0
+			summary.Met = summary.Total >= habit.Target
+		} else {
+			summary.Met = days > 0 && summary.DaysMet >= days
+		}
+
+		if habit.SystemKey == models.HabitKeyWater {
+			review.Water = &summary
+		} else {
+			review.Habits = append(review.Habits, summary)
+		}
+	}
+	return nil
+}
+
+// GetWeeklyReview handles a week's review: priorities and todos planned
+// against completed, day by day, what carries over unfinished, how the
+// habits and water went, the week's thoughts and the next week's plan so far
+func (h *PlannerHandler) GetWeeklyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	monday, today, ok := h.reviewWeek(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	review, err := h.loadWeeklyReview(userID.(uint), monday, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weekly review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DownloadWeeklyReview handles downloading a week's review as an HTML file
+func (h *PlannerHandler) DownloadWeeklyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	monday, today, ok := h.reviewWeek(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	review, err := h.loadWeeklyReview(userID.(uint), monday, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weekly review"})
+		return
+	}
+
+	filename := fmt.Sprintf("weekly-review-%s.html", review.From)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.HTML(http.StatusOK, "weekly_report.html", gin.H{
+		"Title":  "Weekly Review",
+		"Review": review,
+	})
+}
+
+// PlanNextWeek handles setting priorities for the week after a reviewed
+// week, e.g. for todos that carried over. Nothing is added if any day
+// would go over the priority limit.
+func (h *PlannerHandler) PlanNextWeek(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	monday, today, ok := h.reviewWeek(c, userID.(uint))
+	if !ok {
+		return
+	}
+	from, to := monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 13)
+
+	var planData struct {
+		Priorities []struct {
+			Date        string `json:"date"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			TodoID      *uint  `json:"todoId"` // Promote this todo instead of giving a title
+		} `json:"priorities" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&planData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	priorities := make([]*models.Priority, 0, len(planData.Priorities))
+	for _, p := range planData.Priorities {
+		date, err := time.Parse(dateLayout, p.Date)
+		if err != nil || date.Before(from) || date.After(to) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Dates must be YYYY-MM-DD from %s to %s", from.Format(dateLayout), to.Format(dateLayout))})
+			return
+		}
+		priority := &models.Priority{UserID: userID.(uint), Date: date, TodoItemID: p.TodoID}
+		if p.TodoID == nil {
+			priority.Title = strings.TrimSpace(p.Title)
+			priority.Description = p.Description
+			if priority.Title == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each priority needs a title or a todoId"})
+				return
+			}
+		}
+		priorities = append(priorities, priority)
+	}
+
+	limit, err := h.priorityLimit(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan priorities"})
+		return
+	}
+
+	err = h.db.PlanPriorities(priorities, limit)
+	if errors.Is(err, repository.ErrPriorityLimit) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("That would give a day more than %d priorities", limit),
+			"limit": limit,
+		})
+		return
+	}
+	if errors.Is(err, repository.ErrAlreadyPriority) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A todo is already a priority for that day"})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to plan priorities"})
+		return
+	}
+
+	review, err := h.loadWeeklyReview(userID.(uint), monday, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weekly review"})
+		return
+	}
+	c.JSON(http.StatusCreated, review)
+}
+
+// ShowWeeklyReview renders the weekly review page, for the week of ?week= or this week
+func (h *PlannerHandler) ShowWeeklyReview(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	monday, today, ok := h.reviewWeek(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	review, err := h.loadWeeklyReview(userID.(uint), monday, today)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weekly review"})
+		return
+	}
+
+	c.HTML(http.StatusOK, "weekly_review.html", gin.H{
+		"Title":    "Weekly Review",
+		"Review":   review,
+		"Previous": monday.AddDate(0, 0, -7).Format(dateLayout),
+		"Next":     monday.AddDate(0, 0, 7).Format(dateLayout),
+	})
+}
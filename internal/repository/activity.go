@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activityEntities names the planner tables whose changes are logged
+var activityEntities = map[string]string{
+	"todo_items":        "todo",
+	"subtasks":          "subtask",
+	"projects":          "project",
+	"tags":              "tag",
+	"priorities":        "priority",
+	"contacts":          "contact",
+	"people":            "person",
+	"interactions":      "interaction",
+	"significant_dates": "significant_date",
+	"habits":            "habit",
+	"habit_check_ins":   "check_in",
+	"focus_sessions":    "focus_session",
+	"time_blocks":       "time_block",
+	"thoughts":          "thought",
+	"mood_entries":      "mood",
+	"daily_reviews":     "review",
+}
+
+// IsActivityEntity reports whether entity is a kind of record the activity log covers
+func IsActivityEntity(entity string) bool {
+	for _, name := range activityEntities {
+		if name == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// unloggedColumns change without being worth a log entry; a change to only
+// these, such as reordering a list, isn't logged at all
+var unloggedColumns = map[string]bool{
+	"id":         true,
+	"user_id":    true,
+	"created_at": true,
+	"updated_at": true,
+	"deleted_at": true,
+	"position":   true,
+}
+
+type activitySourceKey struct{}
+
+// WithSource returns a Database whose changes are logged as coming from
+// source, one of the models.ActivitySource constants
+func (db *Database) WithSource(source string) *Database {
+	ctx := context.WithValue(db.DB.Statement.Context, activitySourceKey{}, source)
+	return &Database{DB: db.DB.WithContext(ctx)}
+}
+
+// registerActivityCallbacks logs every create, update and delete of a
+// planner record made through GORM, with the record's columns before and
+// after. Rows are read back around each change so that updates made with
+// Save, Updates or a bare Where all look the same. The log is written in the
+// change's transaction, so a change is never saved without its entry.
+func registerActivityCallbacks(db *gorm.DB) error {
+	create, update, remove := db.Callback().Create(), db.Callback().Update(), db.Callback().Delete()
+	if err := create.After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+		Register("activity:after_create", afterActivity(models.ActivityCreate)); err != nil {
+		return err
+	}
+	if err := update.After("gorm:begin_transaction").Before("gorm:update").
+		Register("activity:before_update", beforeActivity); err != nil {
+		return err
+	}
+	if err := update.After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+		Register("activity:after_update", afterActivity(models.ActivityUpdate)); err != nil {
+		return err
+	}
+	if err := remove.After("gorm:begin_transaction").Before("gorm:delete").
+		Register("activity:before_delete", beforeActivity); err != nil {
+		return err
+	}
+	return remove.After("gorm:delete").Before("gorm:commit_or_rollback_transaction").
+		Register("activity:after_delete", afterActivity(models.ActivityDelete))
+}
+
+type activityRows map[uint]map[string]interface{}
+
+// activityEntity returns the entity a statement changes, if it is logged
+func activityEntity(db *gorm.DB) (string, bool) {
+	if db.Statement.Schema == nil {
+		return "", false
+	}
+	entity, ok := activityEntities[db.Statement.Schema.Table]
+	return entity, ok
+}
+
+// beforeActivity reads the rows an update or delete is about to change
+func beforeActivity(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if _, ok := activityEntity(db); !ok {
+		return
+	}
+	if columns, ok := db.Statement.Dest.(map[string]interface{}); ok && onlyUnlogged(columns) {
+		return
+	}
+
+	query := activityQuery(db)
+	if where, ok := db.Statement.Clauses["WHERE"]; ok {
+		query = query.Clauses(where.Expression)
+	}
+	if ids := primaryKeys(db); len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	} else if _, ok := db.Statement.Clauses["WHERE"]; !ok {
+		return // GORM refuses updates and deletes without conditions
+	}
+	if !db.Statement.Unscoped && db.Statement.Schema.LookUpField("deleted_at") != nil {
+		query = query.Where("deleted_at IS NULL")
+	}
+
+	rows, err := loadActivityRows(query)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet("activity:before", rows)
+}
+
+// afterActivity logs the changes a statement made, reading the rows again
+// to see them as saved
+func afterActivity(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		entity, ok := activityEntity(db)
+		if !ok {
+			return
+		}
+		// action is shared by every statement the callback runs for, so only this copy may change
+		logged := action
+
+		before := activityRows{}
+		if value, ok := db.InstanceGet("activity:before"); ok {
+			before = value.(activityRows)
+		}
+		var ids []uint
+		if logged == models.ActivityCreate {
+			ids = primaryKeys(db)
+		} else {
+			for id := range before {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			return
+		}
+
+		after, err := loadActivityRows(activityQuery(db).Where("id IN ?", ids))
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+
+		// An upsert may have changed an existing row instead of adding one
+		if c, ok := db.Statement.Clauses["ON CONFLICT"]; ok && logged == models.ActivityCreate {
+			if onConflict, ok := c.Expression.(clause.OnConflict); ok && !onConflict.DoNothing {
+				logged = models.ActivityUpdate
+			}
+		}
+
+		source, _ := db.Statement.Context.Value(activitySourceKey{}).(string)
+		var logs []models.ActivityLog
+		for _, id := range ids {
+			entry, ok := activityEntry(logged, before[id], after[id])
+			if !ok {
+				continue
+			}
+			entry.Source = source
+			entry.Entity = entity
+			entry.EntityID = id
+			if source != models.ActivitySourceJob {
+				actor := entry.UserID
+				entry.ActorID = &actor
+			}
+			logs = append(logs, entry)
+		}
+		if len(logs) == 0 {
+			return
+		}
+
+		if err := db.Session(&gorm.Session{NewDB: true}).Create(&logs).Error; err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+// activityEntry describes how one row changed, if it changed in a way
+// worth logging. New and restored records list the values they have,
+// deleted ones the values they had, and updates just what changed.
+func activityEntry(action string, before, after map[string]interface{}) (models.ActivityLog, bool) {
+	deletedBefore := before != nil && before["deleted_at"] != nil
+	deletedAfter := after != nil && after["deleted_at"] != nil
+
+	switch {
+	case action == models.ActivityCreate:
+	case after == nil || deletedBefore && action == models.ActivityDelete:
+		action = models.ActivityPurge
+	case !deletedBefore && deletedAfter:
+		action = models.ActivityDelete
+	case deletedBefore && !deletedAfter:
+		action = models.ActivityRestore
+	case before["completed"] == false && after["completed"] == true:
+		action = models.ActivityComplete
+	}
+
+	changes := models.ActivityChanges{}
+	switch action {
+	case models.ActivityCreate, models.ActivityRestore:
+		for column, value := range after {
+			if !unloggedColumns[column] && value != nil {
+				changes[column] = models.FieldChange{After: value}
+			}
+		}
+	case models.ActivityDelete, models.ActivityPurge:
+		for column, value := range before {
+			if !unloggedColumns[column] && value != nil {
+				changes[column] = models.FieldChange{Before: value}
+			}
+		}
+	default:
+		for column, value := range after {
+			if !unloggedColumns[column] && !sameValue(before[column], value) {
+				changes[column] = models.FieldChange{Before: before[column], After: value}
+			}
+		}
+		if len(changes) == 0 {
+			return models.ActivityLog{}, false
+		}
+	}
+
+	row := after
+	if row == nil {
+		row = before
+	}
+	return models.ActivityLog{UserID: uintValue(row["user_id"]), Action: action, Changes: changes}, true
+}
+
+// activityQuery starts a query of the statement's table in the same
+// transaction, seeing deleted rows too
+func activityQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Table(db.Statement.Schema.Table)
+}
+
+func loadActivityRows(query *gorm.DB) (activityRows, error) {
+	var found []map[string]interface{}
+	if err := query.Find(&found).Error; err != nil {
+		return nil, err
+	}
+	rows := make(activityRows, len(found))
+	for _, row := range found {
+		rows[uintValue(row["id"])] = row
+	}
+	return rows, nil
+}
+
+// primaryKeys returns the IDs of the records a statement was given, such as
+// the todo passed to Save or each todo of a batch create
+func primaryKeys(db *gorm.DB) []uint {
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+
+	var ids []uint
+	add := func(value reflect.Value) {
+		value = reflect.Indirect(value)
+		if value.Kind() != reflect.Struct || value.Type() != db.Statement.Schema.ModelType {
+			return
+		}
+		if id, zero := field.ValueOf(db.Statement.Context, value); !zero {
+			ids = append(ids, uintValue(id))
+		}
+	}
+
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			add(value.Index(i))
+		}
+	case reflect.Struct, reflect.Ptr:
+		add(value)
+	}
+	return ids
+}
+
+// onlyUnlogged reports whether an update sets nothing but unlogged columns.
+// Setting deleted_at deletes or restores the record, which is logged.
+func onlyUnlogged(columns map[string]interface{}) bool {
+	for column := range columns {
+		if column == "deleted_at" || !unloggedColumns[column] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameValue(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func uintValue(value interface{}) uint {
+	switch v := value.(type) {
+	case uint:
+		return v
+	case int64:
+		return uint(v)
+	case int32:
+		return uint(v)
+	case int:
+		return uint(v)
+	}
+	return 0
+}
+
+// FindActivity returns the user's activity, newest first. entity narrows it
+// to one kind of record and beforeID pages back past earlier results.
+func (db *Database) FindActivity(userID uint, entity string, beforeID uint, limit int) ([]models.ActivityLog, error) {
+	query := db.DB.Where("user_id = ?", userID)
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	if beforeID > 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var logs []models.ActivityLog
+	err := query.Order("id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// FindRecordActivity returns the history of one record, newest first
+func (db *Database) FindRecordActivity(userID uint, entity string, id uint) ([]models.ActivityLog, error) {
+	var logs []models.ActivityLog
+	err := db.DB.Where("user_id = ? AND entity = ? AND entity_id = ?", userID, entity, id).
+		Order("id DESC").
+		Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,85 @@
+package repository_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/testdb"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func TestActivityLog(t *testing.T) {
+	db := testdb.Open(t)
+	user := testdb.User(t, db, "")
+
+	// expect checks every action logged for the todo so far
+	expect := func(step string, id uint, want ...string) {
+		t.Helper()
+		var actions []string
+		if err := db.DB.Model(&models.ActivityLog{}).
+			Where("user_id = ? AND entity = ? AND entity_id = ?", user.ID, "todo", id).
+			Order("id").Pluck("action", &actions).Error; err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actions, want) {
+			t.Errorf("%s: logged %v, want %v", step, actions, want)
+		}
+	}
+
+	todo := models.TodoItem{UserID: user.ID, Title: "Write report", Position: 1}
+	if err := db.CreateTodo(&todo); err != nil {
+		t.Fatal(err)
+	}
+	expect("create", todo.ID, models.ActivityCreate)
+
+	todo.Title = "Write the report"
+	if err := db.UpdateTodo(&todo); err != nil {
+		t.Fatal(err)
+	}
+	expect("update", todo.ID, models.ActivityCreate, models.ActivityUpdate)
+
+	if err := db.DB.Model(&todo).Update("position", 5).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect("reorder", todo.ID, models.ActivityCreate, models.ActivityUpdate)
+
+	if err := db.DB.Model(&todo).Update("completed", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect("complete", todo.ID, models.ActivityCreate, models.ActivityUpdate, models.ActivityComplete)
+
+	if err := db.DeleteTodo(todo.ID); err != nil {
+		t.Fatal(err)
+	}
+	expect("delete", todo.ID, models.ActivityCreate, models.ActivityUpdate, models.ActivityComplete, models.ActivityDelete)
+
+	if err := db.DB.Unscoped().Model(&todo).Update("deleted_at", gorm.Expr("NULL")).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect("restore", todo.ID, models.ActivityCreate, models.ActivityUpdate, models.ActivityComplete, models.ActivityDelete, models.ActivityRestore)
+
+	if err := db.DB.Unscoped().Delete(&models.TodoItem{}, todo.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect("purge", todo.ID, models.ActivityCreate, models.ActivityUpdate, models.ActivityComplete, models.ActivityDelete, models.ActivityRestore, models.ActivityPurge)
+
+	// An upsert that changes an existing row is an update, and must not turn
+	// the creates that follow it into updates too
+	existing := models.TodoItem{UserID: user.ID, Title: "Call Ann", Position: 2}
+	if err := db.CreateTodo(&existing); err != nil {
+		t.Fatal(err)
+	}
+	existing.Title = "Call Ann back"
+	if err := db.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+	expect("upsert", existing.ID, models.ActivityCreate, models.ActivityUpdate)
+
+	next := models.TodoItem{UserID: user.ID, Title: "Book flights", Position: 3}
+	if err := db.CreateTodo(&next); err != nil {
+		t.Fatal(err)
+	}
+	expect("create after an upsert", next.ID, models.ActivityCreate)
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+func TestActivityEntry(t *testing.T) {
+	created := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	deleted := created.Add(time.Hour)
+	due := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	// row is a todo as loadActivityRows reads it, with changes applied
+	row := func(changes map[string]interface{}) map[string]interface{} {
+		r := map[string]interface{}{
+			"id": int64(7), "user_id": int64(3), "created_at": created, "updated_at": created, "deleted_at": nil,
+			"title": "Write report", "description": nil, "due_date": due, "completed": false, "position": 1.0,
+		}
+		for column, value := range changes {
+			r[column] = value
+		}
+		return r
+	}
+
+	tests := []struct {
+		name          string
+		action        string
+		before, after map[string]interface{}
+		want          string // Logged action, or empty when nothing is logged
+		changes       models.ActivityChanges
+	}{
+		{
+			name:   "create lists the values set",
+			action: models.ActivityCreate,
+			after:  row(nil),
+			want:   models.ActivityCreate,
+			changes: models.ActivityChanges{
+				"title": {After: "Write report"}, "due_date": {After: due}, "completed": {After: false},
+			},
+		},
+		{
+			name:    "update lists what changed",
+			action:  models.ActivityUpdate,
+			before:  row(nil),
+			after:   row(map[string]interface{}{"title": "Write the report", "updated_at": deleted}),
+			want:    models.ActivityUpdate,
+			changes: models.ActivityChanges{"title": {Before: "Write report", After: "Write the report"}},
+		},
+		{
+			name:   "reordering is not logged",
+			action: models.ActivityUpdate,
+			before: row(nil),
+			after:  row(map[string]interface{}{"position": 2.5, "updated_at": deleted}),
+		},
+		{
+			name:   "the same time in another zone is not a change",
+			action: models.ActivityUpdate,
+			before: row(nil),
+			after:  row(map[string]interface{}{"due_date": due.In(time.FixedZone("UTC+2", 2*60*60))}),
+		},
+		{
+			name:    "completing",
+			action:  models.ActivityUpdate,
+			before:  row(nil),
+			after:   row(map[string]interface{}{"completed": true}),
+			want:    models.ActivityComplete,
+			changes: models.ActivityChanges{"completed": {Before: false, After: true}},
+		},
+		{
+			name:    "reopening is an update",
+			action:  models.ActivityUpdate,
+			before:  row(map[string]interface{}{"completed": true}),
+			after:   row(nil),
+			want:    models.ActivityUpdate,
+			changes: models.ActivityChanges{"completed": {Before: true, After: false}},
+		},
+		{
+			name:   "soft delete lists the values it had",
+			action: models.ActivityDelete,
+			before: row(nil),
+			after:  row(map[string]interface{}{"deleted_at": deleted}),
+			want:   models.ActivityDelete,
+			changes: models.ActivityChanges{
+				"title": {Before: "Write report"}, "due_date": {Before: due}, "completed": {Before: false},
+			},
+		},
+		{
+			name:   "setting deleted_at in an update is a delete",
+			action: models.ActivityUpdate,
+			before: row(nil),
+			after:  row(map[string]interface{}{"deleted_at": deleted}),
+			want:   models.ActivityDelete,
+			changes: models.ActivityChanges{
+				"title": {Before: "Write report"}, "due_date": {Before: due}, "completed": {Before: false},
+			},
+		},
+		{
+			name:   "restore lists the values it has",
+			action: models.ActivityUpdate,
+			before: row(map[string]interface{}{"deleted_at": deleted}),
+			after:  row(map[string]interface{}{"position": 9.0}),
+			want:   models.ActivityRestore,
+			changes: models.ActivityChanges{
+				"title": {After: "Write report"}, "due_date": {After: due}, "completed": {After: false},
+			},
+		},
+		{
+			name:   "a row that is gone was purged",
+			action: models.ActivityDelete,
+			before: row(nil),
+			want:   models.ActivityPurge,
+			changes: models.ActivityChanges{
+				"title": {Before: "Write report"}, "due_date": {Before: due}, "completed": {Before: false},
+			},
+		},
+		{
+			name:   "deleting a row already in the trash purges it",
+			action: models.ActivityDelete,
+			before: row(map[string]interface{}{"deleted_at": deleted}),
+			after:  row(map[string]interface{}{"deleted_at": deleted}),
+			want:   models.ActivityPurge,
+			changes: models.ActivityChanges{
+				"title": {Before: "Write report"}, "due_date": {Before: due}, "completed": {Before: false},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := activityEntry(tt.action, tt.before, tt.after)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("activityEntry() logged %s %v, want nothing", entry.Action, entry.Changes)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("activityEntry() logged nothing, want %s", tt.want)
+			}
+			if entry.Action != tt.want || entry.UserID != 3 {
+				t.Errorf("activityEntry() = %s by user %d, want %s by user 3", entry.Action, entry.UserID, tt.want)
+			}
+			if !reflect.DeepEqual(entry.Changes, tt.changes) {
+				t.Errorf("changes = %v, want %v", entry.Changes, tt.changes)
+			}
+		})
+	}
+}
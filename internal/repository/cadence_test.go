@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/testdb"
+)
+
+func TestCreateCadenceReminder(t *testing.T) {
+	today := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		pending     *models.Contact // A reminder the person already has
+		wantCreated bool
+		wantDates   []time.Time // Of the person's pending reminders, oldest first
+	}{
+		{
+			name:        "no pending reminder",
+			wantCreated: true,
+			wantDates:   []time.Time{today},
+		},
+		{
+			name:        "a reminder the user added doesn't count",
+			pending:     &models.Contact{Date: today.AddDate(0, 0, -3)},
+			wantCreated: true,
+			wantDates:   []time.Time{today.AddDate(0, 0, -3), today},
+		},
+		{
+			name:      "a keep-in-touch reminder for today stays",
+			pending:   &models.Contact{Date: today, Source: models.ContactSourceCadence},
+			wantDates: []time.Time{today},
+		},
+		{
+			name:      "an overdue keep-in-touch reminder moves to today",
+			pending:   &models.Contact{Date: today.AddDate(0, 0, -5), Source: models.ContactSourceCadence},
+			wantDates: []time.Time{today},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			user := testdb.User(t, db, "secret")
+			person := models.Person{UserID: user.ID, Name: "Ann", CadenceDays: 7}
+			if err := db.CreatePerson(&person); err != nil {
+				t.Fatal(err)
+			}
+			if tt.pending != nil {
+				tt.pending.UserID, tt.pending.PersonID, tt.pending.Name, tt.pending.Type = user.ID, &person.ID, person.Name, models.ContactTypeCall
+				if err := db.CreateContact(tt.pending); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			created, err := db.CreateCadenceReminder(person, today)
+			if err != nil {
+				t.Fatalf("CreateCadenceReminder() error = %v", err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("CreateCadenceReminder() = %v, want %v", created, tt.wantCreated)
+			}
+
+			pending, err := db.FindFollowUpsByPersonID(person.ID, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != len(tt.wantDates) {
+				t.Fatalf("pending reminders = %d, want %d", len(pending), len(tt.wantDates))
+			}
+			for i, contact := range pending {
+				if !contact.Date.Equal(tt.wantDates[i]) {
+					t.Errorf("reminder %d is on %s, want %s", i+1, contact.Date.Format("2006-01-02"), tt.wantDates[i].Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package repository_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"github.com/himanshu/daily-planner/internal/testdb"
+)
+
+func TestImportData(t *testing.T) {
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+
+	// newImport builds an import of one of everything; clash adds a second
+	// promotion of the same todo on the same day, which ImportData refuses
+	newImport := func(clash bool) repository.DataImport {
+		todo := &models.TodoItem{
+			Title:    "Write report",
+			DueDate:  day,
+			Subtasks: []models.Subtask{{Title: "Outline"}, {Title: "Draft"}},
+		}
+		promoted := &models.Priority{Date: day, Title: "Write report"}
+		imp := repository.DataImport{
+			Todos:         []*models.TodoItem{todo},
+			TodoTags:      map[*models.TodoItem][]string{todo: {"work", "New"}},
+			TodoProjects:  map[*models.TodoItem]string{todo: "Reports"},
+			Priorities:    []*models.Priority{{Date: day, Title: "Exercise"}, promoted},
+			PriorityTodos: map[*models.Priority]*models.TodoItem{promoted: todo},
+			Contacts:      []*models.Contact{{Date: day, Name: "Ann", Type: models.ContactTypeCall}},
+			Water:         []models.HabitCheckIn{{Date: day, Value: 6}},
+			Thoughts:      []*models.Thought{{Date: day, Content: "Keep going"}},
+		}
+		if clash {
+			again := &models.Priority{Date: day, Title: "Write report"}
+			imp.Priorities = append(imp.Priorities, again)
+			imp.PriorityTodos[again] = todo
+		}
+		return imp
+	}
+
+	tables := []string{"todo_items", "subtasks", "tags", "projects", "priorities", "contacts", "habit_check_ins", "thoughts"}
+
+	tests := []struct {
+		name     string
+		clash    bool
+		limit    int              // Priorities allowed per day
+		existing int              // Priorities the day already has
+		wantErr  error            // nil when the import is saved
+		added    map[string]int64 // Rows the import adds, by table
+	}{
+		{
+			name:  "everything is saved",
+			limit: 3,
+			added: map[string]int64{
+				"todo_items": 1, "subtasks": 2, "tags": 1, "projects": 1, "priorities": 2,
+				"contacts": 1, "habit_check_ins": 1, "thoughts": 1,
+			},
+		},
+		{
+			name:     "priorities fill the day up to the limit",
+			limit:    3,
+			existing: 1,
+			added: map[string]int64{
+				"todo_items": 1, "subtasks": 2, "tags": 1, "projects": 1, "priorities": 2,
+				"contacts": 1, "habit_check_ins": 1, "thoughts": 1,
+			},
+		},
+		{
+			name:     "priorities over the limit save nothing",
+			limit:    3,
+			existing: 2,
+			wantErr:  repository.ErrPriorityLimit,
+			added:    map[string]int64{},
+		},
+		{
+			name:    "a todo promoted twice on a day saves nothing",
+			clash:   true,
+			limit:   5,
+			wantErr: repository.ErrAlreadyPriority,
+			added:   map[string]int64{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			user := testdb.User(t, db, "secret")
+
+			// Rows the user already has, which the import adds to
+			existing := models.TodoItem{UserID: user.ID, Title: "Existing", Position: 1}
+			if err := db.CreateTodo(&existing); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.DB.Create(&models.Tag{UserID: user.ID, Name: "Work"}).Error; err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.existing; i++ {
+				priority := models.Priority{UserID: user.ID, Date: day, Title: fmt.Sprintf("Existing %d", i+1), Position: float64(i + 1)}
+				if err := db.CreatePriority(&priority); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			count := func() map[string]int64 {
+				counts := make(map[string]int64, len(tables))
+				for _, table := range tables {
+					var n int64
+					if err := db.DB.Table(table).Where("user_id = ?", user.ID).Count(&n).Error; err != nil {
+						t.Fatal(err)
+					}
+					counts[table] = n
+				}
+				return counts
+			}
+			before := count()
+
+			imp := newImport(tt.clash)
+			err := db.ImportData(user.ID, imp, tt.limit)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ImportData() error = %v, want %v", err, tt.wantErr)
+			}
+
+			after := count()
+			added := make(map[string]int64)
+			for _, table := range tables {
+				if n := after[table] - before[table]; n != 0 {
+					added[table] = n
+				}
+			}
+			if !reflect.DeepEqual(added, tt.added) {
+				t.Errorf("rows added = %v, want %v", added, tt.added)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+			todo := imp.Todos[0]
+			if todo.Position != 2 {
+				t.Errorf("todo position = %v, want 2, after the existing todo", todo.Position)
+			}
+			if todo.ProjectID == nil {
+				t.Error("todo was not put in its project")
+			}
+			if promoted := imp.Priorities[1]; promoted.TodoItemID == nil || *promoted.TodoItemID != todo.ID {
+				t.Errorf("priority promotes todo %v, want %d", promoted.TodoItemID, todo.ID)
+			}
+			positions := []float64{imp.Priorities[0].Position, imp.Priorities[1].Position}
+			if want := []float64{float64(tt.existing + 1), float64(tt.existing + 2)}; !reflect.DeepEqual(positions, want) {
+				t.Errorf("priority positions = %v, want %v, after the day's existing priorities", positions, want)
+			}
+		})
+	}
+}
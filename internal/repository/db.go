@@ -1,14 +1,18 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/himanshu/daily-planner/internal/models"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Database struct that handles all database operations
@@ -34,6 +38,17 @@ func NewDatabase() (*Database, error) {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		dbHost, dbPort, dbUser, dbPassword, dbName)
 
+	db, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Successfully connected to database")
+	return db, nil
+}
+
+// Open connects to the Postgres database at dsn and sets up the activity log
+func Open(dsn string) (*Database, error) {
 	// Open database connection
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
@@ -50,20 +65,17 @@ func NewDatabase() (*Database, error) {
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 
-	log.Println("Successfully connected to database")
+	if err := registerActivityCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to set up the activity log: %v", err)
+	}
+
 	return &Database{DB: db}, nil
 }
 
 // Migrate runs database migrations
 func (db *Database) Migrate() error {
-	// Read migration file
-	migrationSQL, err := os.ReadFile("migrations/001_initial_schema.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration file: %v", err)
-	}
-
-	// Execute migration
-	if err := db.DB.Exec(string(migrationSQL)).Error; err != nil {
+	// Apply every migration file in order
+	if err := RunMigrations(db, "up"); err != nil {
 		return fmt.Errorf("failed to execute migration: %v", err)
 	}
 
@@ -94,12 +106,70 @@ func (db *Database) FindUserByEmail(email string) (*models.User, error) {
 	return &user, err
 }
 
+// FindUserByCalendarToken looks up the owner of a calendar feed
+func (db *Database) FindUserByCalendarToken(token string) (*models.User, error) {
+	var user models.User
+	err := db.DB.Where("calendar_token = ?", token).First(&user).Error
+	return &user, err
+}
+
+// UpdateCalendarToken replaces the user's calendar feed token; nil turns the feed off
+func (db *Database) UpdateCalendarToken(userID uint, token *string) error {
+	return db.DB.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token", token).Error
+}
+
 func (db *Database) UpdateUser(user *models.User) error {
 	return db.DB.Save(user).Error
 }
 
-func (db *Database) DeleteUser(id uint) error {
-	return db.DB.Delete(&models.User{}, id).Error
+// DeleteUser deletes the account, which can be restored until purgeAt. Its
+// calendar feed, CalDAV access and data exports stop working at once.
+func (db *Database) DeleteUser(id uint, purgeAt time.Time) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Update("purge_at", purgeAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", id).Delete(&models.DataExport{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.User{}, id).Error
+	})
+}
+
+// FindDeletedUserByUsername returns a deleted account that can still be restored
+func (db *Database) FindDeletedUserByUsername(username string, now time.Time) (*models.User, error) {
+	var user models.User
+	err := db.DB.Unscoped().
+		Where("username = ? AND deleted_at IS NOT NULL AND purge_at > ?", username, now).
+		First(&user).Error
+	return &user, err
+}
+
+// RestoreUser undoes DeleteUser
+func (db *Database) RestoreUser(id uint) error {
+	return db.DB.Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{"deleted_at": nil, "purge_at": nil}).Error
+}
+
+// FindUsersToPurge returns the IDs of deleted accounts whose grace period is over
+func (db *Database) FindUsersToPurge(now time.Time) ([]uint, error) {
+	var ids []uint
+	err := db.DB.Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND purge_at <= ?", now).
+		Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// PurgeUser erases a deleted account whose grace period is over. Every table
+// with a user's data references users with ON DELETE CASCADE, so removing
+// the user removes the rest. It reports whether the account was purged; one
+// restored in the meantime is left alone.
+func (db *Database) PurgeUser(id uint, now time.Time) (bool, error) {
+	result := db.DB.Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND purge_at <= ?", id, now).
+		Delete(&models.User{})
+	return result.RowsAffected > 0, result.Error
 }
 
 // Todo operations
@@ -119,6 +189,155 @@ func (db *Database) FindTodosByUserID(userID uint) ([]models.TodoItem, error) {
 	return todos, err
 }
 
+// TodoFilter narrows and orders the todos returned by FindTodos
+type TodoFilter struct {
+	ProjectID  *uint // Todos in this project; see Inbox for todos without one
+	Inbox      bool
+	TagIDs     []uint // Todos carrying any of these tags
+	Priorities []int
+	Completed  *bool
+	DueFrom    *time.Time
+	DueTo      *time.Time // Inclusive
+	Sort       string     // due, priority, manual or created
+	Descending bool
+}
+
+// todoSortColumns maps TodoFilter.Sort to its ORDER BY columns
+var todoSortColumns = map[string]string{
+	"due":      "due_date",
+	"priority": "priority_level",
+	"manual":   "position",
+	"created":  "created_at",
+}
+
+// orderByPosition sorts preloaded children by their manual position
+func orderByPosition(tx *gorm.DB) *gorm.DB {
+	return tx.Order("position").Order("id")
+}
+
+// FindTodos returns the user's todos matching the filter, with their tags loaded
+func (db *Database) FindTodos(userID uint, filter TodoFilter) ([]models.TodoItem, error) {
+	query := db.DB.Where("user_id = ?", userID)
+
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	} else if filter.Inbox {
+		query = query.Where("project_id IS NULL")
+	}
+	if len(filter.TagIDs) > 0 {
+		query = query.Where("id IN (?)", db.DB.Table("todo_item_tags").Select("todo_item_id").Where("tag_id IN ?", filter.TagIDs))
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("priority_level IN ?", filter.Priorities)
+	}
+	if filter.Completed != nil {
+		query = query.Where("completed = ?", *filter.Completed)
+	}
+	if filter.DueFrom != nil {
+		query = query.Where("due_date >= ?", *filter.DueFrom)
+	}
+	if filter.DueTo != nil {
+		query = query.Where("due_date < ?", filter.DueTo.AddDate(0, 0, 1))
+	}
+
+	column, ok := todoSortColumns[filter.Sort]
+	if !ok {
+		column = "created_at"
+	}
+	direction := "ASC"
+	if filter.Descending {
+		direction = "DESC"
+	}
+	query = query.Order(column + " " + direction).Order("id")
+
+	var todos []models.TodoItem
+	err := query.Preload("Tags").Preload("Subtasks", orderByPosition).Find(&todos).Error
+	return todos, err
+}
+
+// FindCalendarTodos returns the user's todos due on or after since, plus any
+// older ones still open, with their tags loaded
+func (db *Database) FindCalendarTodos(userID uint, since time.Time) ([]models.TodoItem, error) {
+	var todos []models.TodoItem
+	err := db.DB.Preload("Tags").
+		Where("user_id = ?", userID).
+		Where("due_date >= ? OR completed = ?", since, false).
+		Order("due_date").Order("id").
+		Find(&todos).Error
+	return todos, err
+}
+
+// ErrUIDConflict is returned when a CalDAV client creates a todo with a UID
+// another todo already has
+var ErrUIDConflict = errors.New("another todo has this UID")
+
+// FindCalDAVTodos returns every todo in the user's CalDAV collection, with their tags loaded
+func (db *Database) FindCalDAVTodos(userID uint) ([]models.TodoItem, error) {
+	var todos []models.TodoItem
+	err := db.DB.Preload("Tags").Where("user_id = ?", userID).Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// FindTodoByCalDAVName looks up a todo, with its tags, by the resource name
+// a CalDAV client created it under
+func (db *Database) FindTodoByCalDAVName(userID uint, name string) (*models.TodoItem, error) {
+	var todo models.TodoItem
+	err := db.DB.Preload("Tags").Where("user_id = ? AND caldav_name = ?", userID, name).First(&todo).Error
+	return &todo, err
+}
+
+// CalDAVCollectionTag changes whenever a todo in the user's collection is
+// added, changed or deleted, so clients know when to look for changes
+func (db *Database) CalDAVCollectionTag(userID uint) (string, error) {
+	var row struct {
+		Count   int64
+		Changed *time.Time
+	}
+	err := db.DB.Unscoped().Model(&models.TodoItem{}).
+		Select("COUNT(*) AS count, MAX(GREATEST(updated_at, COALESCE(deleted_at, updated_at))) AS changed").
+		Where("user_id = ?", userID).
+		Scan(&row).Error
+	if err != nil {
+		return "", err
+	}
+	tag := fmt.Sprintf("%d", row.Count)
+	if row.Changed != nil {
+		tag += fmt.Sprintf("-%d", row.Changed.UnixNano())
+	}
+	return tag, nil
+}
+
+// SaveCalDAVTodo creates or updates a todo written by a CalDAV client. New
+// todos go to the end of the list and must have a UID no other todo has.
+func (db *Database) SaveCalDAVTodo(todo *models.TodoItem) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if todo.ID != 0 {
+			return tx.Omit(clause.Associations).Save(todo).Error
+		}
+
+		var conflicts int64
+		if err := tx.Model(&models.TodoItem{}).Where("user_id = ? AND external_uid = ?", todo.UserID, todo.ExternalUID).Count(&conflicts).Error; err != nil {
+			return err
+		}
+		if conflicts > 0 {
+			return ErrUIDConflict
+		}
+		// A deleted todo keeps its UID; let the new one have it
+		if err := tx.Unscoped().Model(&models.TodoItem{}).
+			Where("user_id = ? AND external_uid = ? AND deleted_at IS NOT NULL", todo.UserID, todo.ExternalUID).
+			Update("external_uid", "").Error; err != nil {
+			return err
+		}
+
+		var max float64
+		if err := tx.Model(&models.TodoItem{}).Where("user_id = ?", todo.UserID).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+			return err
+		}
+		todo.Position = max + 1
+		return tx.Omit(clause.Associations).Create(todo).Error
+	})
+}
+
 func (db *Database) UpdateTodo(todo *models.TodoItem) error {
 	return db.DB.Save(todo).Error
 }
@@ -127,6 +346,19 @@ func (db *Database) DeleteTodo(id uint) error {
 	return db.DB.Delete(&models.TodoItem{}, id).Error
 }
 
+// DeleteTodoAndPriorities deletes a todo and drops it from any day it was
+// promoted to. They are all stamped with the same deletion time so that
+// restoring the todo from the trash can bring its priorities back too.
+func (db *Database) DeleteTodoAndPriorities(todo *models.TodoItem) error {
+	now := time.Now()
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Priority{}).Where("todo_item_id = ?", todo.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(todo).Update("deleted_at", now).Error
+	})
+}
+
 // Priority operations
 func (db *Database) CreatePriority(priority *models.Priority) error {
 	return db.DB.Create(priority).Error
@@ -152,6 +384,76 @@ func (db *Database) DeletePriority(id uint) error {
 	return db.DB.Delete(&models.Priority{}, id).Error
 }
 
+// ErrPriorityLimit is returned when a day already has the user's maximum number of priorities
+var ErrPriorityLimit = errors.New("daily priority limit reached")
+
+// CreatePriorityWithinLimit appends a priority to its day's list unless the
+// day already holds limit priorities. The user row is locked so concurrent
+// requests cannot both squeeze in under the limit.
+func (db *Database) CreatePriorityWithinLimit(priority *models.Priority, limit int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		return createPriorityWithinLimit(tx, priority, limit)
+	})
+}
+
+func createPriorityWithinLimit(tx *gorm.DB, priority *models.Priority, limit int) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, priority.UserID).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, priority.Date)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return ErrPriorityLimit
+	}
+
+	var max float64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, priority.Date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+		return err
+	}
+	priority.Position = max + 1
+
+	return tx.Create(priority).Error
+}
+
+// FindPrioritiesByUserIDAndDate returns a day's priorities in rank order with promoted todos loaded
+func (db *Database) FindPrioritiesByUserIDAndDate(userID uint, date time.Time) ([]models.Priority, error) {
+	var priorities []models.Priority
+	err := db.DB.Preload("TodoItem").Scopes(UserDateScope(userID, date)).Order("position").Order("id").Find(&priorities).Error
+	return priorities, err
+}
+
+// FindPrioritiesSince returns the user's priorities dated on or after since,
+// sorted by date and position
+func (db *Database) FindPrioritiesSince(userID uint, since time.Time) ([]models.Priority, error) {
+	var priorities []models.Priority
+	err := db.DB.Preload("TodoItem").Where("user_id = ? AND date >= ?", userID, since).Order("date").Order("position").Order("id").Find(&priorities).Error
+	return priorities, err
+}
+
+// FindPrioritiesByUserIDAndDateRange returns the priorities of the days
+// from from to to, sorted by date and position, with promoted todos loaded
+func (db *Database) FindPrioritiesByUserIDAndDateRange(userID uint, from, to time.Time) ([]models.Priority, error) {
+	var priorities []models.Priority
+	err := db.DB.Preload("TodoItem").Where("user_id = ? AND date BETWEEN ? AND ?", userID, from, to).Order("date").Order("position").Order("id").Find(&priorities).Error
+	return priorities, err
+}
+
+// FindPriorityByTodoID returns the priority a todo was promoted to on the given day
+func (db *Database) FindPriorityByTodoID(userID, todoID uint, date time.Time) (*models.Priority, error) {
+	var priority models.Priority
+	err := db.DB.Scopes(UserDateScope(userID, date)).Where("todo_item_id = ?", todoID).First(&priority).Error
+	return &priority, err
+}
+
+// DeletePrioritiesByTodoID demotes a todo from every day it was promoted to
+func (db *Database) DeletePrioritiesByTodoID(todoID uint) error {
+	return db.DB.Where("todo_item_id = ?", todoID).Delete(&models.Priority{}).Error
+}
+
 // Contact operations
 func (db *Database) CreateContact(contact *models.Contact) error {
 	return db.DB.Create(contact).Error
@@ -169,6 +471,13 @@ func (db *Database) FindContactsByUserID(userID uint) ([]models.Contact, error)
 	return contacts, err
 }
 
+// FindContactsSince returns the user's follow-up reminders dated on or after since
+func (db *Database) FindContactsSince(userID uint, since time.Time) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := db.DB.Where("user_id = ? AND date >= ?", userID, since).Order("date").Order("position").Order("id").Find(&contacts).Error
+	return contacts, err
+}
+
 func (db *Database) UpdateContact(contact *models.Contact) error {
 	return db.DB.Save(contact).Error
 }
@@ -219,6 +528,12 @@ func (db *Database) FindThoughtByUserIDAndDate(userID uint, date string) (*model
 	return &thought, err
 }
 
+func (db *Database) FindThoughtsByUserIDAndDateRange(userID uint, from, to string) ([]models.Thought, error) {
+	var thoughts []models.Thought
+	err := db.DB.Where("user_id = ? AND date BETWEEN ? AND ?", userID, from, to).Order("date").Find(&thoughts).Error
+	return thoughts, err
+}
+
 func (db *Database) UpdateThought(thought *models.Thought) error {
 	return db.DB.Save(thought).Error
 }
@@ -226,3 +541,1260 @@ func (db *Database) UpdateThought(thought *models.Thought) error {
 func (db *Database) DeleteThought(id uint) error {
 	return db.DB.Delete(&models.Thought{}, id).Error
 }
+
+// MoodEntry operations
+func (db *Database) FindMoodEntryByUserIDAndDate(userID uint, date string) (*models.MoodEntry, error) {
+	var entry models.MoodEntry
+	err := db.DB.Where("user_id = ? AND date = ?", userID, date).First(&entry).Error
+	return &entry, err
+}
+
+func (db *Database) FindMoodEntriesByUserIDAndDateRange(userID uint, from, to string) ([]models.MoodEntry, error) {
+	var entries []models.MoodEntry
+	err := db.DB.Where("user_id = ? AND date BETWEEN ? AND ?", userID, from, to).Order("date").Find(&entries).Error
+	return entries, err
+}
+
+func (db *Database) SaveMoodEntry(entry *models.MoodEntry) error {
+	return db.DB.Save(entry).Error
+}
+
+// Habit operations
+func (db *Database) CreateHabit(habit *models.Habit) error {
+	return db.DB.Create(habit).Error
+}
+
+func (db *Database) FindHabitByIDAndUserID(id, userID uint) (*models.Habit, error) {
+	var habit models.Habit
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&habit).Error
+	return &habit, err
+}
+
+func (db *Database) FindHabitsByUserID(userID uint, includeArchived bool) ([]models.Habit, error) {
+	var habits []models.Habit
+	query := db.DB.Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("archived = ?", false)
+	}
+	err := query.Order("id").Find(&habits).Error
+	return habits, err
+}
+
+// FindOrCreateWaterHabit returns the user's built-in water habit, creating it on first use
+func (db *Database) FindOrCreateWaterHabit(userID uint) (*models.Habit, error) {
+	return findOrCreateWaterHabit(db.DB, userID)
+}
+
+func findOrCreateWaterHabit(tx *gorm.DB, userID uint) (*models.Habit, error) {
+	habit := models.Habit{
+		UserID:    userID,
+		SystemKey: models.HabitKeyWater,
+		Name:      "Water",
+		Icon:      "fa-glass-whiskey",
+		Unit:      "glasses",
+		Kind:      models.HabitKindCounter,
+		Period:    models.HabitPeriodDaily,
+		Target:    10,
+	}
+	err := tx.Where("user_id = ? AND system_key = ?", userID, models.HabitKeyWater).FirstOrCreate(&habit).Error
+	return &habit, err
+}
+
+func (db *Database) UpdateHabit(habit *models.Habit) error {
+	return db.DB.Save(habit).Error
+}
+
+func (db *Database) DeleteHabit(id uint) error {
+	return db.DB.Delete(&models.Habit{}, id).Error
+}
+
+// HabitCheckIn operations
+func (db *Database) FindHabitCheckInByDate(habitID uint, date string) (*models.HabitCheckIn, error) {
+	var checkIn models.HabitCheckIn
+	err := db.DB.Where("habit_id = ? AND date = ?", habitID, date).First(&checkIn).Error
+	return &checkIn, err
+}
+
+func (db *Database) FindHabitCheckInsByDateRange(habitIDs []uint, from, to string) ([]models.HabitCheckIn, error) {
+	var checkIns []models.HabitCheckIn
+	err := db.DB.Where("habit_id IN ? AND date BETWEEN ? AND ?", habitIDs, from, to).Order("date").Find(&checkIns).Error
+	return checkIns, err
+}
+
+func (db *Database) SaveHabitCheckIn(checkIn *models.HabitCheckIn) error {
+	return db.DB.Save(checkIn).Error
+}
+
+// FocusSession operations
+
+// ErrFocusSessionActive is returned when a focus session is started while
+// another one is still running or paused
+var ErrFocusSessionActive = errors.New("another focus session is already active")
+
+// CreateFocusSession starts a focus session. The database allows one active
+// session per user, so of two concurrent starts one fails with
+// ErrFocusSessionActive.
+func (db *Database) CreateFocusSession(session *models.FocusSession) error {
+	err := db.DB.Create(session).Error
+	if db.isUniqueViolation(err) {
+		return ErrFocusSessionActive
+	}
+	return err
+}
+
+// isUniqueViolation reports whether err is the database rejecting a row that
+// would break a unique index
+func (db *Database) isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	if translator, ok := db.DB.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+func (db *Database) FindFocusSessionByIDAndUserID(id, userID uint) (*models.FocusSession, error) {
+	var session models.FocusSession
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	return &session, err
+}
+
+// FindActiveFocusSession returns the user's running or paused session, if any
+func (db *Database) FindActiveFocusSession(userID uint) (*models.FocusSession, error) {
+	var session models.FocusSession
+	err := db.DB.Where("user_id = ? AND status <> ?", userID, models.FocusStatusStopped).First(&session).Error
+	return &session, err
+}
+
+func (db *Database) FindFocusSessionsByDateRange(userID uint, from, to time.Time) ([]models.FocusSession, error) {
+	var sessions []models.FocusSession
+	err := db.DB.Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, from, to).Order("started_at").Find(&sessions).Error
+	return sessions, err
+}
+
+func (db *Database) UpdateFocusSession(session *models.FocusSession) error {
+	return db.DB.Save(session).Error
+}
+
+func (db *Database) DeleteFocusSession(id uint) error {
+	return db.DB.Delete(&models.FocusSession{}, id).Error
+}
+
+// FocusTotal is the focus time recorded against one todo or priority
+type FocusTotal struct {
+	TodoItemID *uint
+	PriorityID *uint
+	Sessions   int
+	Seconds    int
+}
+
+// SumFocusSecondsByTask aggregates recorded focus time per linked todo or priority
+func (db *Database) SumFocusSecondsByTask(userID uint, from, to time.Time) ([]FocusTotal, error) {
+	var totals []FocusTotal
+	err := db.DB.Model(&models.FocusSession{}).
+		Select("todo_item_id, priority_id, COUNT(*) AS sessions, SUM(duration_seconds) AS seconds").
+		Where("user_id = ? AND started_at >= ? AND started_at < ?", userID, from, to).
+		Group("todo_item_id, priority_id").
+		Order("seconds DESC").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// TimeBlock operations
+func (db *Database) CreateTimeBlock(block *models.TimeBlock) error {
+	return db.DB.Create(block).Error
+}
+
+func (db *Database) FindTimeBlockByIDAndUserID(id, userID uint) (*models.TimeBlock, error) {
+	var block models.TimeBlock
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&block).Error
+	return &block, err
+}
+
+func (db *Database) FindTimeBlocksByUserIDAndDate(userID uint, date string) ([]models.TimeBlock, error) {
+	var blocks []models.TimeBlock
+	err := db.DB.Where("user_id = ? AND date = ?", userID, date).Order("start_at").Find(&blocks).Error
+	return blocks, err
+}
+
+// FindTimeBlocksSince returns the user's time blocks dated on or after since
+func (db *Database) FindTimeBlocksSince(userID uint, since time.Time) ([]models.TimeBlock, error) {
+	var blocks []models.TimeBlock
+	err := db.DB.Where("user_id = ? AND date >= ?", userID, since).Order("start_at").Find(&blocks).Error
+	return blocks, err
+}
+
+// FindOverlappingTimeBlocks returns the user's blocks that intersect [start, end), ignoring excludeID
+func (db *Database) FindOverlappingTimeBlocks(userID uint, start, end time.Time, excludeID uint) ([]models.TimeBlock, error) {
+	var blocks []models.TimeBlock
+	err := db.DB.Where("user_id = ? AND id <> ? AND start_at < ? AND end_at > ?", userID, excludeID, end, start).Order("start_at").Find(&blocks).Error
+	return blocks, err
+}
+
+func (db *Database) UpdateTimeBlock(block *models.TimeBlock) error {
+	return db.DB.Save(block).Error
+}
+
+func (db *Database) DeleteTimeBlock(id uint) error {
+	return db.DB.Delete(&models.TimeBlock{}, id).Error
+}
+
+// Tag operations
+// ErrTagExists is returned when a tag would get the name of another of the
+// user's tags, which are unique ignoring case
+var ErrTagExists = errors.New("a tag with this name already exists")
+
+func (db *Database) CreateTag(tag *models.Tag) error {
+	err := db.DB.Create(tag).Error
+	if db.isUniqueViolation(err) {
+		return ErrTagExists
+	}
+	return err
+}
+
+func (db *Database) FindTagByIDAndUserID(id, userID uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&tag).Error
+	return &tag, err
+}
+
+func (db *Database) FindTagsByUserID(userID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := db.DB.Where("user_id = ?", userID).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+// FindTagsByIDs returns the user's tags with the given IDs, skipping any that belong to others
+func (db *Database) FindTagsByIDs(userID uint, ids []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	err := db.DB.Where("user_id = ? AND id IN ?", userID, ids).Find(&tags).Error
+	return tags, err
+}
+
+func (db *Database) UpdateTag(tag *models.Tag) error {
+	err := db.DB.Save(tag).Error
+	if db.isUniqueViolation(err) {
+		return ErrTagExists
+	}
+	return err
+}
+
+// DeleteTag removes a tag and detaches it from every todo
+func (db *Database) DeleteTag(tag *models.Tag) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM todo_item_tags WHERE tag_id = ?", tag.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+}
+
+// ReplaceTodoTags sets the todo's tags to exactly the given list
+func (db *Database) ReplaceTodoTags(todo *models.TodoItem, tags []models.Tag) error {
+	return db.DB.Model(todo).Association("Tags").Replace(tags)
+}
+
+// NextTodoPosition returns a position that sorts after all of the user's todos
+func (db *Database) NextTodoPosition(userID uint) (float64, error) {
+	var max float64
+	err := db.DB.Model(&models.TodoItem{}).Where("user_id = ?", userID).Select("COALESCE(MAX(position), 0)").Scan(&max).Error
+	return max + 1, err
+}
+
+// Subtask operations
+func (db *Database) CreateSubtask(subtask *models.Subtask) error {
+	return db.DB.Create(subtask).Error
+}
+
+func (db *Database) FindSubtaskByID(todoID, id uint) (*models.Subtask, error) {
+	var subtask models.Subtask
+	err := db.DB.Where("id = ? AND todo_item_id = ?", id, todoID).First(&subtask).Error
+	return &subtask, err
+}
+
+func (db *Database) FindSubtasksByTodoID(todoID uint) ([]models.Subtask, error) {
+	var subtasks []models.Subtask
+	err := orderByPosition(db.DB.Where("todo_item_id = ?", todoID)).Find(&subtasks).Error
+	return subtasks, err
+}
+
+// NextSubtaskPosition returns a position that sorts after the todo's existing subtasks
+func (db *Database) NextSubtaskPosition(todoID uint) (float64, error) {
+	var max float64
+	err := db.DB.Model(&models.Subtask{}).Where("todo_item_id = ?", todoID).Select("COALESCE(MAX(position), 0)").Scan(&max).Error
+	return max + 1, err
+}
+
+func (db *Database) UpdateSubtask(subtask *models.Subtask) error {
+	return db.DB.Save(subtask).Error
+}
+
+func (db *Database) DeleteSubtask(id uint) error {
+	return db.DB.Delete(&models.Subtask{}, id).Error
+}
+
+// Project operations
+// ErrProjectExists is returned when a project would get the name of another
+// of the user's projects, which are unique ignoring case
+var ErrProjectExists = errors.New("a project with this name already exists")
+
+func (db *Database) CreateProject(project *models.Project) error {
+	err := db.DB.Create(project).Error
+	if db.isUniqueViolation(err) {
+		return ErrProjectExists
+	}
+	return err
+}
+
+func (db *Database) FindProjectByIDAndUserID(id, userID uint) (*models.Project, error) {
+	var project models.Project
+	err := db.DB.Where("id = ? AND user_id = ?", id, userID).First(&project).Error
+	return &project, err
+}
+
+func (db *Database) FindProjectsByUserID(userID uint, includeArchived bool) ([]models.Project, error) {
+	var projects []models.Project
+	query := db.DB.Where("user_id = ?", userID)
+	if !includeArchived {
+		query = query.Where("archived = ?", false)
+	}
+	err := orderByPosition(query).Find(&projects).Error
+	return projects, err
+}
+
+// NextProjectPosition returns a position that sorts after the user's existing projects
+func (db *Database) NextProjectPosition(userID uint) (float64, error) {
+	var max float64
+	err := db.DB.Model(&models.Project{}).Where("user_id = ?", userID).Select("COALESCE(MAX(position), 0)").Scan(&max).Error
+	return max + 1, err
+}
+
+func (db *Database) UpdateProject(project *models.Project) error {
+	err := db.DB.Save(project).Error
+	if db.isUniqueViolation(err) {
+		return ErrProjectExists
+	}
+	return err
+}
+
+// DeleteProject removes a project and moves its todos back to the inbox
+func (db *Database) DeleteProject(project *models.Project) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TodoItem{}).Where("project_id = ?", project.ID).Update("project_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(project).Error
+	})
+}
+
+// ProjectStats is the todo completion count for one project (nil for the inbox)
+type ProjectStats struct {
+	ProjectID *uint
+	Total     int
+	Completed int
+}
+
+// CountTodosByProject aggregates todo completion per project for the user
+func (db *Database) CountTodosByProject(userID uint) ([]ProjectStats, error) {
+	var stats []ProjectStats
+	err := db.DB.Model(&models.TodoItem{}).
+		Select("project_id, COUNT(*) AS total, COUNT(*) FILTER (WHERE completed) AS completed").
+		Where("user_id = ?", userID).
+		Group("project_id").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// Person operations
+func (db *Database) CreatePerson(person *models.Person) error {
+	return db.DB.Create(person).Error
+}
+
+func (db *Database) FindPersonByIDAndUserID(id, userID uint) (*models.Person, error) {
+	var person models.Person
+	err := db.DB.Preload("Emails").Preload("Phones").Preload("Tags").Preload("Dates").Where("id = ? AND user_id = ?", id, userID).First(&person).Error
+	return &person, err
+}
+
+// FindPersonByName looks up a person by name, ignoring case
+func (db *Database) FindPersonByName(userID uint, name string) (*models.Person, error) {
+	var person models.Person
+	err := db.DB.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).Order("id").First(&person).Error
+	return &person, err
+}
+
+// PersonFilter narrows the people returned by FindPeople
+type PersonFilter struct {
+	Query string // Matches names, email addresses and phone numbers
+	TagID *uint
+}
+
+func (db *Database) FindPeople(userID uint, filter PersonFilter) ([]models.Person, error) {
+	query := db.DB.Preload("Emails").Preload("Phones").Preload("Tags").Where("user_id = ?", userID)
+
+	if filter.Query != "" {
+		like := "%" + strings.ToLower(filter.Query) + "%"
+		query = query.Where(
+			"LOWER(name) LIKE ? OR id IN (?) OR id IN (?)", like,
+			db.DB.Model(&models.PersonEmail{}).Select("person_id").Where("LOWER(address) LIKE ?", like),
+			db.DB.Model(&models.PersonPhone{}).Select("person_id").Where("number LIKE ?", like),
+		)
+	}
+	if filter.TagID != nil {
+		query = query.Where("id IN (?)", db.DB.Table("person_tags").Select("person_id").Where("tag_id = ?", *filter.TagID))
+	}
+
+	var people []models.Person
+	err := query.Order("LOWER(name)").Order("id").Find(&people).Error
+	return people, err
+}
+
+// UpdatePerson saves a person's fields and replaces whichever of emails,
+// phones and tags are non-nil
+func (db *Database) UpdatePerson(person *models.Person, emails []models.PersonEmail, phones []models.PersonPhone, tags []models.Tag) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(person).Error; err != nil {
+			return err
+		}
+
+		if emails != nil {
+			if err := tx.Where("person_id = ?", person.ID).Delete(&models.PersonEmail{}).Error; err != nil {
+				return err
+			}
+			for i := range emails {
+				emails[i].ID = 0
+				emails[i].PersonID = person.ID
+			}
+			if len(emails) > 0 {
+				if err := tx.Create(&emails).Error; err != nil {
+					return err
+				}
+			}
+			person.Emails = emails
+		}
+
+		if phones != nil {
+			if err := tx.Where("person_id = ?", person.ID).Delete(&models.PersonPhone{}).Error; err != nil {
+				return err
+			}
+			for i := range phones {
+				phones[i].ID = 0
+				phones[i].PersonID = person.ID
+			}
+			if len(phones) > 0 {
+				if err := tx.Create(&phones).Error; err != nil {
+					return err
+				}
+			}
+			person.Phones = phones
+		}
+
+		if tags != nil {
+			if err := tx.Model(person).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+			person.Tags = tags
+		}
+
+		return nil
+	})
+}
+
+// DeletePerson removes a person and their interaction history. Follow-up
+// reminders are kept under the name they were created with.
+func (db *Database) DeletePerson(person *models.Person) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contact{}).Where("person_id = ?", person.ID).Update("person_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.Interaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.SignificantDate{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.PersonEmail{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("person_id = ?", person.ID).Delete(&models.PersonPhone{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(person).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(person).Error
+	})
+}
+
+// FindFollowUpsByPersonID returns a person's reminders, newest first
+func (db *Database) FindFollowUpsByPersonID(personID uint, pendingOnly bool) ([]models.Contact, error) {
+	query := db.DB.Where("person_id = ?", personID)
+	if pendingOnly {
+		query = query.Where("completed = ?", false).Order("date")
+	} else {
+		query = query.Order("date DESC")
+	}
+
+	var contacts []models.Contact
+	err := query.Order("id").Find(&contacts).Error
+	return contacts, err
+}
+
+// PersonCadence is a person with a keep-in-touch cadence and the day they were last reached
+type PersonCadence struct {
+	models.Person
+	LastContacted time.Time // Latest interaction, or the day the person was added
+}
+
+// DueDate is the day the person should next be contacted
+func (p PersonCadence) DueDate() time.Time {
+	return p.LastContacted.AddDate(0, 0, p.CadenceDays)
+}
+
+// FindCadencePeople returns the people with a keep-in-touch cadence, for one
+// user or, when userID is nil, for everyone
+func (db *Database) FindCadencePeople(userID *uint) ([]PersonCadence, error) {
+	query := db.DB.Table("people").
+		Select("people.*, COALESCE(MAX(interactions.date), DATE(people.created_at)) AS last_contacted").
+		Joins("LEFT JOIN interactions ON interactions.person_id = people.id AND interactions.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = people.user_id AND users.deleted_at IS NULL").
+		Where("people.cadence_days > 0 AND people.deleted_at IS NULL")
+	if userID != nil {
+		query = query.Where("people.user_id = ?", *userID)
+	}
+
+	var people []PersonCadence
+	err := query.Group("people.id").Order("people.id").Scan(&people).Error
+	return people, err
+}
+
+// CreateCadenceReminder adds a keep-in-touch reminder for the person on the
+// given day unless they already have a pending one. Reminders the user added
+// or that an occasion generated don't count. A pending keep-in-touch reminder
+// from an earlier day is moved to the end of the given day instead, so the
+// person isn't lost among old, overdue reminders. It reports whether a
+// reminder was created.
+func (db *Database) CreateCadenceReminder(person models.Person, date time.Time) (bool, error) {
+	created := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		// Serialize with other runs so a person never gets two reminders
+		var locked models.Person
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, person.ID).Error; err != nil {
+			return err
+		}
+
+		var pending []models.Contact
+		err := tx.Where("person_id = ? AND source = ? AND completed = ?", person.ID, models.ContactSourceCadence, false).
+			Order("date DESC").Limit(1).Find(&pending).Error
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 && !pending[0].Date.Before(date) {
+			return nil
+		}
+
+		var max float64
+		if err := tx.Model(&models.Contact{}).Scopes(UserDateScope(person.UserID, date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			// Overdue: roll it forward rather than add a second one
+			return tx.Model(&pending[0]).Updates(map[string]interface{}{"date": date, "position": max + 1}).Error
+		}
+
+		kind := person.CadenceType
+		if kind == "" {
+			kind = models.ContactTypeCall
+		}
+
+		reminder := models.Contact{
+			UserID:      person.UserID,
+			PersonID:    &person.ID,
+			Name:        person.Name,
+			Type:        kind,
+			Description: fmt.Sprintf("Keep in touch (every %d days)", person.CadenceDays),
+			Date:        date,
+			Position:    max + 1,
+			Source:      models.ContactSourceCadence,
+		}
+		if err := tx.Omit("Person").Create(&reminder).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// SignificantDate operations
+func (db *Database) CreateSignificantDate(date *models.SignificantDate) error {
+	return db.DB.Create(date).Error
+}
+
+func (db *Database) FindSignificantDateByIDAndPersonID(id, personID uint) (*models.SignificantDate, error) {
+	var date models.SignificantDate
+	err := db.DB.Where("id = ? AND person_id = ?", id, personID).First(&date).Error
+	return &date, err
+}
+
+func (db *Database) FindSignificantDatesByPersonID(personID uint) ([]models.SignificantDate, error) {
+	var dates []models.SignificantDate
+	err := db.DB.Where("person_id = ?", personID).Order("EXTRACT(MONTH FROM date)").Order("EXTRACT(DAY FROM date)").Find(&dates).Error
+	return dates, err
+}
+
+func (db *Database) FindSignificantDatesByUserID(userID uint) ([]models.SignificantDate, error) {
+	var dates []models.SignificantDate
+	err := db.DB.Where("user_id = ?", userID).Find(&dates).Error
+	return dates, err
+}
+
+func (db *Database) UpdateSignificantDate(date *models.SignificantDate) error {
+	return db.DB.Save(date).Error
+}
+
+func (db *Database) DeleteSignificantDate(id uint) error {
+	return db.DB.Delete(&models.SignificantDate{}, id).Error
+}
+
+// FindOccasionPeople returns the people who have a birthday or significant date
+func (db *Database) FindOccasionPeople(userID uint) ([]models.Person, error) {
+	var people []models.Person
+	err := db.DB.Where("user_id = ?", userID).
+		Where("birthday IS NOT NULL OR id IN (?)", db.DB.Model(&models.SignificantDate{}).Select("person_id")).
+		Find(&people).Error
+	return people, err
+}
+
+// FindOccasionUsers returns the users with at least one birthday or
+// significant date, with just the settings reminders need
+func (db *Database) FindOccasionUsers() ([]models.User, error) {
+	var users []models.User
+	err := db.DB.Select("id, timezone, occasion_lead_days").
+		Where("id IN (?) OR id IN (?)",
+			db.DB.Model(&models.Person{}).Select("user_id").Where("birthday IS NOT NULL"),
+			db.DB.Model(&models.SignificantDate{}).Select("user_id"),
+		).
+		Find(&users).Error
+	return users, err
+}
+
+// CreateOccasionReminder adds a reminder for one occurrence of a birthday or
+// significant date. A reminder is only ever made once per occurrence, even if
+// the user has since deleted it. It reports whether a reminder was created.
+func (db *Database) CreateOccasionReminder(reminder *models.Contact) (bool, error) {
+	created := false
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Unscoped().Model(&models.Contact{}).
+			Where("person_id = ? AND occasion_key = ?", reminder.PersonID, reminder.OccasionKey).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+
+		var max float64
+		if err := tx.Model(&models.Contact{}).Scopes(UserDateScope(reminder.UserID, reminder.Date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+			return err
+		}
+		reminder.Position = max + 1
+
+		result := tx.Omit("Person").Clauses(clause.OnConflict{DoNothing: true}).Create(reminder)
+		created = result.RowsAffected > 0
+		return result.Error
+	})
+	return created, err
+}
+
+// Interaction operations
+
+// CreateInteraction records an interaction and closes the person's pending
+// keep-in-touch reminders, which it fulfils
+func (db *Database) CreateInteraction(interaction *models.Interaction) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(interaction).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Contact{}).
+			Where("person_id = ? AND source = ? AND completed = ?", interaction.PersonID, models.ContactSourceCadence, false).
+			Update("completed", true).Error
+	})
+}
+
+func (db *Database) FindInteractionByIDAndPersonID(id, personID uint) (*models.Interaction, error) {
+	var interaction models.Interaction
+	err := db.DB.Where("id = ? AND person_id = ?", id, personID).First(&interaction).Error
+	return &interaction, err
+}
+
+// FindInteractionsByPersonID returns a person's interaction history, newest first
+func (db *Database) FindInteractionsByPersonID(personID uint) ([]models.Interaction, error) {
+	var interactions []models.Interaction
+	err := db.DB.Where("person_id = ?", personID).Order("date DESC").Order("id DESC").Find(&interactions).Error
+	return interactions, err
+}
+
+func (db *Database) DeleteInteraction(id uint) error {
+	return db.DB.Delete(&models.Interaction{}, id).Error
+}
+
+// PersonImport describes one imported contact: a new person (ID 0) or an
+// existing one, plus the details to add to it
+type PersonImport struct {
+	Person   *models.Person
+	Emails   []models.PersonEmail
+	Phones   []models.PersonPhone
+	TagNames []string // Tags are matched by name and created when missing
+}
+
+// ImportPeople applies a batch of imports in one transaction, so a failure
+// leaves the contact book unchanged
+func (db *Database) ImportPeople(userID uint, imports []PersonImport) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []models.Tag
+		if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return err
+		}
+		tags := make(map[string]models.Tag, len(existing))
+		for _, tag := range existing {
+			tags[strings.ToLower(tag.Name)] = tag
+		}
+
+		for _, imp := range imports {
+			person := imp.Person
+			if err := tx.Omit(clause.Associations).Save(person).Error; err != nil {
+				return err
+			}
+
+			for i := range imp.Emails {
+				imp.Emails[i].PersonID = person.ID
+			}
+			if len(imp.Emails) > 0 {
+				if err := tx.Create(&imp.Emails).Error; err != nil {
+					return err
+				}
+			}
+			for i := range imp.Phones {
+				imp.Phones[i].PersonID = person.ID
+			}
+			if len(imp.Phones) > 0 {
+				if err := tx.Create(&imp.Phones).Error; err != nil {
+					return err
+				}
+			}
+
+			var personTags []models.Tag
+			for _, name := range imp.TagNames {
+				tag, ok := tags[strings.ToLower(name)]
+				if !ok {
+					tag = models.Tag{UserID: userID, Name: name}
+					if err := tx.Create(&tag).Error; err != nil {
+						return err
+					}
+					tags[strings.ToLower(name)] = tag
+				}
+				personTags = append(personTags, tag)
+			}
+			if len(personTags) > 0 {
+				if err := tx.Model(person).Association("Tags").Append(personTags); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// CalendarImport holds the planner items made from an iCalendar file. Items
+// with an ID are updates of earlier imports; the rest are new.
+type CalendarImport struct {
+	Todos      []*models.TodoItem
+	TodoTags   map[*models.TodoItem][]string // Tags are matched by name and created when missing
+	TimeBlocks []*models.TimeBlock
+	Contacts   []*models.Contact
+}
+
+// FindImportedTodos returns the user's todos that came from a calendar,
+// including deleted ones so that re-importing does not bring them back
+func (db *Database) FindImportedTodos(userID uint) ([]models.TodoItem, error) {
+	var todos []models.TodoItem
+	err := db.DB.Unscoped().Where("user_id = ? AND external_uid <> ''", userID).Find(&todos).Error
+	return todos, err
+}
+
+// FindImportedTimeBlocks returns the user's time blocks that came from a calendar, including deleted ones
+func (db *Database) FindImportedTimeBlocks(userID uint) ([]models.TimeBlock, error) {
+	var blocks []models.TimeBlock
+	err := db.DB.Unscoped().Where("user_id = ? AND external_uid <> ''", userID).Find(&blocks).Error
+	return blocks, err
+}
+
+// FindImportedContacts returns the user's follow-up reminders that came from a calendar, including deleted ones
+func (db *Database) FindImportedContacts(userID uint) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := db.DB.Unscoped().Where("user_id = ? AND external_uid <> ''", userID).Find(&contacts).Error
+	return contacts, err
+}
+
+// tagNames finds a user's tags by name during an import, creating missing ones
+type tagNames struct {
+	tx     *gorm.DB
+	userID uint
+	byName map[string]models.Tag // By lower-case name
+}
+
+func loadTagNames(tx *gorm.DB, userID uint) (*tagNames, error) {
+	var existing []models.Tag
+	if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	t := &tagNames{tx: tx, userID: userID, byName: make(map[string]models.Tag, len(existing))}
+	for _, tag := range existing {
+		t.byName[strings.ToLower(tag.Name)] = tag
+	}
+	return t, nil
+}
+
+// tag adds the named tags to a saved todo
+func (t *tagNames) tag(todo *models.TodoItem, names []string) error {
+	var todoTags []models.Tag
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		tag, ok := t.byName[key]
+		if !ok {
+			tag = models.Tag{UserID: t.userID, Name: name}
+			if err := t.tx.Create(&tag).Error; err != nil {
+				return err
+			}
+			t.byName[key] = tag
+		}
+		todoTags = append(todoTags, tag)
+	}
+	if len(todoTags) == 0 {
+		return nil
+	}
+	return t.tx.Model(todo).Association("Tags").Append(todoTags)
+}
+
+// ImportCalendar saves an import in one transaction, so a failure leaves the
+// planner unchanged. New todos and reminders go to the end of their lists.
+func (db *Database) ImportCalendar(userID uint, imp CalendarImport) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := loadTagNames(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var todoPosition float64
+		if err := tx.Model(&models.TodoItem{}).Where("user_id = ?", userID).Select("COALESCE(MAX(position), 0)").Scan(&todoPosition).Error; err != nil {
+			return err
+		}
+		for _, todo := range imp.Todos {
+			if todo.ID == 0 {
+				todoPosition++
+				todo.Position = todoPosition
+			}
+			if err := tx.Omit(clause.Associations).Save(todo).Error; err != nil {
+				return err
+			}
+
+			if err := tags.tag(todo, imp.TodoTags[todo]); err != nil {
+				return err
+			}
+		}
+
+		for _, block := range imp.TimeBlocks {
+			if err := tx.Omit(clause.Associations).Save(block).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, contact := range imp.Contacts {
+			if contact.ID == 0 {
+				var max float64
+				if err := tx.Model(&models.Contact{}).Scopes(UserDateScope(userID, contact.Date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+					return err
+				}
+				contact.Position = max + 1
+			}
+			if err := tx.Omit(clause.Associations).Save(contact).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DataExport operations
+func (db *Database) CreateDataExport(export *models.DataExport) error {
+	return db.DB.Create(export).Error
+}
+
+// FindDataExportByIDAndUserID returns an export without its archive
+func (db *Database) FindDataExportByIDAndUserID(id, userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	err := db.DB.Omit("archive").Where("id = ? AND user_id = ?", id, userID).First(&export).Error
+	return &export, err
+}
+
+// FindDataExportsByUserID returns the user's exports, newest first, without their archives
+func (db *Database) FindDataExportsByUserID(userID uint) ([]models.DataExport, error) {
+	var exports []models.DataExport
+	err := db.DB.Omit("archive").Where("user_id = ?", userID).Order("created_at DESC").Find(&exports).Error
+	return exports, err
+}
+
+// FindUnfinishedDataExport returns the user's pending or running export, if any
+func (db *Database) FindUnfinishedDataExport(userID uint) (*models.DataExport, error) {
+	var export models.DataExport
+	err := db.DB.Omit("archive").
+		Where("user_id = ? AND status IN ?", userID, []string{models.DataExportPending, models.DataExportRunning}).
+		First(&export).Error
+	return &export, err
+}
+
+// FindDataExportByToken returns a finished export, archive included, by its download token
+func (db *Database) FindDataExportByToken(token string) (*models.DataExport, error) {
+	var export models.DataExport
+	err := db.DB.Where("token = ? AND status = ?", token, models.DataExportReady).First(&export).Error
+	return &export, err
+}
+
+// ClaimDataExport takes the oldest pending export for building. It returns
+// gorm.ErrRecordNotFound when none is waiting; two workers never get the same one.
+func (db *Database) ClaimDataExport() (*models.DataExport, error) {
+	var export models.DataExport
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("archive").Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.DataExportPending).
+			Order("created_at").
+			First(&export).Error; err != nil {
+			return err
+		}
+		export.Status = models.DataExportRunning
+		return tx.Model(&export).Update("status", export.Status).Error
+	})
+	return &export, err
+}
+
+// CompleteDataExport stores a built archive and starts its download window
+func (db *Database) CompleteDataExport(export *models.DataExport, archive []byte, expiresAt time.Time) error {
+	export.Status = models.DataExportReady
+	export.Size = len(archive)
+	export.ExpiresAt = &expiresAt
+	return db.DB.Model(export).Updates(map[string]interface{}{
+		"status":     export.Status,
+		"archive":    archive,
+		"size":       export.Size,
+		"expires_at": expiresAt,
+	}).Error
+}
+
+func (db *Database) FailDataExport(export *models.DataExport, reason string) error {
+	export.Status = models.DataExportFailed
+	export.Error = reason
+	return db.DB.Model(export).Updates(map[string]interface{}{"status": export.Status, "error": reason}).Error
+}
+
+// FailStaleDataExports gives up on exports left running since before, e.g.
+// by a worker that was stopped part-way
+func (db *Database) FailStaleDataExports(before time.Time) error {
+	return db.DB.Model(&models.DataExport{}).
+		Where("status = ? AND updated_at < ?", models.DataExportRunning, before).
+		Updates(map[string]interface{}{"status": models.DataExportFailed, "error": "Export did not finish"}).Error
+}
+
+// PurgeDataExports permanently deletes exports whose download window closed
+// before now, and failed ones older than that window
+func (db *Database) PurgeDataExports(now time.Time) error {
+	return db.DB.Unscoped().
+		Where("expires_at < ? OR (status = ? AND created_at < ?)", now, models.DataExportFailed, now.Add(-models.DataExportTTL)).
+		Delete(&models.DataExport{}).Error
+}
+
+// UserData is everything a data export holds for one user
+type UserData struct {
+	User        models.User
+	Todos       []models.TodoItem // With tags and subtasks
+	Priorities  []models.Priority
+	Contacts    []models.Contact
+	WaterTarget int                   // Daily target of the water habit
+	Water       []models.HabitCheckIn // Check-ins of the water habit
+	Thoughts    []models.Thought
+}
+
+// FindUserData reads a user's planner data for an export, all from one snapshot
+func (db *Database) FindUserData(userID uint) (*UserData, error) {
+	data := &UserData{}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ").Error; err != nil {
+			return err
+		}
+		if err := tx.First(&data.User, userID).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("Tags").Preload("Subtasks", orderByPosition).Where("user_id = ?", userID).Order("id").Find(&data.Todos).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Order("date").Order("position").Order("id").Find(&data.Priorities).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Order("date").Order("position").Order("id").Find(&data.Contacts).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Order("date").Order("id").Find(&data.Thoughts).Error; err != nil {
+			return err
+		}
+
+		var water models.Habit
+		err := tx.Where("user_id = ? AND system_key = ?", userID, models.HabitKeyWater).First(&water).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data.WaterTarget = water.Target
+		return tx.Where("habit_id = ?", water.ID).Order("date").Find(&data.Water).Error
+	})
+	return data, err
+}
+
+// DataImport holds the records made from an export archive, a CSV file or
+// another app's export. Everything in it is new.
+type DataImport struct {
+	Todos         []*models.TodoItem                    // Their Subtasks are created with them
+	TodoTags      map[*models.TodoItem][]string         // Matched by name and created when missing
+	TodoProjects  map[*models.TodoItem]string           // Matched by name and created when missing
+	Priorities    []*models.Priority                    // Placed at the end of their day
+	PriorityTodos map[*models.Priority]*models.TodoItem // Todos in the import that priorities promote
+	Contacts      []*models.Contact                     // Placed at the end of their day
+	Water         []models.HabitCheckIn                 // Check-ins of the water habit, which is created if missing
+	Thoughts      []*models.Thought
+}
+
+// FindThoughtDays returns the dates, as YYYY-MM-DD, the user has a thought
+// for. Deleted thoughts count, since a day can only ever have one.
+func (db *Database) FindThoughtDays(userID uint) (map[string]bool, error) {
+	var dates []time.Time
+	if err := db.DB.Unscoped().Model(&models.Thought{}).Where("user_id = ?", userID).Pluck("date", &dates).Error; err != nil {
+		return nil, err
+	}
+	return dateSet(dates), nil
+}
+
+// FindWaterDays returns the dates, as YYYY-MM-DD, the user's water habit has a check-in for
+func (db *Database) FindWaterDays(userID uint) (map[string]bool, error) {
+	var dates []time.Time
+	err := db.DB.Unscoped().Model(&models.HabitCheckIn{}).
+		Joins("JOIN habits ON habits.id = habit_check_ins.habit_id").
+		Where("habits.user_id = ? AND habits.system_key = ?", userID, models.HabitKeyWater).
+		Pluck("habit_check_ins.date", &dates).Error
+	if err != nil {
+		return nil, err
+	}
+	return dateSet(dates), nil
+}
+
+// CountPrioritiesByDay returns how many priorities the user has on each
+// day they have any, keyed by YYYY-MM-DD
+func (db *Database) CountPrioritiesByDay(userID uint) (map[string]int, error) {
+	var rows []struct {
+		Date  time.Time
+		Count int
+	}
+	err := db.DB.Model(&models.Priority{}).Scopes(UserScope(userID)).
+		Select("date, COUNT(*) AS count").Group("date").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.Date.Format("2006-01-02")] = row.Count
+	}
+	return counts, nil
+}
+
+func dateSet(dates []time.Time) map[string]bool {
+	set := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		set[date.Format("2006-01-02")] = true
+	}
+	return set
+}
+
+// ImportData saves an import in one transaction: either all of it is saved
+// or, on any error, none of it. New todos go to the end of the list and
+// priorities and reminders to the end of their day. It fails with
+// ErrPriorityLimit when a day would get more than limit priorities, and
+// with ErrAlreadyPriority when a todo is promoted twice on the same day.
+func (db *Database) ImportData(userID uint, imp DataImport, limit int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		tags, err := loadTagNames(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		var projects []models.Project
+		if err := tx.Where("user_id = ?", userID).Find(&projects).Error; err != nil {
+			return err
+		}
+		projectIDs := make(map[string]uint, len(projects))
+		for _, project := range projects {
+			projectIDs[strings.ToLower(project.Name)] = project.ID
+		}
+
+		// Positions already handed out, per list
+		positions := make(map[string]float64)
+		next := func(key string, list func() *gorm.DB) (float64, error) {
+			if _, ok := positions[key]; !ok {
+				var max float64
+				if err := list().Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+					return 0, err
+				}
+				positions[key] = max
+			}
+			positions[key]++
+			return positions[key], nil
+		}
+
+		for _, todo := range imp.Todos {
+			if name := imp.TodoProjects[todo]; name != "" {
+				id, ok := projectIDs[strings.ToLower(name)]
+				if !ok {
+					position, err := next("projects", func() *gorm.DB { return tx.Model(&models.Project{}).Scopes(UserScope(userID)) })
+					if err != nil {
+						return err
+					}
+					project := models.Project{UserID: userID, Name: name, Position: position}
+					if err := tx.Omit(clause.Associations).Create(&project).Error; err != nil {
+						return err
+					}
+					id = project.ID
+					projectIDs[strings.ToLower(name)] = id
+				}
+				todo.ProjectID = &id
+			}
+
+			position, err := next("todos", func() *gorm.DB { return tx.Model(&models.TodoItem{}).Scopes(UserScope(userID)) })
+			if err != nil {
+				return err
+			}
+			todo.UserID = userID
+			todo.Position = position
+			if err := tx.Omit(clause.Associations).Create(todo).Error; err != nil {
+				return err
+			}
+
+			for i := range todo.Subtasks {
+				todo.Subtasks[i].UserID = userID
+				todo.Subtasks[i].TodoItemID = todo.ID
+				todo.Subtasks[i].Position = float64(i + 1)
+			}
+			if len(todo.Subtasks) > 0 {
+				if err := tx.Create(&todo.Subtasks).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := tags.tag(todo, imp.TodoTags[todo]); err != nil {
+				return err
+			}
+		}
+
+		if len(imp.Priorities) > 0 {
+			// Lock the user as createPriorityWithinLimit does, so no other
+			// request squeezes in under the limit while the import runs
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+				return err
+			}
+		}
+		perDay := make(map[string]int64)
+		promoted := make(map[string]bool)
+		for _, priority := range imp.Priorities {
+			date := priority.Date
+			day := date.Format("2006-01-02")
+			if todo := imp.PriorityTodos[priority]; todo != nil {
+				key := fmt.Sprintf("%s %d", day, todo.ID)
+				if promoted[key] {
+					return ErrAlreadyPriority
+				}
+				promoted[key] = true
+				priority.TodoItemID = &todo.ID
+			}
+			if _, ok := perDay[day]; !ok {
+				var count int64
+				if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(userID, date)).Count(&count).Error; err != nil {
+					return err
+				}
+				perDay[day] = count
+			}
+			if perDay[day] >= int64(limit) {
+				return ErrPriorityLimit
+			}
+			perDay[day]++
+
+			position, err := next("priorities "+date.Format("2006-01-02"), func() *gorm.DB {
+				return tx.Model(&models.Priority{}).Scopes(UserDateScope(userID, date))
+			})
+			if err != nil {
+				return err
+			}
+			priority.UserID = userID
+			priority.Position = position
+			if err := tx.Omit(clause.Associations).Create(priority).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, contact := range imp.Contacts {
+			date := contact.Date
+			position, err := next("contacts "+date.Format("2006-01-02"), func() *gorm.DB {
+				return tx.Model(&models.Contact{}).Scopes(UserDateScope(userID, date))
+			})
+			if err != nil {
+				return err
+			}
+			contact.UserID = userID
+			contact.Position = position
+			if err := tx.Omit(clause.Associations).Create(contact).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(imp.Water) > 0 {
+			habit, err := findOrCreateWaterHabit(tx, userID)
+			if err != nil {
+				return err
+			}
+			for i := range imp.Water {
+				imp.Water[i].UserID = userID
+				imp.Water[i].HabitID = habit.ID
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&imp.Water).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, thought := range imp.Thoughts {
+			thought.UserID = userID
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(thought).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
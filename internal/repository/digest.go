@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// digestSentColumns holds, per kind of email, the day it was last sent for
+var digestSentColumns = map[string]string{
+	models.DigestKindMorning: "digest_morning_sent",
+	models.DigestKindEvening: "digest_evening_sent",
+}
+
+// FindDigestUsers returns the users who get a morning or evening email,
+// with just the settings sending needs
+func (db *Database) FindDigestUsers() ([]models.User, error) {
+	var users []models.User
+	err := db.DB.Select("id, username, email, timezone, digest_morning, digest_evening, digest_morning_at, digest_evening_at, digest_token, digest_morning_sent, digest_evening_sent").
+		Where("(digest_morning OR digest_evening) AND digest_token IS NOT NULL").
+		Find(&users).Error
+	return users, err
+}
+
+// ClaimDigest records that the user's email of the given kind is being sent
+// for day. It reports false when it already was, so that each email goes out
+// once a day even with several processes running the job.
+func (db *Database) ClaimDigest(userID uint, kind string, day time.Time) (bool, error) {
+	column, ok := digestSentColumns[kind]
+	if !ok {
+		return false, fmt.Errorf("unknown digest kind %q", kind)
+	}
+	result := db.DB.Model(&models.User{}).
+		Where("id = ?", userID).
+		Where("("+column+" IS NULL OR "+column+" < ?)", day).
+		Update(column, day)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseDigest undoes ClaimDigest after the email failed to send, so that
+// the next run tries again
+func (db *Database) ReleaseDigest(userID uint, kind string, day time.Time) error {
+	column, ok := digestSentColumns[kind]
+	if !ok {
+		return fmt.Errorf("unknown digest kind %q", kind)
+	}
+	return db.DB.Model(&models.User{}).Where("id = ? AND "+column+" = ?", userID, day).Update(column, nil).Error
+}
+
+// FindUserByDigestToken looks up the user an unsubscribe link was sent to
+func (db *Database) FindUserByDigestToken(token string) (*models.User, error) {
+	var user models.User
+	err := db.DB.Where("digest_token = ?", token).First(&user).Error
+	return &user, err
+}
+
+// UnsubscribeDigest turns off both of the user's daily emails
+func (db *Database) UnsubscribeDigest(userID uint) error {
+	return db.DB.Model(&models.User{}).Where("id = ?", userID).
+		Updates(map[string]interface{}{"digest_morning": false, "digest_evening": false}).Error
+}
+
+// FindTodosDueOn returns the user's todos due on day
+func (db *Database) FindTodosDueOn(userID uint, day time.Time) ([]models.TodoItem, error) {
+	var todos []models.TodoItem
+	err := db.DB.Where("user_id = ? AND DATE(due_date) = ?", userID, day.Format("2006-01-02")).
+		Order("priority_level").Order("position").Order("id").
+		Find(&todos).Error
+	return todos, err
+}
+
+// FindOverdueTodos returns the user's open todos due before day, oldest first
+func (db *Database) FindOverdueTodos(userID uint, day time.Time) ([]models.TodoItem, error) {
+	var todos []models.TodoItem
+	err := db.DB.Where("user_id = ? AND DATE(due_date) < ? AND NOT completed", userID, day.Format("2006-01-02")).
+		Order("due_date").Order("priority_level").Order("id").
+		Find(&todos).Error
+	return todos, err
+}
+
+// FindPendingContacts returns the user's open follow-up reminders dated on
+// or before day, oldest first
+func (db *Database) FindPendingContacts(userID uint, day time.Time) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := db.DB.Where("user_id = ? AND date <= ? AND NOT completed", userID, day).
+		Order("date").Order("position").Order("id").
+		Find(&contacts).Error
+	return contacts, err
+}
+
+// FindContactsByUserIDAndDate returns a day's follow-up reminders
+func (db *Database) FindContactsByUserIDAndDate(userID uint, day time.Time) ([]models.Contact, error) {
+	var contacts []models.Contact
+	err := db.DB.Scopes(UserDateScope(userID, day)).Order("position").Order("id").Find(&contacts).Error
+	return contacts, err
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"gorm.io/gorm"
+)
+
+// minPositionGap is the smallest gap between neighbours before a list is rebalanced
+const minPositionGap = 1e-9
+
+// ErrNoNeighbour is returned when a move names neither neighbour
+var ErrNoNeighbour = errors.New("a move needs an afterId or beforeId")
+
+// ErrNeighbourOrder is returned when the named neighbours are in the wrong order
+var ErrNeighbourOrder = errors.New("afterId must come before beforeId")
+
+// Scope restricts a query to one orderable list, e.g. a user's priorities for a day
+type Scope func(*gorm.DB) *gorm.DB
+
+// Reposition moves the row id of model between afterID and beforeID within
+// the list selected by scope. The row gets the midpoint of its neighbours'
+// positions so no other row is touched; only when repeated moves have
+// exhausted the gap between two neighbours is the list renumbered. Either
+// neighbour may be omitted, in which case the row is placed directly after
+// afterID or directly before beforeID.
+func (db *Database) Reposition(model interface{}, scope Scope, id uint, afterID, beforeID *uint) (float64, error) {
+	if afterID == nil && beforeID == nil {
+		return 0, ErrNoNeighbour
+	}
+
+	var position float64
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		list := func() *gorm.DB { return scope(tx.Model(model)) }
+
+		// The moved row must belong to the list
+		if _, err := positionOf(list(), id); err != nil {
+			return err
+		}
+
+		lo, hi, err := neighbourPositions(list, id, afterID, beforeID)
+		if err != nil {
+			return err
+		}
+
+		if hi-lo < minPositionGap {
+			if err := renumber(list); err != nil {
+				return err
+			}
+			if lo, hi, err = neighbourPositions(list, id, afterID, beforeID); err != nil {
+				return err
+			}
+		}
+
+		position = lo + (hi-lo)/2
+		return list().Where("id = ?", id).Update("position", position).Error
+	})
+
+	return position, err
+}
+
+// neighbourPositions returns the positions the moved row must fall between.
+// A missing neighbour is the row next to the given one, or one step beyond
+// the end of the list.
+func neighbourPositions(list func() *gorm.DB, id uint, afterID, beforeID *uint) (float64, float64, error) {
+	lo, hi := math.Inf(-1), math.Inf(1)
+
+	if afterID != nil {
+		pos, err := positionOf(list(), *afterID)
+		if err != nil {
+			return 0, 0, err
+		}
+		lo = pos
+	}
+	if beforeID != nil {
+		pos, err := positionOf(list(), *beforeID)
+		if err != nil {
+			return 0, 0, err
+		}
+		hi = pos
+	}
+
+	if afterID == nil {
+		var prev []float64
+		if err := list().Where("position < ? AND id <> ?", hi, id).Order("position DESC").Limit(1).Pluck("position", &prev).Error; err != nil {
+			return 0, 0, err
+		}
+		lo = hi - 2
+		if len(prev) > 0 {
+			lo = prev[0]
+		}
+	}
+	if beforeID == nil {
+		var next []float64
+		if err := list().Where("position > ? AND id <> ?", lo, id).Order("position").Limit(1).Pluck("position", &next).Error; err != nil {
+			return 0, 0, err
+		}
+		hi = lo + 2
+		if len(next) > 0 {
+			hi = next[0]
+		}
+	}
+
+	if hi < lo {
+		return 0, 0, ErrNeighbourOrder
+	}
+	return lo, hi, nil
+}
+
+func positionOf(query *gorm.DB, id uint) (float64, error) {
+	var positions []float64
+	if err := query.Where("id = ?", id).Pluck("position", &positions).Error; err != nil {
+		return 0, err
+	}
+	if len(positions) == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	return positions[0], nil
+}
+
+// renumber rewrites the list's positions to 1..n in their current order
+func renumber(list func() *gorm.DB) error {
+	var ids []uint
+	if err := list().Order("position").Order("id").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for i, id := range ids {
+		if err := list().Where("id = ?", id).Update("position", float64(i+1)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserScope selects all of a user's rows, e.g. their todos or projects
+func UserScope(userID uint) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ?", userID)
+	}
+}
+
+// UserDateScope selects a user's rows for one day, e.g. that day's priorities
+func UserDateScope(userID uint, date time.Time) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("user_id = ? AND date = ?", userID, date)
+	}
+}
+
+// NextContactPosition returns a position that sorts after the day's existing contact reminders
+func (db *Database) NextContactPosition(userID uint, date time.Time) (float64, error) {
+	var max float64
+	err := db.DB.Model(&models.Contact{}).Scopes(UserDateScope(userID, date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error
+	return max + 1, err
+}
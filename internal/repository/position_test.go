@@ -0,0 +1,106 @@
+package repository_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"github.com/himanshu/daily-planner/internal/testdb"
+	"gorm.io/gorm"
+)
+
+func TestReposition(t *testing.T) {
+	// Todos are named by their index in the starting list
+	tests := []struct {
+		name          string
+		positions     []float64 // Of todos 0, 1, 2 and 3
+		move          int
+		after, before int  // Index of the neighbour, or -1 for none
+		otherUser     bool // The neighbour belongs to someone else
+		want          float64
+		order         []int
+		err           error
+	}{
+		{name: "between two neighbours", move: 3, after: 0, before: 1, want: 1.5, order: []int{0, 3, 1, 2}},
+		{name: "after a row only", move: 0, after: 2, before: -1, want: 3.5, order: []int{1, 2, 0, 3}},
+		{name: "before a row only", move: 2, after: -1, before: 1, want: 1.5, order: []int{0, 2, 1, 3}},
+		{name: "to the top", move: 3, after: -1, before: 0, want: 0, order: []int{3, 0, 1, 2}},
+		{name: "to the bottom", move: 0, after: 3, before: -1, want: 5, order: []int{1, 2, 3, 0}},
+		{
+			name:      "an exhausted gap renumbers the list",
+			positions: []float64{1, 1 + 1e-10, 3, 4},
+			move:      2, after: 0, before: 1,
+			want:  1.5,
+			order: []int{0, 2, 1, 3},
+		},
+		{name: "no neighbour", move: 0, after: -1, before: -1, err: repository.ErrNoNeighbour},
+		{name: "neighbours in the wrong order", move: 0, after: 3, before: 1, err: repository.ErrNeighbourOrder},
+		{name: "neighbour in another list", move: 0, after: 1, before: -1, otherUser: true, err: gorm.ErrRecordNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Open(t)
+			user := testdb.User(t, db, "")
+
+			positions := tt.positions
+			if positions == nil {
+				positions = []float64{1, 2, 3, 4}
+			}
+			todos := make([]models.TodoItem, len(positions))
+			for i, position := range positions {
+				todos[i] = models.TodoItem{UserID: user.ID, Title: "todo", Position: position}
+				if err := db.CreateTodo(&todos[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			idOf := func(i int) *uint {
+				if i < 0 {
+					return nil
+				}
+				return &todos[i].ID
+			}
+			after, before := idOf(tt.after), idOf(tt.before)
+			if tt.otherUser {
+				other := models.TodoItem{UserID: testdb.User(t, db, "").ID, Title: "elsewhere", Position: 1}
+				if err := db.CreateTodo(&other); err != nil {
+					t.Fatal(err)
+				}
+				after = &other.ID
+			}
+
+			got, err := db.Reposition(&models.TodoItem{}, repository.UserScope(user.ID), todos[tt.move].ID, after, before)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Reposition() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reposition() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Reposition() = %v, want %v", got, tt.want)
+			}
+
+			var ids []uint
+			if err := db.DB.Model(&models.TodoItem{}).Scopes(repository.UserScope(user.ID)).Order("position").Pluck("id", &ids).Error; err != nil {
+				t.Fatal(err)
+			}
+			order := make([]int, len(ids))
+			for i, id := range ids {
+				for j := range todos {
+					if todos[j].ID == id {
+						order[i] = j
+					}
+				}
+			}
+			if !reflect.DeepEqual(order, tt.order) {
+				t.Errorf("order = %v, want %v", order, tt.order)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewMove reschedules one of a day's unfinished todos or priorities
+// when the day is reviewed
+type ReviewMove struct {
+	TodoID     uint      // Set for a todo
+	PriorityID uint      // Set for a priority
+	Drop       bool      // Delete it, to the trash
+	Date       time.Time // Otherwise the day it moves to
+}
+
+// FindDailyReview returns the user's review of a day
+func (db *Database) FindDailyReview(userID uint, date time.Time) (*models.DailyReview, error) {
+	var review models.DailyReview
+	err := db.DB.Scopes(UserDateScope(userID, date)).First(&review).Error
+	return &review, err
+}
+
+// FindDailyReviews returns the user's reviews of the days from from to to
+func (db *Database) FindDailyReviews(userID uint, from, to time.Time) ([]models.DailyReview, error) {
+	var reviews []models.DailyReview
+	err := db.DB.Where("user_id = ? AND date BETWEEN ? AND ?", userID, from, to).Order("date").Find(&reviews).Error
+	return reviews, err
+}
+
+// SaveDailyReview saves the review of a day and reschedules the day's
+// unfinished items, all or nothing. A priority goes to the end of its new
+// day, and the review fails with ErrPriorityLimit when that day already
+// has limit priorities. A promoted priority takes its todo along when the
+// todo was due that day; dropping it only demotes the todo.
+func (db *Database) SaveDailyReview(review *models.DailyReview, moves []ReviewMove, limit int) error {
+	day := review.Date.Format("2006-01-02")
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, move := range moves {
+			if move.TodoID != 0 {
+				var todo models.TodoItem
+				if err := tx.Where("id = ? AND user_id = ? AND DATE(due_date) = ? AND NOT completed", move.TodoID, review.UserID, day).First(&todo).Error; err != nil {
+					return err
+				}
+				if move.Drop {
+					now := time.Now()
+					if err := tx.Model(&models.Priority{}).Where("todo_item_id = ?", todo.ID).Update("deleted_at", now).Error; err != nil {
+						return err
+					}
+					if err := tx.Model(&todo).Update("deleted_at", now).Error; err != nil {
+						return err
+					}
+					continue
+				}
+				if err := tx.Model(&todo).Update("due_date", move.Date).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			var priority models.Priority
+			if err := tx.Preload("TodoItem").Scopes(UserDateScope(review.UserID, review.Date)).Where("id = ?", move.PriorityID).First(&priority).Error; err != nil {
+				return err
+			}
+			if priority.Completed || priority.TodoItem != nil && priority.TodoItem.Completed {
+				return gorm.ErrRecordNotFound // Only unfinished items are rescheduled
+			}
+			if move.Drop {
+				if err := tx.Delete(&priority).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := movePriority(tx, &priority, move.Date, limit); err != nil {
+				return err
+			}
+			if priority.TodoItemID != nil {
+				if err := tx.Model(&models.TodoItem{}).Where("id = ? AND DATE(due_date) = ?", *priority.TodoItemID, day).Update("due_date", move.Date).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		reviewedAt := time.Now()
+		review.ReviewedAt = &reviewedAt
+		return tx.Save(review).Error
+	})
+}
+
+// movePriority moves a priority to the end of another day within the
+// limit. A promoted todo already on that day's list just leaves this one.
+func movePriority(tx *gorm.DB, priority *models.Priority, date time.Time, limit int) error {
+	if priority.TodoItemID != nil {
+		var promoted int64
+		if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, date)).Where("todo_item_id = ?", *priority.TodoItemID).Count(&promoted).Error; err != nil {
+			return err
+		}
+		if promoted > 0 {
+			return tx.Delete(priority).Error
+		}
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, priority.UserID).Error; err != nil {
+		return err
+	}
+
+	var count int64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, date)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= int64(limit) {
+		return ErrPriorityLimit
+	}
+
+	var max float64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+		return err
+	}
+	return tx.Model(priority).Updates(map[string]interface{}{"date": date, "position": max + 1}).Error
+}
+
+// PlanPriorities adds priorities for the days ahead, all or nothing. Each
+// goes to the end of its day. It fails with ErrPriorityLimit when a day
+// would get more than limit priorities, and with ErrAlreadyPriority when a
+// todo is promoted to a day it already is.
+func (db *Database) PlanPriorities(priorities []*models.Priority, limit int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, priority := range priorities {
+			if priority.TodoItemID != nil {
+				var todo models.TodoItem
+				if err := tx.Select("id").Where("id = ? AND user_id = ?", *priority.TodoItemID, priority.UserID).First(&todo).Error; err != nil {
+					return err
+				}
+				var promoted int64
+				if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, priority.Date)).Where("todo_item_id = ?", todo.ID).Count(&promoted).Error; err != nil {
+					return err
+				}
+				if promoted > 0 {
+					return ErrAlreadyPriority
+				}
+			}
+			if err := createPriorityWithinLimit(tx, priority, limit); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+)
+
+// Stats intervals
+const (
+	StatsDaily  = "day"
+	StatsWeekly = "week" // Weeks start on Monday
+)
+
+// StatsPeriod is the range of days stats are computed over
+type StatsPeriod struct {
+	From, To time.Time      // First and last day, inclusive
+	Today    time.Time      // The user's today; later days don't count towards rates yet
+	Location *time.Location // The user's time zone, for the day a timestamp falls on
+	Interval string         // StatsDaily or StatsWeekly
+}
+
+// until returns the last day of the period that counts towards rates
+func (p StatsPeriod) until() time.Time {
+	if p.Today.Before(p.To) {
+		return p.Today
+	}
+	return p.To
+}
+
+func (p StatsPeriod) args(userID uint) map[string]interface{} {
+	return map[string]interface{}{
+		"user":     userID,
+		"from":     p.From.Format("2006-01-02"),
+		"to":       p.To.Format("2006-01-02"),
+		"until":    p.until().Format("2006-01-02"),
+		"start":    time.Date(p.From.Year(), p.From.Month(), p.From.Day(), 0, 0, 0, 0, p.Location),
+		"tz":       p.Location.String(),
+		"interval": p.Interval,
+		"water":    models.HabitKeyWater,
+	}
+}
+
+// TodoFlow counts the todos created and completed in one day or week
+type TodoFlow struct {
+	Bucket          time.Time // The day, or the Monday of the week
+	Created         int
+	Completed       int
+	CompleteSeconds float64 // Total time the completed todos took from creation to completion
+}
+
+// todoCompletedAt is when a completed todo was last completed: the time
+// the activity log recorded, or for todos completed before there was a log
+// the time they were last changed
+const todoCompletedAt = `COALESCE((
+	SELECT MAX(a.created_at) FROM activity_logs a
+	WHERE a.user_id = t.user_id AND a.entity = 'todo' AND a.entity_id = t.id AND a.action = '` + models.ActivityComplete + `'
+), t.updated_at)`
+
+// CountTodoFlow returns how many todos the user created and completed in
+// each day or week of the period that had any. Completing a todo changes
+// it, so only todos changed since the period began are looked at.
+func (db *Database) CountTodoFlow(userID uint, period StatsPeriod) ([]TodoFlow, error) {
+	var flow []TodoFlow
+	err := db.DB.Raw(`
+		WITH todos AS (
+			SELECT t.created_at, CASE WHEN t.completed THEN `+todoCompletedAt+` END AS completed_at
+			FROM todo_items t
+			WHERE t.user_id = @user AND t.deleted_at IS NULL AND t.updated_at >= @start
+		), days AS (
+			SELECT (created_at AT TIME ZONE @tz)::date AS day, 1 AS created, 0 AS completed, 0::float8 AS seconds
+			FROM todos
+			UNION ALL
+			SELECT (completed_at AT TIME ZONE @tz)::date, 0, 1, GREATEST(EXTRACT(EPOCH FROM completed_at - created_at), 0)::float8
+			FROM todos
+			WHERE completed_at IS NOT NULL
+		)
+		SELECT date_trunc(@interval, day::timestamp)::date AS bucket,
+			SUM(created) AS created, SUM(completed) AS completed, SUM(seconds) AS complete_seconds
+		FROM days
+		WHERE day BETWEEN @from AND @to
+		GROUP BY 1
+		ORDER BY 1`, period.args(userID)).Scan(&flow).Error
+	return flow, err
+}
+
+// StatsRate counts things that were due in one day or week and how many of
+// them hit their goal, e.g. priorities and how many were completed
+type StatsRate struct {
+	Bucket time.Time // The day, or the Monday of the week
+	Total  int
+	Hit    int
+}
+
+// CountPriorityCompletion returns how many of the user's priorities were
+// completed in each day or week of the period up to today. A promoted
+// priority is completed when its todo is.
+func (db *Database) CountPriorityCompletion(userID uint, period StatsPeriod) ([]StatsRate, error) {
+	var rates []StatsRate
+	err := db.DB.Raw(`
+		SELECT date_trunc(@interval, p.date::timestamp)::date AS bucket,
+			COUNT(*) AS total, COUNT(*) FILTER (WHERE COALESCE(t.completed, p.completed)) AS hit
+		FROM priorities p
+		LEFT JOIN todo_items t ON t.id = p.todo_item_id
+		WHERE p.user_id = @user AND p.deleted_at IS NULL AND p.date BETWEEN @from AND @until
+		GROUP BY 1
+		ORDER BY 1`, period.args(userID)).Scan(&rates).Error
+	return rates, err
+}
+
+// CountContactFollowUps returns how many of the user's follow-up reminders
+// were done in each day or week of the period up to today
+func (db *Database) CountContactFollowUps(userID uint, period StatsPeriod) ([]StatsRate, error) {
+	var rates []StatsRate
+	err := db.DB.Raw(`
+		SELECT date_trunc(@interval, date::timestamp)::date AS bucket,
+			COUNT(*) AS total, COUNT(*) FILTER (WHERE completed) AS hit
+		FROM contacts
+		WHERE user_id = @user AND deleted_at IS NULL AND date BETWEEN @from AND @until
+		GROUP BY 1
+		ORDER BY 1`, period.args(userID)).Scan(&rates).Error
+	return rates, err
+}
+
+// CountWaterTargetDays returns on how many days of each day or week of the
+// period up to today the user drank their water target. Days before their
+// first water check-in don't count.
+func (db *Database) CountWaterTargetDays(userID uint, period StatsPeriod) ([]StatsRate, error) {
+	var rates []StatsRate
+	err := db.DB.Raw(`
+		SELECT date_trunc(@interval, d)::date AS bucket,
+			COUNT(*) AS total, COUNT(c.id) FILTER (WHERE c.value >= h.target) AS hit
+		FROM habits h
+		CROSS JOIN generate_series(
+			GREATEST(CAST(@from AS date), (SELECT MIN(date) FROM habit_check_ins WHERE habit_id = h.id AND deleted_at IS NULL))::timestamp,
+			CAST(@until AS timestamp), interval '1 day') AS d
+		LEFT JOIN habit_check_ins c ON c.habit_id = h.id AND c.date = d::date AND c.deleted_at IS NULL
+		WHERE h.user_id = @user AND h.system_key = @water AND h.deleted_at IS NULL
+		GROUP BY 1
+		ORDER BY 1`, period.args(userID)).Scan(&rates).Error
+	return rates, err
+}
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlreadyPriority is returned when a todo would be promoted to a day it
+// already is a priority for, e.g. restoring a priority for a day the todo
+// has been promoted to again since
+var ErrAlreadyPriority = errors.New("todo is already a priority that day")
+
+// Trash holds a user's deleted todos, priorities and follow-up reminders
+type Trash struct {
+	Todos      []models.TodoItem
+	Priorities []models.Priority // Promoted ones carry their todo
+	Contacts   []models.Contact
+}
+
+// FindTrash returns the user's deleted items, most recently deleted first.
+// Priorities whose todo is deleted too are left out; they come back with
+// the todo.
+func (db *Database) FindTrash(userID uint) (*Trash, error) {
+	trash := &Trash{}
+	deleted := func() *gorm.DB {
+		return db.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC").Order("id DESC")
+	}
+
+	if err := deleted().Find(&trash.Todos).Error; err != nil {
+		return nil, err
+	}
+	if err := deleted().Preload("TodoItem").
+		Where("todo_item_id IS NULL OR todo_item_id IN (?)", db.DB.Model(&models.TodoItem{}).Select("id")).
+		Find(&trash.Priorities).Error; err != nil {
+		return nil, err
+	}
+	if err := deleted().Find(&trash.Contacts).Error; err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+// RestoreTodo brings back a deleted todo at the end of the list, together
+// with the priorities it was promoted to when it was deleted. A priority
+// whose day already has limit priorities stays in the trash. It reports how
+// many priorities came back.
+func (db *Database) RestoreTodo(userID, id uint, limit int) (int, error) {
+	restored := 0
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		var todo models.TodoItem
+		if err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&todo).Error; err != nil {
+			return err
+		}
+		deletedAt := todo.DeletedAt.Time
+
+		// Another todo may have taken its CalDAV name in the meantime
+		if todo.CalDAVName != "" {
+			var taken int64
+			if err := tx.Model(&models.TodoItem{}).Where("user_id = ? AND caldav_name = ?", userID, todo.CalDAVName).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				todo.CalDAVName = ""
+			}
+		}
+
+		var max float64
+		if err := tx.Model(&models.TodoItem{}).Where("user_id = ?", userID).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&todo).Updates(map[string]interface{}{
+			"deleted_at":  nil,
+			"position":    max + 1,
+			"caldav_name": todo.CalDAVName,
+		}).Error; err != nil {
+			return err
+		}
+
+		var priorities []models.Priority
+		if err := tx.Unscoped().Where("todo_item_id = ? AND deleted_at = ?", todo.ID, deletedAt).Order("id").Find(&priorities).Error; err != nil {
+			return err
+		}
+		for i := range priorities {
+			ok, err := restorePriority(tx, &priorities[i], limit)
+			if err != nil {
+				return err
+			}
+			if ok {
+				restored++
+			}
+		}
+		return nil
+	})
+	return restored, err
+}
+
+// RestorePriority brings back a deleted priority at the end of its day,
+// unless the day already has limit priorities
+func (db *Database) RestorePriority(userID, id uint, limit int) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var priority models.Priority
+		err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+			Where("todo_item_id IS NULL OR todo_item_id IN (?)", tx.Model(&models.TodoItem{}).Select("id")).
+			First(&priority).Error
+		if err != nil {
+			return err
+		}
+
+		if priority.TodoItemID != nil {
+			var promoted int64
+			if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(userID, priority.Date)).Where("todo_item_id = ?", *priority.TodoItemID).Count(&promoted).Error; err != nil {
+				return err
+			}
+			if promoted > 0 {
+				return ErrAlreadyPriority
+			}
+		}
+
+		ok, err := restorePriority(tx, &priority, limit)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrPriorityLimit
+		}
+		return nil
+	})
+}
+
+// restorePriority restores priority if its day has fewer than limit
+// priorities. The user row is locked as in CreatePriorityWithinLimit.
+func restorePriority(tx *gorm.DB, priority *models.Priority, limit int) (bool, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, priority.UserID).Error; err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, priority.Date)).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count >= int64(limit) {
+		return false, nil
+	}
+
+	var max float64
+	if err := tx.Model(&models.Priority{}).Scopes(UserDateScope(priority.UserID, priority.Date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+		return false, err
+	}
+	err := tx.Unscoped().Model(priority).Updates(map[string]interface{}{"deleted_at": nil, "position": max + 1}).Error
+	return err == nil, err
+}
+
+// RestoreContact brings back a deleted follow-up reminder at the end of its day
+func (db *Database) RestoreContact(userID, id uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		var contact models.Contact
+		if err := tx.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&contact).Error; err != nil {
+			return err
+		}
+
+		var max float64
+		if err := tx.Model(&models.Contact{}).Scopes(UserDateScope(userID, contact.Date)).Select("COALESCE(MAX(position), 0)").Scan(&max).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&contact).Updates(map[string]interface{}{"deleted_at": nil, "position": max + 1}).Error
+	})
+}
+
+// DeleteFromTrash permanently deletes one of the user's deleted items;
+// model is a pointer to a TodoItem, Priority or Contact. Deleting a todo
+// takes its subtasks and priorities with it.
+func (db *Database) DeleteFromTrash(model interface{}, userID, id uint) error {
+	result := db.DB.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).Delete(model)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// EmptyTrash permanently deletes all of the user's deleted items
+func (db *Database) EmptyTrash(userID uint) error {
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.Priority{}, &models.Contact{}, &models.TodoItem{}} {
+			if err := tx.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PurgeTrash permanently deletes every user's items that were deleted
+// before the given time. It returns how many items went.
+func (db *Database) PurgeTrash(before time.Time) (int64, error) {
+	var purged int64
+	for _, model := range []interface{}{&models.Priority{}, &models.Contact{}, &models.TodoItem{}} {
+		result := db.DB.Unscoped().Where("deleted_at < ?", before).Delete(model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += result.RowsAffected
+	}
+	return purged, nil
+}
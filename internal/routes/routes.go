@@ -3,14 +3,20 @@ package routes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/himanshu/daily-planner/internal/auth"
+	"github.com/himanshu/daily-planner/internal/config"
+	"github.com/himanshu/daily-planner/internal/models"
 	"github.com/himanshu/daily-planner/internal/planner"
 	"github.com/himanshu/daily-planner/internal/repository"
+	"github.com/himanshu/daily-planner/pkg/middleware"
 )
 
-func SetupRoutes(r *gin.Engine, db *repository.Database) {
-	// Initialize handlers
-	authHandler := auth.NewAuthHandler(db)
-	plannerHandler := planner.NewPlannerHandler(db)
+func SetupRoutes(r *gin.Engine, db *repository.Database, cfg *config.Config) {
+	// Initialize handlers. Changes through the site and through sync clients
+	// are told apart in the activity log.
+	webDB := db.WithSource(models.ActivitySourceWeb)
+	authHandler := auth.NewAuthHandler(webDB, cfg.AccountDeletionGrace)
+	plannerHandler := planner.NewPlannerHandler(webDB, cfg.TrashRetention)
+	caldavHandler := planner.NewPlannerHandler(db.WithSource(models.ActivitySourceAPI), cfg.TrashRetention)
 
 	// Auth routes
 	authGroup := r.Group("/auth")
@@ -24,33 +30,161 @@ func SetupRoutes(r *gin.Engine, db *repository.Database) {
 		authGroup.POST("/reset-password", authHandler.ResetPasswordHandler)
 		authGroup.GET("/google/login", authHandler.GoogleLoginHandler)
 		authGroup.GET("/google/callback", authHandler.GoogleCallbackHandler)
+		authGroup.POST("/restore-account", authHandler.RestoreAccountHandler)
+		authGroup.GET("/delete-account", middleware.ActiveAccount(db), authHandler.ShowDeleteAccountPage)
+		authGroup.POST("/delete-account", middleware.ActiveAccount(db), authHandler.DeleteAccountHandler)
+	}
+
+	// Calendar feed, authenticated by the secret token in its URL
+	r.GET("/calendar/:token", plannerHandler.CalendarFeed)
+
+	// Unsubscribing from the daily emails, authenticated by the secret token in the link
+	r.GET("/digest/unsubscribe/:token", plannerHandler.ShowDigestUnsubscribe)
+	r.POST("/digest/unsubscribe/:token", plannerHandler.UnsubscribeDigest)
+
+	// Data export downloads, authenticated by the secret token in their URL
+	r.GET("/exports/:token", plannerHandler.DownloadDataExport)
+
+	// CalDAV task sync for reminders apps, authenticated with HTTP Basic auth
+	r.GET("/.well-known/caldav", plannerHandler.WellKnownCalDAV)
+	r.Handle("PROPFIND", "/.well-known/caldav", plannerHandler.WellKnownCalDAV)
+	caldavGroup := r.Group("/caldav", middleware.BasicAuth(db))
+	{
+		for _, method := range []string{"OPTIONS", "PROPFIND", "REPORT", "GET", "HEAD", "PUT", "DELETE"} {
+			caldavGroup.Handle(method, "/*path", caldavHandler.CalDAV)
+		}
 	}
 
 	// Planner routes
-	plannerGroup := r.Group("/planner")
+	plannerGroup := r.Group("/planner", middleware.ActiveAccount(db))
 	{
 		plannerGroup.GET("/", plannerHandler.ShowDashboard)
 		plannerGroup.POST("/todos", plannerHandler.CreateTodo)
 		plannerGroup.GET("/todos", plannerHandler.GetTodos)
 		plannerGroup.PUT("/todos/:id", plannerHandler.UpdateTodo)
 		plannerGroup.DELETE("/todos/:id", plannerHandler.DeleteTodo)
+		plannerGroup.PUT("/todos/:id/move", plannerHandler.MoveTodo)
+		plannerGroup.POST("/todos/:id/promote", plannerHandler.PromoteTodo)
+		plannerGroup.DELETE("/todos/:id/promote", plannerHandler.DemoteTodo)
+		plannerGroup.GET("/todos/:id/subtasks", plannerHandler.GetSubtasks)
+		plannerGroup.POST("/todos/:id/subtasks", plannerHandler.CreateSubtask)
+		plannerGroup.PUT("/todos/:id/subtasks/:subtaskId", plannerHandler.UpdateSubtask)
+		plannerGroup.DELETE("/todos/:id/subtasks/:subtaskId", plannerHandler.DeleteSubtask)
+
+		plannerGroup.GET("/projects", plannerHandler.GetProjects)
+		plannerGroup.POST("/projects", plannerHandler.CreateProject)
+		plannerGroup.PUT("/projects/:id", plannerHandler.UpdateProject)
+		plannerGroup.DELETE("/projects/:id", plannerHandler.DeleteProject)
+		plannerGroup.PUT("/projects/:id/move", plannerHandler.MoveProject)
+		plannerGroup.GET("/projects/:id/todos", plannerHandler.GetProjectTodos)
+		plannerGroup.GET("/projects/:id/stats", plannerHandler.GetProjectStats)
+
+		plannerGroup.GET("/tags", plannerHandler.GetTags)
+		plannerGroup.POST("/tags", plannerHandler.CreateTag)
+		plannerGroup.PUT("/tags/:id", plannerHandler.UpdateTag)
+		plannerGroup.DELETE("/tags/:id", plannerHandler.DeleteTag)
 
 		plannerGroup.POST("/priorities", plannerHandler.CreatePriority)
 		plannerGroup.GET("/priorities", plannerHandler.GetPriorities)
 		plannerGroup.PUT("/priorities/:id", plannerHandler.UpdatePriority)
 		plannerGroup.DELETE("/priorities/:id", plannerHandler.DeletePriority)
+		plannerGroup.PUT("/priorities/:id/move", plannerHandler.MovePriority)
+		plannerGroup.GET("/priorities/settings", plannerHandler.GetPrioritySettings)
+		plannerGroup.PUT("/priorities/settings", plannerHandler.UpdatePrioritySettings)
+
+		plannerGroup.GET("/trash", plannerHandler.GetTrash)
+		plannerGroup.DELETE("/trash", plannerHandler.EmptyTrash)
+		plannerGroup.POST("/trash/:type/:id/restore", plannerHandler.RestoreFromTrash)
+		plannerGroup.DELETE("/trash/:type/:id", plannerHandler.DeleteFromTrash)
 
 		plannerGroup.POST("/contacts", plannerHandler.CreateContact)
 		plannerGroup.GET("/contacts", plannerHandler.GetContacts)
 		plannerGroup.PUT("/contacts/:id", plannerHandler.UpdateContact)
 		plannerGroup.DELETE("/contacts/:id", plannerHandler.DeleteContact)
+		plannerGroup.PUT("/contacts/:id/move", plannerHandler.MoveContact)
+
+		plannerGroup.GET("/people", plannerHandler.GetPeople)
+		plannerGroup.POST("/people", plannerHandler.CreatePerson)
+		plannerGroup.POST("/people/import", plannerHandler.ImportVCards)
+		plannerGroup.GET("/people/export", plannerHandler.ExportVCards)
+		plannerGroup.GET("/people/overdue", plannerHandler.GetOverduePeople)
+		plannerGroup.GET("/people/:id", plannerHandler.GetPerson)
+		plannerGroup.PUT("/people/:id", plannerHandler.UpdatePerson)
+		plannerGroup.DELETE("/people/:id", plannerHandler.DeletePerson)
+		plannerGroup.GET("/people/:id/vcard", plannerHandler.ExportVCard)
+		plannerGroup.GET("/people/:id/interactions", plannerHandler.GetInteractions)
+		plannerGroup.POST("/people/:id/interactions", plannerHandler.CreateInteraction)
+		plannerGroup.DELETE("/people/:id/interactions/:interactionId", plannerHandler.DeleteInteraction)
+		plannerGroup.GET("/people/:id/dates", plannerHandler.GetSignificantDates)
+		plannerGroup.POST("/people/:id/dates", plannerHandler.CreateSignificantDate)
+		plannerGroup.PUT("/people/:id/dates/:dateId", plannerHandler.UpdateSignificantDate)
+		plannerGroup.DELETE("/people/:id/dates/:dateId", plannerHandler.DeleteSignificantDate)
+
+		plannerGroup.GET("/calendar", plannerHandler.GetCalendarFeed)
+		plannerGroup.POST("/calendar/token", plannerHandler.RegenerateCalendarToken)
+		plannerGroup.DELETE("/calendar/token", plannerHandler.DisableCalendarFeed)
+		plannerGroup.POST("/calendar/import", plannerHandler.ImportCalendar)
+
+		plannerGroup.POST("/import", plannerHandler.ImportData)
+		plannerGroup.POST("/exports", plannerHandler.RequestDataExport)
+		plannerGroup.GET("/exports", plannerHandler.GetDataExports)
+		plannerGroup.GET("/exports/:id", plannerHandler.GetDataExport)
+
+		plannerGroup.GET("/review", plannerHandler.ShowDailyReview)
+		plannerGroup.GET("/reviews/:date", plannerHandler.GetDailyReview)
+		plannerGroup.PUT("/reviews/:date", plannerHandler.SaveDailyReview)
+
+		plannerGroup.GET("/weekly-review", plannerHandler.ShowWeeklyReview)
+		plannerGroup.GET("/weekly-reviews/:week", plannerHandler.GetWeeklyReview)
+		plannerGroup.GET("/weekly-reviews/:week/download", plannerHandler.DownloadWeeklyReview)
+		plannerGroup.POST("/weekly-reviews/:week/plan", plannerHandler.PlanNextWeek)
+
+		plannerGroup.GET("/stats", plannerHandler.GetStats)
+		plannerGroup.GET("/analytics", plannerHandler.ShowStats)
+
+		plannerGroup.GET("/activity", plannerHandler.GetActivity)
+		plannerGroup.GET("/activity/:entity/:id", plannerHandler.GetRecordActivity)
+
+		plannerGroup.GET("/occasions", plannerHandler.GetOccasions)
+		plannerGroup.GET("/occasions/settings", plannerHandler.GetOccasionSettings)
+		plannerGroup.PUT("/occasions/settings", plannerHandler.UpdateOccasionSettings)
+
+		plannerGroup.GET("/digest/settings", plannerHandler.GetDigestSettings)
+		plannerGroup.PUT("/digest/settings", plannerHandler.UpdateDigestSettings)
 
 		plannerGroup.POST("/water-intake", plannerHandler.UpdateWaterIntake)
 		plannerGroup.GET("/water-intake", plannerHandler.GetWaterIntake)
 
+		plannerGroup.GET("/habits", plannerHandler.GetHabits)
+		plannerGroup.POST("/habits", plannerHandler.CreateHabit)
+		plannerGroup.PUT("/habits/:id", plannerHandler.UpdateHabit)
+		plannerGroup.DELETE("/habits/:id", plannerHandler.DeleteHabit)
+		plannerGroup.POST("/habits/:id/check-ins", plannerHandler.CheckInHabit)
+		plannerGroup.GET("/habits/:id/check-ins", plannerHandler.GetHabitCheckIns)
+		plannerGroup.GET("/habits/:id/stats", plannerHandler.GetHabitStats)
+		plannerGroup.GET("/habits/:id/heatmap", plannerHandler.GetHabitHeatmap)
+
+		plannerGroup.POST("/focus-sessions/start", plannerHandler.StartFocusSession)
+		plannerGroup.GET("/focus-sessions/active", plannerHandler.GetActiveFocusSession)
+		plannerGroup.GET("/focus-sessions/report", plannerHandler.GetFocusReport)
+		plannerGroup.GET("/focus-sessions", plannerHandler.GetFocusSessions)
+		plannerGroup.POST("/focus-sessions/:id/pause", plannerHandler.PauseFocusSession)
+		plannerGroup.POST("/focus-sessions/:id/resume", plannerHandler.ResumeFocusSession)
+		plannerGroup.POST("/focus-sessions/:id/stop", plannerHandler.StopFocusSession)
+		plannerGroup.DELETE("/focus-sessions/:id", plannerHandler.DeleteFocusSession)
+
+		plannerGroup.GET("/time-blocks", plannerHandler.GetTimeBlocks)
+		plannerGroup.POST("/time-blocks", plannerHandler.CreateTimeBlock)
+		plannerGroup.PUT("/time-blocks/:id", plannerHandler.UpdateTimeBlock)
+		plannerGroup.DELETE("/time-blocks/:id", plannerHandler.DeleteTimeBlock)
+
 		plannerGroup.POST("/thought", plannerHandler.CreateThought)
 		plannerGroup.GET("/thought", plannerHandler.GetTodayThought)
 		plannerGroup.POST("/thought/generate", plannerHandler.GenerateThought)
+
+		plannerGroup.POST("/mood", plannerHandler.SaveMood)
+		plannerGroup.GET("/mood", plannerHandler.GetMood)
+		plannerGroup.GET("/mood/trends", plannerHandler.GetMoodTrends)
 	}
 
 	// Root route redirects to login if not authenticated
@@ -0,0 +1,118 @@
+// Package testdb gives tests a migrated PostgreSQL database to work in.
+package testdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/himanshu/daily-planner/internal/models"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// migrationLock is the advisory lock held while the test database is migrated
+const migrationLock = 81724301
+
+var (
+	setupOnce sync.Once
+	shared    *repository.Database
+	setupErr  error
+	userCount atomic.Int64
+)
+
+// Open returns the database named by TEST_DATABASE_DSN, e.g.
+// "host=localhost user=postgres password=postgres dbname=daily_planner_test",
+// migrated to the latest schema. The test works inside a transaction that is
+// rolled back when it ends, so tests never see each other's rows. Tests are
+// skipped when TEST_DATABASE_DSN is not set.
+func Open(t *testing.T) *repository.Database {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	setupOnce.Do(func() {
+		shared, setupErr = repository.Open(dsn)
+		if setupErr != nil {
+			return
+		}
+		shared.DB.Logger = logger.Default.LogMode(logger.Silent)
+		setupErr = migrate(shared)
+	})
+	if setupErr != nil {
+		t.Fatalf("failed to set up the test database: %v", setupErr)
+	}
+
+	tx := shared.DB.Begin()
+	if tx.Error != nil {
+		t.Fatal(tx.Error)
+	}
+	t.Cleanup(func() {
+		tx.Rollback()
+	})
+	return &repository.Database{DB: tx}
+}
+
+// migrate applies every migration from the module root, where RunMigrations
+// looks for them
+func migrate(db *repository.Database) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	root := wd
+	for {
+		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return errors.New("module root not found")
+		}
+		root = parent
+	}
+	if err := os.Chdir(root); err != nil {
+		return err
+	}
+	defer os.Chdir(wd)
+
+	// Packages are tested in parallel, so only one may migrate at a time
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", migrationLock).Error; err != nil {
+			return err
+		}
+		return repository.RunMigrations(&repository.Database{DB: tx}, "up")
+	})
+}
+
+// User creates a user with a name no other test uses, who logs in with
+// password. An empty password makes an account like those created through
+// Google, which has none.
+func User(t *testing.T, db *repository.Database, password string) *models.User {
+	t.Helper()
+	n := userCount.Add(1)
+	user := &models.User{
+		Username: fmt.Sprintf("test-%d-%d", os.Getpid(), n),
+		Email:    fmt.Sprintf("test-%d-%d@example.com", os.Getpid(), n),
+	}
+	if password != "" {
+		// The lowest cost keeps the tests quick
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Password = string(hash)
+	}
+	if err := db.CreateUser(user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,389 @@
+// Package vcard reads and writes the parts of vCard 2.1, 3.0 and 4.0
+// (RFC 2426, RFC 6350) that the planner's contact book uses.
+package vcard
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// Supported output versions
+const (
+	Version3 = "3.0"
+	Version4 = "4.0"
+)
+
+// Field is a typed value such as an email address or phone number
+type Field struct {
+	Label string // e.g. work or cell; empty when the card gives no type
+	Value string
+}
+
+// Card is one contact
+type Card struct {
+	UID        string
+	Name       string
+	Emails     []Field
+	Phones     []Field
+	Notes      string
+	Birthday   *time.Time // nil when missing or given without a year
+	Categories []string
+}
+
+// ErrNoCards is returned when the input holds no BEGIN:VCARD block
+var ErrNoCards = errors.New("no vCards found")
+
+// line is one unfolded content line
+type line struct {
+	name   string
+	params map[string][]string
+	value  string
+}
+
+// Parse reads every card in r. Cards without a name are skipped.
+func Parse(r io.Reader) ([]Card, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var cards []Card
+	var current *Card
+	var formattedName, structuredName string
+	found := false
+
+	for i, raw := range lines {
+		l, err := parseLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch {
+		case l.name == "BEGIN" && strings.EqualFold(l.value, "VCARD"):
+			found = true
+			current = &Card{}
+			formattedName, structuredName = "", ""
+			continue
+		case l.name == "END" && strings.EqualFold(l.value, "VCARD"):
+			if current != nil {
+				current.Name = formattedName
+				if current.Name == "" {
+					current.Name = structuredName
+				}
+				if current.Name != "" {
+					cards = append(cards, *current)
+				}
+			}
+			current = nil
+			continue
+		}
+		if current == nil {
+			continue
+		}
+
+		value := l.value
+		if enc := l.param("ENCODING"); strings.EqualFold(enc, "QUOTED-PRINTABLE") {
+			decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(value)))
+			if err == nil {
+				value = string(decoded)
+			}
+		}
+
+		switch l.name {
+		case "FN":
+			formattedName = strings.TrimSpace(unescape(value))
+		case "N":
+			structuredName = nameFromN(value)
+		case "EMAIL":
+			if v := strings.TrimSpace(unescape(value)); v != "" {
+				current.Emails = append(current.Emails, Field{Label: l.label(), Value: strings.TrimPrefix(v, "mailto:")})
+			}
+		case "TEL":
+			if v := strings.TrimSpace(unescape(value)); v != "" {
+				current.Phones = append(current.Phones, Field{Label: l.label(), Value: strings.TrimPrefix(v, "tel:")})
+			}
+		case "NOTE":
+			current.Notes = unescape(value)
+		case "BDAY":
+			current.Birthday = parseDate(value)
+		case "CATEGORIES":
+			for _, c := range splitUnescaped(value, ',') {
+				if c = strings.TrimSpace(c); c != "" {
+					current.Categories = append(current.Categories, c)
+				}
+			}
+		case "UID":
+			current.UID = strings.TrimSpace(unescape(value))
+		}
+	}
+
+	if !found {
+		return nil, ErrNoCards
+	}
+	return cards, nil
+}
+
+// unfold joins folded continuation lines and drops blank ones
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) {
+			lines[len(lines)-1] += text[1:]
+			continue
+		}
+		// vCard 2.1 quoted-printable values continue with a soft line break
+		if len(lines) > 0 && strings.HasSuffix(lines[len(lines)-1], "=") && strings.Contains(strings.ToUpper(lines[len(lines)-1]), "QUOTED-PRINTABLE") {
+			lines[len(lines)-1] += "\r\n" + text
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return lines, scanner.Err()
+}
+
+func parseLine(raw string) (line, error) {
+	colon := indexOutsideQuotes(raw, ':')
+	if colon < 0 {
+		return line{}, errors.New("missing ':'")
+	}
+
+	parts := splitOutsideQuotes(raw[:colon], ';')
+	name := strings.ToUpper(parts[0])
+	// Drop the group prefix, e.g. item1.EMAIL
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+
+	params := make(map[string][]string)
+	for _, p := range parts[1:] {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok {
+			// vCard 2.1 bare types such as TEL;CELL:
+			params["TYPE"] = append(params["TYPE"], p)
+			continue
+		}
+		key = strings.ToUpper(key)
+		for _, v := range splitOutsideQuotes(value, ',') {
+			v = strings.Trim(v, `"`)
+			// Types may also be given as one quoted list, e.g. TYPE="work,voice"
+			if key == "TYPE" {
+				params[key] = append(params[key], strings.Split(v, ",")...)
+				continue
+			}
+			params[key] = append(params[key], v)
+		}
+	}
+
+	return line{name: name, params: params, value: raw[colon+1:]}, nil
+}
+
+func (l line) param(key string) string {
+	if values := l.params[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// label returns the first type that describes where a value belongs
+func (l line) label() string {
+	for _, t := range l.params["TYPE"] {
+		switch t = strings.ToLower(t); t {
+		case "pref", "internet", "voice", "x400":
+			continue
+		default:
+			return t
+		}
+	}
+	return ""
+}
+
+// nameFromN builds "Given Family" from a structured N value
+func nameFromN(value string) string {
+	parts := splitUnescaped(value, ';')
+	var family, given string
+	if len(parts) > 0 {
+		family = strings.TrimSpace(parts[0])
+	}
+	if len(parts) > 1 {
+		given = strings.TrimSpace(parts[1])
+	}
+	return strings.TrimSpace(given + " " + family)
+}
+
+// parseDate accepts the date forms phones produce; dates without a year yield nil
+func parseDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "--") {
+		return nil
+	}
+	if t := strings.IndexByte(value, 'T'); t >= 0 {
+		value = value[:t]
+	}
+	for _, layout := range []string{"2006-01-02", "20060102"} {
+		if d, err := time.Parse(layout, value); err == nil {
+			return &d
+		}
+	}
+	return nil
+}
+
+func indexOutsideQuotes(s string, sep byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexOutsideQuotes(s, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+// splitUnescaped splits a value on sep, ignoring backslash-escaped separators, and unescapes each part
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			b.WriteByte(s[i])
+			b.WriteByte(s[i+1])
+			i++
+		case s[i] == sep:
+			parts = append(parts, unescape(b.String()))
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(parts, unescape(b.String()))
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// Write encodes cards in the given version, 3.0 or 4.0
+func Write(w io.Writer, cards []Card, version string) error {
+	if version != Version3 && version != Version4 {
+		return fmt.Errorf("unsupported vCard version %q", version)
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, card := range cards {
+		writeLine(bw, "BEGIN:VCARD")
+		writeLine(bw, "VERSION:"+version)
+		if card.UID != "" {
+			writeLine(bw, "UID:"+escape(card.UID))
+		}
+		writeLine(bw, "FN:"+escape(card.Name))
+		writeLine(bw, "N:"+structuredName(card.Name))
+		for _, email := range card.Emails {
+			writeLine(bw, "EMAIL"+typeParam(email.Label, version, "internet")+":"+escape(email.Value))
+		}
+		for _, phone := range card.Phones {
+			writeLine(bw, "TEL"+typeParam(phone.Label, version, "voice")+":"+escape(phone.Value))
+		}
+		if card.Birthday != nil {
+			writeLine(bw, "BDAY:"+card.Birthday.Format("2006-01-02"))
+		}
+		if len(card.Categories) > 0 {
+			escaped := make([]string, len(card.Categories))
+			for i, c := range card.Categories {
+				escaped[i] = escape(c)
+			}
+			writeLine(bw, "CATEGORIES:"+strings.Join(escaped, ","))
+		}
+		if card.Notes != "" {
+			writeLine(bw, "NOTE:"+escape(card.Notes))
+		}
+		writeLine(bw, "END:VCARD")
+	}
+	return bw.Flush()
+}
+
+// structuredName splits a display name into N's family and given parts
+func structuredName(name string) string {
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return escape(name) + ";;;;"
+	}
+	family := fields[len(fields)-1]
+	given := strings.Join(fields[:len(fields)-1], " ")
+	return escape(family) + ";" + escape(given) + ";;;"
+}
+
+func typeParam(label, version, fallback string) string {
+	if label == "" {
+		if version == Version3 {
+			return ";TYPE=" + fallback
+		}
+		return ""
+	}
+	return ";TYPE=" + strings.ToLower(strings.NewReplacer(";", "", ":", "", ",", "").Replace(label))
+}
+
+// writeLine writes a content line folded at 75 octets without splitting UTF-8 sequences
+func writeLine(w *bufio.Writer, s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // Continuation lines start with a space
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
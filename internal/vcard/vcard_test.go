@@ -0,0 +1,158 @@
+package vcard
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	birthday := time.Date(1990, 2, 28, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		card Card
+	}{
+		{
+			name: "name only",
+			card: Card{Name: "Prince"},
+		},
+		{
+			name: "every field",
+			card: Card{
+				UID:        "abc-123",
+				Name:       "Ada Lovelace",
+				Emails:     []Field{{Label: "work", Value: "ada@example.com"}, {Value: "ada@home.example"}},
+				Phones:     []Field{{Label: "cell", Value: "+44 20 7946 0000"}},
+				Notes:      "Met at the conference",
+				Birthday:   &birthday,
+				Categories: []string{"friends", "work"},
+			},
+		},
+		{
+			name: "special characters are escaped",
+			card: Card{
+				Name:       `Jean-Luc O'Brien, Jr.`,
+				Notes:      "Line one\nsemi; colon: comma, back\\slash",
+				Categories: []string{"a,b", "c;d"},
+			},
+		},
+		{
+			name: "long values are folded without splitting characters",
+			card: Card{
+				Name:  "Zoë Ångström",
+				Notes: strings.Repeat("héllo wörld ", 20),
+			},
+		},
+	}
+
+	for _, version := range []string{Version3, Version4} {
+		for _, tt := range tests {
+			t.Run(version+"/"+tt.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Write(&buf, []Card{tt.card}, version); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				for _, l := range strings.Split(buf.String(), "\r\n") {
+					if len(l) > 75 {
+						t.Errorf("line longer than 75 octets: %q", l)
+					}
+				}
+
+				cards, err := Parse(&buf)
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if len(cards) != 1 {
+					t.Fatalf("Parse() returned %d cards, want 1", len(cards))
+				}
+				if !reflect.DeepEqual(cards[0], tt.card) {
+					t.Errorf("round trip = %+v, want %+v", cards[0], tt.card)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteUnsupportedVersion(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, []Card{{Name: "A"}}, "2.1"); err == nil {
+		t.Error("Write() with version 2.1 succeeded, want an error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	birthday := time.Date(1985, 7, 4, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		input string
+		want  []Card
+	}{
+		{
+			name: "vCard 2.1 with bare types and quoted-printable",
+			input: "BEGIN:VCARD\r\nVERSION:2.1\r\nN:Smith;John;;;\r\n" +
+				"TEL;CELL;PREF:555-0100\r\n" +
+				"NOTE;ENCODING=QUOTED-PRINTABLE:Caf=C3=A9 =\r\nregular\r\n" +
+				"END:VCARD\r\n",
+			want: []Card{{
+				Name:   "John Smith",
+				Phones: []Field{{Label: "cell", Value: "555-0100"}},
+				Notes:  "Café regular",
+			}},
+		},
+		{
+			name: "vCard 4.0 with URIs, groups and a compact birthday",
+			input: "BEGIN:VCARD\nVERSION:4.0\nFN:Grace Hopper\n" +
+				"item1.EMAIL;TYPE=\"work,pref\":mailto:grace@example.com\n" +
+				"TEL;VALUE=uri:tel:+1-555-0101\n" +
+				"BDAY:19850704T000000Z\n" +
+				"END:VCARD\n",
+			want: []Card{{
+				Name:     "Grace Hopper",
+				Emails:   []Field{{Label: "work", Value: "grace@example.com"}},
+				Phones:   []Field{{Value: "+1-555-0101"}},
+				Birthday: &birthday,
+			}},
+		},
+		{
+			name:  "birthday without a year is dropped",
+			input: "BEGIN:VCARD\nVERSION:4.0\nFN:Alan\nBDAY:--0623\nEND:VCARD\n",
+			want:  []Card{{Name: "Alan"}},
+		},
+		{
+			name:  "folded lines are joined",
+			input: "BEGIN:VCARD\nVERSION:3.0\nFN:Katherine\n  Johnson\nNOTE:one\n two\nEND:VCARD\n",
+			want:  []Card{{Name: "Katherine Johnson", Notes: "onetwo"}},
+		},
+		{
+			name: "cards without a name are skipped",
+			input: "BEGIN:VCARD\nVERSION:3.0\nEMAIL:nobody@example.com\nEND:VCARD\n" +
+				"BEGIN:VCARD\nVERSION:3.0\nFN:Somebody\nEND:VCARD\n",
+			want: []Card{{Name: "Somebody"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cards, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !reflect.DeepEqual(cards, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", cards, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse(strings.NewReader("FN:Nobody\n")); !errors.Is(err, ErrNoCards) {
+		t.Errorf("Parse() without cards error = %v, want ErrNoCards", err)
+	}
+	if _, err := Parse(strings.NewReader("BEGIN:VCARD\nFN Nobody\nEND:VCARD\n")); err == nil {
+		t.Error("Parse() of a line without ':' succeeded, want an error")
+	}
+}
@@ -0,0 +1,17 @@
+-- Create mood_entries table
+CREATE TABLE IF NOT EXISTS mood_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    mood INTEGER NOT NULL CHECK (mood BETWEEN 1 AND 5),
+    energy INTEGER NOT NULL CHECK (energy BETWEEN 1 AND 5),
+    emoji VARCHAR(32),
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(user_id, date)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_mood_entries_user_id ON mood_entries(user_id);
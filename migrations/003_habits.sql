@@ -0,0 +1,49 @@
+-- Create habits table
+CREATE TABLE IF NOT EXISTS habits (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    system_key VARCHAR(32),
+    name VARCHAR(255) NOT NULL,
+    icon VARCHAR(64),
+    unit VARCHAR(64),
+    kind VARCHAR(16) NOT NULL DEFAULT 'counter' CHECK (kind IN ('counter', 'boolean')),
+    period VARCHAR(16) NOT NULL DEFAULT 'daily' CHECK (period IN ('daily', 'weekly')),
+    target INTEGER NOT NULL DEFAULT 1 CHECK (target > 0),
+    archived BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create habit_check_ins table
+CREATE TABLE IF NOT EXISTS habit_check_ins (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    habit_id INTEGER NOT NULL REFERENCES habits(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    value INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(habit_id, date)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_habits_user_id ON habits(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_habits_user_system_key ON habits(user_id, system_key) WHERE system_key <> '';
+CREATE INDEX IF NOT EXISTS idx_habit_check_ins_user_id ON habit_check_ins(user_id);
+
+-- Give every existing user the default water habit
+INSERT INTO habits (user_id, system_key, name, icon, unit, kind, period, target)
+SELECT u.id, 'water', 'Water', 'fa-glass-whiskey', 'glasses', 'counter', 'daily', 10
+FROM users u
+WHERE NOT EXISTS (
+    SELECT 1 FROM habits h WHERE h.user_id = u.id AND h.system_key = 'water'
+);
+
+-- Carry water intake history over as check-ins of the water habit
+INSERT INTO habit_check_ins (user_id, habit_id, date, value)
+SELECT w.user_id, h.id, w.date, w.glasses
+FROM water_intake w
+JOIN habits h ON h.user_id = w.user_id AND h.system_key = 'water'
+ON CONFLICT (habit_id, date) DO NOTHING;
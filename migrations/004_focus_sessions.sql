@@ -0,0 +1,27 @@
+-- Create focus_sessions table
+CREATE TABLE IF NOT EXISTS focus_sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    todo_item_id INTEGER REFERENCES todo_items(id) ON DELETE SET NULL,
+    priority_id INTEGER REFERENCES priorities(id) ON DELETE SET NULL,
+    label VARCHAR(255),
+    status VARCHAR(16) NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'paused', 'stopped')),
+    planned_minutes INTEGER NOT NULL DEFAULT 0,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    resumed_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    duration_seconds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Only a user's latest session may still be active before that is enforced below
+UPDATE focus_sessions SET status = 'stopped', ended_at = COALESCE(ended_at, CURRENT_TIMESTAMP)
+WHERE status <> 'stopped' AND deleted_at IS NULL
+  AND id NOT IN (SELECT MAX(id) FROM focus_sessions WHERE status <> 'stopped' AND deleted_at IS NULL GROUP BY user_id);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_focus_sessions_user_id ON focus_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_focus_sessions_started_at ON focus_sessions(user_id, started_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_focus_sessions_user_active ON focus_sessions(user_id) WHERE status <> 'stopped' AND deleted_at IS NULL;
@@ -0,0 +1,20 @@
+-- Create time_blocks table
+CREATE TABLE IF NOT EXISTS time_blocks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    start_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    end_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    color VARCHAR(16),
+    todo_item_id INTEGER REFERENCES todo_items(id) ON DELETE SET NULL,
+    priority_id INTEGER REFERENCES priorities(id) ON DELETE SET NULL,
+    contact_id INTEGER REFERENCES contacts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (end_at > start_at)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_time_blocks_user_date ON time_blocks(user_id, date);
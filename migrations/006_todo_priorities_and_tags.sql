@@ -0,0 +1,26 @@
+-- Add priority level and manual position to todo_items
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS priority_level INTEGER NOT NULL DEFAULT 4 CHECK (priority_level BETWEEN 1 AND 4);
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Create tags table
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    color VARCHAR(16),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create todo_item_tags join table
+CREATE TABLE IF NOT EXISTS todo_item_tags (
+    todo_item_id INTEGER NOT NULL REFERENCES todo_items(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_item_id, tag_id)
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags(user_id, LOWER(name)) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_todo_item_tags_tag_id ON todo_item_tags(tag_id);
+CREATE INDEX IF NOT EXISTS idx_todo_items_user_priority ON todo_items(user_id, priority_level);
@@ -0,0 +1,18 @@
+-- Allow todos to opt out of completing themselves with their subtasks
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS manual_completion BOOLEAN DEFAULT FALSE;
+
+-- Create subtasks table
+CREATE TABLE IF NOT EXISTS subtasks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    todo_item_id INTEGER NOT NULL REFERENCES todo_items(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    completed BOOLEAN DEFAULT FALSE,
+    position DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_subtasks_todo_item_id ON subtasks(todo_item_id);
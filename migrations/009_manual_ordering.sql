@@ -0,0 +1,16 @@
+-- Add manual position to priorities and contacts
+ALTER TABLE priorities ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS position DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- Seed positions from creation order so existing lists keep their order.
+-- Only users whose rows are all still unpositioned are seeded, so re-running
+-- the migration never undoes a manual reorder.
+UPDATE todo_items SET position = id WHERE user_id IN (SELECT user_id FROM todo_items GROUP BY user_id HAVING MIN(position) = 0 AND MAX(position) = 0);
+UPDATE priorities SET position = id WHERE user_id IN (SELECT user_id FROM priorities GROUP BY user_id HAVING MIN(position) = 0 AND MAX(position) = 0);
+UPDATE contacts SET position = id WHERE user_id IN (SELECT user_id FROM contacts GROUP BY user_id HAVING MIN(position) = 0 AND MAX(position) = 0);
+UPDATE projects SET position = id WHERE user_id IN (SELECT user_id FROM projects GROUP BY user_id HAVING MIN(position) = 0 AND MAX(position) = 0);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_todo_items_user_position ON todo_items(user_id, position);
+CREATE INDEX IF NOT EXISTS idx_priorities_user_date_position ON priorities(user_id, date, position);
+CREATE INDEX IF NOT EXISTS idx_contacts_user_date_position ON contacts(user_id, date, position);
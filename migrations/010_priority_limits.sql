@@ -0,0 +1,11 @@
+-- Per-user cap on priorities per day
+ALTER TABLE users ADD COLUMN IF NOT EXISTS max_daily_priorities INTEGER NOT NULL DEFAULT 3;
+
+-- Link priorities to promoted todos
+ALTER TABLE priorities ADD COLUMN IF NOT EXISTS todo_item_id INTEGER REFERENCES todo_items(id) ON DELETE CASCADE;
+
+-- Make sure priorities has the soft-delete column the model uses
+ALTER TABLE priorities ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_priorities_user_date_todo ON priorities(user_id, date, todo_item_id) WHERE todo_item_id IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,119 @@
+-- Create people table
+CREATE TABLE IF NOT EXISTS people (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    notes TEXT,
+    birthday DATE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create person_emails table
+CREATE TABLE IF NOT EXISTS person_emails (
+    id SERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    label VARCHAR(32),
+    address VARCHAR(255) NOT NULL
+);
+
+-- Create person_phones table
+CREATE TABLE IF NOT EXISTS person_phones (
+    id SERIAL PRIMARY KEY,
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    label VARCHAR(32),
+    number VARCHAR(64) NOT NULL
+);
+
+-- Create person_tags join table
+CREATE TABLE IF NOT EXISTS person_tags (
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (person_id, tag_id)
+);
+
+-- Create interactions table
+CREATE TABLE IF NOT EXISTS interactions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    contact_id INTEGER REFERENCES contacts(id) ON DELETE SET NULL,
+    type VARCHAR(16) NOT NULL,
+    date DATE NOT NULL,
+    notes TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Make sure contacts has every column the follow-up reminder model uses
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS type VARCHAR(16);
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS completed BOOLEAN DEFAULT FALSE;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS person_id INTEGER REFERENCES people(id) ON DELETE SET NULL;
+
+-- Map free-form reminder types onto the supported channels
+UPDATE contacts SET type = CASE LOWER(TRIM(COALESCE(type, '')))
+    WHEN 'call' THEN 'Call'
+    WHEN 'phone' THEN 'Call'
+    WHEN 'email' THEN 'Email'
+    WHEN 'e-mail' THEN 'Email'
+    WHEN 'text' THEN 'Text'
+    WHEN 'sms' THEN 'Text'
+    WHEN 'meeting' THEN 'Meeting'
+    ELSE 'Other'
+END
+WHERE type IS NULL OR type NOT IN ('Call', 'Email', 'Text', 'Meeting', 'Other');
+
+ALTER TABLE contacts ALTER COLUMN type SET NOT NULL;
+ALTER TABLE contacts DROP CONSTRAINT IF EXISTS chk_contacts_type;
+ALTER TABLE contacts ADD CONSTRAINT chk_contacts_type CHECK (type IN ('Call', 'Email', 'Text', 'Meeting', 'Other'));
+
+-- Create a person for every name used in existing reminders
+INSERT INTO people (user_id, name)
+SELECT DISTINCT ON (c.user_id, LOWER(c.name)) c.user_id, c.name
+FROM contacts c
+WHERE c.person_id IS NULL
+  AND NOT EXISTS (
+    SELECT 1 FROM people p
+    WHERE p.user_id = c.user_id AND LOWER(p.name) = LOWER(c.name) AND p.deleted_at IS NULL
+  )
+ORDER BY c.user_id, LOWER(c.name), c.id;
+
+UPDATE contacts c SET person_id = p.id
+FROM people p
+WHERE c.person_id IS NULL
+  AND p.user_id = c.user_id AND LOWER(p.name) = LOWER(c.name) AND p.deleted_at IS NULL;
+
+-- Keep the email addresses and phone numbers stored on reminders
+INSERT INTO person_emails (person_id, address)
+SELECT DISTINCT c.person_id, c.email
+FROM contacts c
+WHERE c.person_id IS NOT NULL AND COALESCE(c.email, '') <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM person_emails e WHERE e.person_id = c.person_id AND LOWER(e.address) = LOWER(c.email)
+  );
+
+INSERT INTO person_phones (person_id, number)
+SELECT DISTINCT c.person_id, c.phone
+FROM contacts c
+WHERE c.person_id IS NOT NULL AND COALESCE(c.phone, '') <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM person_phones ph WHERE ph.person_id = c.person_id AND ph.number = c.phone
+  );
+
+-- Completed reminders become the start of each person's interaction history
+INSERT INTO interactions (user_id, person_id, contact_id, type, date, notes)
+SELECT c.user_id, c.person_id, c.id, c.type, c.date, COALESCE(c.description, '')
+FROM contacts c
+WHERE c.completed AND c.person_id IS NOT NULL AND c.deleted_at IS NULL
+  AND NOT EXISTS (SELECT 1 FROM interactions i WHERE i.contact_id = c.id);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_people_user_id ON people(user_id);
+CREATE INDEX IF NOT EXISTS idx_person_emails_person_id ON person_emails(person_id);
+CREATE INDEX IF NOT EXISTS idx_person_phones_person_id ON person_phones(person_id);
+CREATE INDEX IF NOT EXISTS idx_contacts_person_id ON contacts(person_id);
+CREATE INDEX IF NOT EXISTS idx_interactions_person_date ON interactions(person_id, date);
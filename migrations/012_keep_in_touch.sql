@@ -0,0 +1,10 @@
+-- Keep-in-touch cadence per person
+ALTER TABLE people ADD COLUMN IF NOT EXISTS cadence_days INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE people ADD COLUMN IF NOT EXISTS cadence_type VARCHAR(16);
+
+-- Mark reminders the planner generated
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS source VARCHAR(32);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_people_cadence ON people(user_id) WHERE cadence_days > 0 AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_contacts_person_pending ON contacts(person_id) WHERE NOT completed AND deleted_at IS NULL;
@@ -0,0 +1,24 @@
+-- Per-user timezone and how far ahead to show upcoming occasions
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS occasion_lead_days INTEGER NOT NULL DEFAULT 14;
+
+-- Create significant_dates table
+CREATE TABLE IF NOT EXISTS significant_dates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    person_id INTEGER NOT NULL REFERENCES people(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    label VARCHAR(255),
+    date DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Remember which occurrence an occasion reminder was made for, so it is made once
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS occasion_key VARCHAR(64);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_significant_dates_person_id ON significant_dates(person_id);
+CREATE INDEX IF NOT EXISTS idx_significant_dates_user_id ON significant_dates(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_person_occasion ON contacts(person_id, occasion_key) WHERE occasion_key IS NOT NULL AND occasion_key <> '';
@@ -0,0 +1,5 @@
+-- Secret token in each user's calendar feed URL
+ALTER TABLE users ADD COLUMN IF NOT EXISTS calendar_token VARCHAR(64);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_calendar_token ON users(calendar_token);
@@ -0,0 +1,9 @@
+-- Remember which calendar entry an imported item came from, so re-importing updates it
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS external_uid VARCHAR(512);
+ALTER TABLE time_blocks ADD COLUMN IF NOT EXISTS external_uid VARCHAR(512);
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS external_uid VARCHAR(512);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_todo_items_user_external_uid ON todo_items(user_id, external_uid) WHERE external_uid IS NOT NULL AND external_uid <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_blocks_user_external_uid ON time_blocks(user_id, external_uid) WHERE external_uid IS NOT NULL AND external_uid <> '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_contacts_user_external_uid ON contacts(user_id, external_uid) WHERE external_uid IS NOT NULL AND external_uid <> '';
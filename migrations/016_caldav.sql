@@ -0,0 +1,8 @@
+-- Resource names CalDAV clients chose for the todos they created
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS caldav_name VARCHAR(512);
+
+-- Make sure todo_items has the soft-delete column the model uses
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_todo_items_user_caldav_name ON todo_items(user_id, caldav_name) WHERE caldav_name IS NOT NULL AND caldav_name <> '' AND deleted_at IS NULL;
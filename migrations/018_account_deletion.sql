@@ -0,0 +1,11 @@
+-- When a deleted account is erased for good, until then it can be restored
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_at TIMESTAMP WITH TIME ZONE;
+
+-- Make sure users has the soft-delete column the model uses
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Accounts deleted before there was a grace period get the default one
+UPDATE users SET purge_at = deleted_at + INTERVAL '30 days' WHERE deleted_at IS NOT NULL AND purge_at IS NULL;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_users_purge_at ON users(purge_at);
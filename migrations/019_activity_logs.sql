@@ -0,0 +1,19 @@
+-- Create activity_logs table
+CREATE TABLE IF NOT EXISTS activity_logs (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id INTEGER,
+    source VARCHAR(16) NOT NULL DEFAULT '',
+    entity VARCHAR(32) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    changes JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The log is append-only, rows go only when their user is purged
+CREATE OR REPLACE RULE activity_logs_no_update AS ON UPDATE TO activity_logs DO INSTEAD NOTHING;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_activity_logs_user_id ON activity_logs(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_activity_logs_entity ON activity_logs(user_id, entity, entity_id);
@@ -0,0 +1,15 @@
+-- Create daily_reviews table
+CREATE TABLE IF NOT EXISTS daily_reviews (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    reflection TEXT NOT NULL DEFAULT '',
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE(user_id, date)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_daily_reviews_user_id ON daily_reviews(user_id);
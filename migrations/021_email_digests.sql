@@ -0,0 +1,12 @@
+-- Morning and evening emails, both off until the user turns them on
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_morning BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_evening BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_morning_at INTEGER NOT NULL DEFAULT 420 CHECK (digest_morning_at BETWEEN 0 AND 1439);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_evening_at INTEGER NOT NULL DEFAULT 1200 CHECK (digest_evening_at BETWEEN 0 AND 1439);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_token VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_morning_sent DATE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS digest_evening_sent DATE;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_digest_token ON users(digest_token);
+CREATE INDEX IF NOT EXISTS idx_users_digest ON users(id) WHERE digest_morning OR digest_evening;
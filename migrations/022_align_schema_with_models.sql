@@ -0,0 +1,22 @@
+-- Users log in with password, which accounts created through Google leave empty.
+-- Hashes stored by the initial schema are carried over, read through JSON so that
+-- this still runs once password_hash is gone.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE users u SET password = to_jsonb(u) ->> 'password_hash' WHERE u.password = '' AND to_jsonb(u) ->> 'password_hash' IS NOT NULL;
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS google_id VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE;
+
+-- Todos are planned for a day, and existing ones stay on the day they were created
+ALTER TABLE todo_items ADD COLUMN IF NOT EXISTS due_date DATE;
+UPDATE todo_items SET due_date = COALESCE(created_at::date, CURRENT_DATE) WHERE due_date IS NULL;
+
+-- Priorities are checked off like todos
+ALTER TABLE priorities ADD COLUMN IF NOT EXISTS completed BOOLEAN DEFAULT FALSE;
+
+-- Make sure thoughts and water_intake have the soft-delete column the models use
+ALTER TABLE thoughts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE water_intake ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id);
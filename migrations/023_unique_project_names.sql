@@ -0,0 +1,10 @@
+-- Project names are unique per user, ignoring case, like tag names. Existing
+-- duplicates keep their name with the project ID added, the oldest unchanged.
+UPDATE projects p SET name = p.name || ' (' || p.id || ')'
+WHERE p.deleted_at IS NULL AND EXISTS (
+    SELECT 1 FROM projects q
+    WHERE q.user_id = p.user_id AND LOWER(q.name) = LOWER(p.name) AND q.deleted_at IS NULL AND q.id < p.id
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_projects_user_name ON projects(user_id, LOWER(name)) WHERE deleted_at IS NULL;
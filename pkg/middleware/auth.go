@@ -1,10 +1,14 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/himanshu/daily-planner/internal/auth"
+	"github.com/himanshu/daily-planner/internal/repository"
+	"gorm.io/gorm"
 )
 
 func SessionAuth() gin.HandlerFunc {
@@ -36,6 +40,50 @@ func SessionAuth() gin.HandlerFunc {
 	}
 }
 
+// BasicAuth authenticates with an HTTP Basic username (or email) and
+// password, for clients such as CalDAV apps that can't log in through the web
+func BasicAuth(db *repository.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		login, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="Daily Planner", charset="UTF-8"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		user, err := auth.CheckCredentials(db, login, password)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="Daily Planner", charset="UTF-8"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Next()
+	}
+}
+
+// ActiveAccount turns away sessions whose account has been deleted since
+// they logged in
+func ActiveAccount(db *repository.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, _ := c.Get("user_id")
+		id, _ := userID.(uint)
+		_, err := db.FindUserByID(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.SetCookie("auth_token", "", -1, "/", "", false, true)
+			c.Redirect(http.StatusFound, "/auth/login")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Next()
+	}
+}
+
 func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
@@ -43,7 +91,8 @@ func CORS() gin.HandlerFunc {
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
-		if c.Request.Method == "OPTIONS" {
+		// CalDAV clients use OPTIONS to discover what the server supports
+		if c.Request.Method == "OPTIONS" && !strings.HasPrefix(c.Request.URL.Path, "/caldav") {
 			c.AbortWithStatus(204)
 			return
 		}
@@ -60,7 +109,13 @@ func isPublicRoute(path string) bool {
 		"/auth/reset-password",
 		"/auth/google/login",
 		"/auth/google/callback",
+		"/auth/restore-account",
 		"/static/",
+		"/calendar/",
+		"/exports/",
+		"/digest/unsubscribe/",
+		"/caldav",
+		"/.well-known/caldav",
 	}
 
 	for _, route := range publicRoutes {
@@ -3,6 +3,11 @@ function addTodo() {
     const title = document.getElementById('todoTitle').value;
     const description = document.getElementById('todoDescription').value;
     const dueDate = document.getElementById('todoDueDate').value;
+    const priority = parseInt(document.getElementById('todoPriority').value, 10);
+    const tagIds = Array.from(document.querySelectorAll('#todoTags input:checked'))
+        .map(input => parseInt(input.value, 10));
+    const projectSelect = document.getElementById('todoProject');
+    const projectId = projectSelect && projectSelect.value ? parseInt(projectSelect.value, 10) : null;
 
     fetch('/planner/todos', {
         method: 'POST',
@@ -13,6 +18,9 @@ function addTodo() {
             title: title,
             description: description,
             dueDate: dueDate,
+            priority: priority,
+            tagIds: tagIds,
+            projectId: projectId,
         }),
     })
     .then(response => response.json())
@@ -54,23 +62,105 @@ function updateTodo(id, completed) {
 
 // Delete Todo
 function deleteTodo(id) {
-    if (confirm('Are you sure you want to delete this todo?')) {
-        fetch(`/planner/todos/${id}`, {
-            method: 'DELETE',
-        })
-        .then(response => response.json())
-        .then(data => {
-            if (data.error) {
-                alert(data.error);
-            } else {
-                location.reload();
-            }
-        })
-        .catch(error => {
-            console.error('Error:', error);
-            alert('Failed to delete todo');
-        });
+    fetch(`/planner/todos/${id}`, {
+        method: 'DELETE',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            offerUndo('todos', id, 'Todo deleted');
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to delete todo');
+    });
+}
+
+// Add Subtask
+function addSubtask(todoId) {
+    const title = prompt('Subtask title');
+    if (!title) {
+        return;
     }
+
+    fetch(`/planner/todos/${todoId}/subtasks`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            title: title,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to add subtask');
+    });
+}
+
+// Update Subtask
+function updateSubtask(todoId, id, completed) {
+    fetch(`/planner/todos/${todoId}/subtasks/${id}`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            completed: completed,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to update subtask');
+    });
+}
+
+// Add Project
+function addProject() {
+    const name = document.getElementById('projectName').value;
+    const color = document.getElementById('projectColor').value;
+
+    fetch('/planner/projects', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            name: name,
+            color: color,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to add project');
+    });
 }
 
 // Add Priority
@@ -127,23 +217,84 @@ function updatePriority(id, completed) {
 
 // Delete Priority
 function deletePriority(id) {
-    if (confirm('Are you sure you want to delete this priority?')) {
-        fetch(`/planner/priorities/${id}`, {
-            method: 'DELETE',
-        })
-        .then(response => response.json())
-        .then(data => {
-            if (data.error) {
-                alert(data.error);
-            } else {
-                location.reload();
-            }
-        })
-        .catch(error => {
-            console.error('Error:', error);
-            alert('Failed to delete priority');
-        });
-    }
+    fetch(`/planner/priorities/${id}`, {
+        method: 'DELETE',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            offerUndo('priorities', id, 'Priority deleted');
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to delete priority');
+    });
+}
+
+// Promote a todo into today's priorities
+function promoteTodo(id) {
+    fetch(`/planner/todos/${id}/promote`, {
+        method: 'POST',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to promote todo');
+    });
+}
+
+// Demote a todo from today's priorities
+function demoteTodo(id) {
+    fetch(`/planner/todos/${id}/promote`, {
+        method: 'DELETE',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to demote todo');
+    });
+}
+
+// Log an interaction with a person, e.g. from the keep-in-touch list
+function logInteraction(personId, type) {
+    fetch(`/planner/people/${personId}/interactions`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            type: type,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to log interaction');
+    });
 }
 
 // Add Contact
@@ -202,23 +353,21 @@ function updateContact(id, completed) {
 
 // Delete Contact
 function deleteContact(id) {
-    if (confirm('Are you sure you want to delete this contact?')) {
-        fetch(`/planner/contacts/${id}`, {
-            method: 'DELETE',
-        })
-        .then(response => response.json())
-        .then(data => {
-            if (data.error) {
-                alert(data.error);
-            } else {
-                location.reload();
-            }
-        })
-        .catch(error => {
-            console.error('Error:', error);
-            alert('Failed to delete contact');
-        });
-    }
+    fetch(`/planner/contacts/${id}`, {
+        method: 'DELETE',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            offerUndo('contacts', id, 'Contact deleted');
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to delete contact');
+    });
 }
 
 // Update Water Intake
@@ -246,6 +395,189 @@ function updateWaterIntake(glasses) {
     });
 }
 
+// Add Habit
+function addHabit() {
+    const name = document.getElementById('habitName').value;
+    const icon = document.getElementById('habitIcon').value;
+    const kind = document.getElementById('habitKind').value;
+    const period = document.getElementById('habitPeriod').value;
+    const target = parseInt(document.getElementById('habitTarget').value, 10);
+    const unit = document.getElementById('habitUnit').value;
+
+    fetch('/planner/habits', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            name: name,
+            icon: icon,
+            kind: kind,
+            period: period,
+            target: target,
+            unit: unit,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to add habit');
+    });
+}
+
+// Check In Habit
+function checkInHabit(id, value) {
+    fetch(`/planner/habits/${id}/check-ins`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            value: value,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to check in habit');
+    });
+}
+
+// Start Focus Session
+function startFocus(link) {
+    fetch('/planner/focus-sessions/start', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(link),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to start focus session');
+    });
+}
+
+// Pause, Resume or Stop Focus Session
+function focusAction(id, action) {
+    fetch(`/planner/focus-sessions/${id}/${action}`, {
+        method: 'POST',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to update focus session');
+    });
+}
+
+// Tick the active focus timer
+document.addEventListener('DOMContentLoaded', () => {
+    const active = document.getElementById('activeFocus');
+    const timer = document.getElementById('focusTimer');
+    if (!active || !timer) {
+        return;
+    }
+
+    let elapsed = parseInt(active.dataset.elapsed, 10);
+    const render = () => {
+        const minutes = Math.floor(elapsed / 60);
+        const seconds = String(elapsed % 60).padStart(2, '0');
+        timer.textContent = `${minutes}:${seconds}`;
+    };
+
+    render();
+    if (active.dataset.status === 'running') {
+        setInterval(() => {
+            elapsed++;
+            render();
+        }, 1000);
+    }
+});
+
+// Add Time Block
+function addTimeBlock() {
+    const todo = document.getElementById('timeBlockTodo').value;
+    const block = {
+        title: document.getElementById('timeBlockTitle').value,
+        start: document.getElementById('timeBlockStart').value,
+        end: document.getElementById('timeBlockEnd').value,
+        allowOverlap: document.getElementById('timeBlockAllowOverlap').checked,
+    };
+    if (todo) {
+        block.todoId = parseInt(todo, 10);
+    }
+
+    fetch('/planner/time-blocks', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify(block),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to add time block');
+    });
+}
+
+// Delete Time Block
+function deleteTimeBlock(id) {
+    if (confirm('Are you sure you want to delete this time block?')) {
+        fetch(`/planner/time-blocks/${id}`, {
+            method: 'DELETE',
+        })
+        .then(response => response.json())
+        .then(data => {
+            if (data.error) {
+                alert(data.error);
+            } else {
+                location.reload();
+            }
+        })
+        .catch(error => {
+            console.error('Error:', error);
+            alert('Failed to delete time block');
+        });
+    }
+}
+
 // Add Thought
 function addThought() {
     const content = document.getElementById('thoughtContent').value;
@@ -290,4 +622,350 @@ function generateThought() {
         console.error('Error:', error);
         alert('Failed to generate thought');
     });
-} 
\ No newline at end of file
+}
+
+// Save Mood
+function saveMood() {
+    const mood = parseInt(document.getElementById('moodValue').value, 10);
+    const energy = parseInt(document.getElementById('energyValue').value, 10);
+    const emoji = document.getElementById('moodEmoji').value;
+    const notes = document.getElementById('moodNotes').value;
+
+    fetch('/planner/mood', {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            mood: mood,
+            energy: energy,
+            emoji: emoji,
+            notes: notes,
+        }),
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to save mood');
+    });
+}
+
+// Drag and drop reordering; only the moved item and its new neighbours are sent
+function enableReorder(listId, endpoint) {
+    const list = document.getElementById(listId);
+    if (!list) {
+        return;
+    }
+
+    let dragged = null;
+
+    list.addEventListener('dragstart', (event) => {
+        dragged = event.target.closest('li[data-id]');
+        if (dragged) {
+            dragged.classList.add('dragging');
+            event.dataTransfer.effectAllowed = 'move';
+        }
+    });
+
+    list.addEventListener('dragover', (event) => {
+        if (!dragged) {
+            return;
+        }
+        event.preventDefault();
+        const target = event.target.closest('li[data-id]');
+        if (!target || target === dragged || target.parentElement !== list) {
+            return;
+        }
+        const rect = target.getBoundingClientRect();
+        const after = event.clientY > rect.top + rect.height / 2;
+        list.insertBefore(dragged, after ? target.nextSibling : target);
+    });
+
+    list.addEventListener('dragend', () => {
+        if (!dragged) {
+            return;
+        }
+        const item = dragged;
+        dragged = null;
+        item.classList.remove('dragging');
+
+        const prev = item.previousElementSibling;
+        const next = item.nextElementSibling;
+        if (!prev && !next) {
+            return;
+        }
+
+        fetch(`/planner/${endpoint}/${item.dataset.id}/move`, {
+            method: 'PUT',
+            headers: {
+                'Content-Type': 'application/json',
+            },
+            body: JSON.stringify({
+                afterId: prev ? parseInt(prev.dataset.id, 10) : null,
+                beforeId: next ? parseInt(next.dataset.id, 10) : null,
+            }),
+        })
+        .then(response => response.json())
+        .then(data => {
+            if (data.error) {
+                alert(data.error);
+                location.reload();
+                return;
+            }
+            list.querySelectorAll('.priority-rank').forEach((badge, i) => {
+                badge.textContent = i + 1;
+            });
+        })
+        .catch(error => {
+            console.error('Error:', error);
+            alert('Failed to save order');
+            location.reload();
+        });
+    });
+}
+
+// Request a "download my data" archive and download it once the server has built it
+function requestDataExport() {
+    const link = document.getElementById('dataExportLink');
+    const label = link.innerHTML;
+    link.innerHTML = '<i class="fas fa-spinner fa-spin"></i> Preparing export...';
+
+    const finish = () => {
+        link.innerHTML = label;
+    };
+
+    const poll = (id) => {
+        fetch(`/planner/exports/${id}`)
+        .then(response => response.json())
+        .then(data => {
+            if (data.status === 'ready') {
+                finish();
+                window.location = data.downloadUrl;
+            } else if (data.status === 'failed' || data.error) {
+                finish();
+                alert(data.error || 'Export failed');
+            } else {
+                setTimeout(() => poll(id), 3000);
+            }
+        })
+        .catch(error => {
+            console.error('Error:', error);
+            finish();
+            alert('Failed to check on export');
+        });
+    };
+
+    fetch('/planner/exports', {
+        method: 'POST',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            finish();
+            alert(data.error);
+        } else {
+            poll(data.id);
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        finish();
+        alert('Failed to request export');
+    });
+}
+
+// Remember a deletion across the reload so the page can offer to undo it
+function offerUndo(type, id, message) {
+    sessionStorage.setItem('undo', JSON.stringify({ type, id, message }));
+    location.reload();
+}
+
+// Show the undo toast for the deletion made just before the page loaded
+function showUndo() {
+    const pending = JSON.parse(sessionStorage.getItem('undo') || 'null');
+    sessionStorage.removeItem('undo');
+    if (!pending) {
+        return;
+    }
+
+    document.getElementById('undoMessage').textContent = pending.message;
+    document.getElementById('undoButton').onclick = () => restoreFromTrash(pending.type, pending.id);
+    bootstrap.Toast.getOrCreateInstance(document.getElementById('undoToast'), { delay: 10000 }).show();
+}
+
+// Bring an item back from the trash
+function restoreFromTrash(type, id) {
+    fetch(`/planner/trash/${type}/${id}/restore`, {
+        method: 'POST',
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to restore item');
+    });
+}
+
+// Delete an item in the trash for good
+function deleteFromTrash(type, id) {
+    if (confirm('Delete this for good? It can\'t be restored.')) {
+        fetch(`/planner/trash/${type}/${id}`, {
+            method: 'DELETE',
+        })
+        .then(response => response.json())
+        .then(data => {
+            if (data.error) {
+                alert(data.error);
+            } else {
+                loadTrash();
+            }
+        })
+        .catch(error => {
+            console.error('Error:', error);
+            alert('Failed to delete item');
+        });
+    }
+}
+
+// Delete everything in the trash for good
+function emptyTrash() {
+    if (confirm('Delete everything in the trash for good?')) {
+        fetch('/planner/trash', {
+            method: 'DELETE',
+        })
+        .then(response => response.json())
+        .then(data => {
+            if (data.error) {
+                alert(data.error);
+            } else {
+                loadTrash();
+            }
+        })
+        .catch(error => {
+            console.error('Error:', error);
+            alert('Failed to empty trash');
+        });
+    }
+}
+
+// Fill the trash modal with the deleted items
+function loadTrash() {
+    const list = document.getElementById('trashList');
+    const labels = { todos: 'Todo', priorities: 'Priority', contacts: 'Follow-up' };
+
+    fetch('/planner/trash')
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+            return;
+        }
+        document.getElementById('trashRetention').textContent = data.retentionDays;
+        list.innerHTML = '';
+        if (data.items.length === 0) {
+            list.innerHTML = '<li class="list-group-item text-muted">The trash is empty</li>';
+            return;
+        }
+        data.items.forEach(item => {
+            const li = document.createElement('li');
+            li.className = 'list-group-item d-flex justify-content-between align-items-center';
+
+            const text = document.createElement('div');
+            const title = document.createElement('span');
+            title.textContent = item.title;
+            const meta = document.createElement('small');
+            meta.className = 'd-block text-muted';
+            meta.textContent = `${labels[item.type]} · deleted ${new Date(item.deletedAt).toLocaleDateString()}`;
+            text.append(title, meta);
+
+            const actions = document.createElement('div');
+            actions.className = 'btn-group btn-group-sm';
+            actions.innerHTML = '<button class="btn btn-outline-success" title="Restore"><i class="fas fa-undo"></i></button>' +
+                '<button class="btn btn-outline-danger" title="Delete for good"><i class="fas fa-times"></i></button>';
+            actions.children[0].onclick = () => restoreFromTrash(item.type, item.id);
+            actions.children[1].onclick = () => deleteFromTrash(item.type, item.id);
+
+            li.append(text, actions);
+            list.appendChild(li);
+        });
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to fetch trash');
+    });
+}
+
+// Fill the daily emails modal with the user's settings
+function loadDigestSettings() {
+    fetch('/planner/digest/settings')
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+            return;
+        }
+        document.getElementById('digestMorning').checked = data.morning;
+        document.getElementById('digestMorningAt').value = data.morningAt;
+        document.getElementById('digestEvening').checked = data.evening;
+        document.getElementById('digestEveningAt').value = data.eveningAt;
+        document.getElementById('digestTimezone').textContent = data.timezone;
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to fetch email settings');
+    });
+}
+
+// Save which daily emails the user gets and when
+function saveDigestSettings(event) {
+    event.preventDefault();
+
+    fetch('/planner/digest/settings', {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            morning: document.getElementById('digestMorning').checked,
+            morningAt: document.getElementById('digestMorningAt').value,
+            evening: document.getElementById('digestEvening').checked,
+            eveningAt: document.getElementById('digestEveningAt').value
+        })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            bootstrap.Modal.getInstance(document.getElementById('digestModal')).hide();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('Failed to save email settings');
+    });
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+    showUndo();
+    document.getElementById('trashModal').addEventListener('show.bs.modal', loadTrash);
+    document.getElementById('digestModal').addEventListener('show.bs.modal', loadDigestSettings);
+    document.getElementById('digestForm').addEventListener('submit', saveDigestSettings);
+    enableReorder('todoList', 'todos');
+    enableReorder('priorityList', 'priorities');
+    enableReorder('contactList', 'contacts');
+    enableReorder('projectList', 'projects');
+});
@@ -0,0 +1,104 @@
+// Daily and weekly review pages
+
+function finishReview(form) {
+    const items = [];
+    document.querySelectorAll('.review-item').forEach(li => {
+        const action = li.querySelector('.review-action').value;
+        if (!action) return;
+        const item = { type: li.dataset.type, id: parseInt(li.dataset.id, 10), action: action };
+        if (action === 'later') {
+            item.date = li.querySelector('.review-date').value;
+        }
+        items.push(item);
+    });
+
+    fetch(`/planner/reviews/${form.dataset.date}`, {
+        method: 'PUT',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({
+            reflection: document.getElementById('reviewReflection').value,
+            items: items
+        })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('An error occurred while saving the review');
+    });
+}
+
+// Weekly review: add the chosen items to next week's priorities
+function planNextWeek(form) {
+    const priorities = [];
+    form.querySelectorAll('.plan-item').forEach(li => {
+        const date = li.querySelector('.plan-date').value;
+        if (!date) return;
+        const titleInput = li.querySelector('.plan-title');
+        if (titleInput) {
+            if (titleInput.value.trim()) {
+                priorities.push({ date: date, title: titleInput.value });
+            }
+        } else if (li.dataset.todoId) {
+            priorities.push({ date: date, todoId: parseInt(li.dataset.todoId, 10) });
+        } else {
+            priorities.push({ date: date, title: li.dataset.title, description: li.dataset.description });
+        }
+    });
+    if (priorities.length === 0) {
+        alert('Pick a day for what you want to do next week');
+        return;
+    }
+
+    fetch(`/planner/weekly-reviews/${form.dataset.week}/plan`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+        },
+        body: JSON.stringify({ priorities: priorities })
+    })
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+        } else {
+            location.reload();
+        }
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('An error occurred while planning next week');
+    });
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    document.querySelectorAll('.review-action').forEach(select => {
+        select.addEventListener('change', function() {
+            select.parentElement.querySelector('.review-date').classList.toggle('d-none', select.value !== 'later');
+        });
+    });
+
+    const reviewForm = document.getElementById('reviewForm');
+    if (reviewForm) {
+        reviewForm.addEventListener('submit', function(event) {
+            event.preventDefault();
+            finishReview(reviewForm);
+        });
+    }
+
+    const planForm = document.getElementById('planForm');
+    if (planForm) {
+        planForm.addEventListener('submit', function(event) {
+            event.preventDefault();
+            planNextWeek(planForm);
+        });
+    }
+});
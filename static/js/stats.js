@@ -0,0 +1,82 @@
+// Stats page charts
+
+let todoChart = null;
+let rateChart = null;
+
+function loadStats() {
+    const params = new URLSearchParams({ interval: document.getElementById('statsInterval').value });
+    const from = document.getElementById('statsFrom').value;
+    const to = document.getElementById('statsTo').value;
+    if (from) params.set('from', from);
+    if (to) params.set('to', to);
+
+    fetch(`/planner/stats?${params}`)
+    .then(response => response.json())
+    .then(data => {
+        if (data.error) {
+            alert(data.error);
+            return;
+        }
+        document.getElementById('statsFrom').value = data.from;
+        document.getElementById('statsTo').value = data.to;
+        renderStats(data);
+    })
+    .catch(error => {
+        console.error('Error:', error);
+        alert('An error occurred while loading stats');
+    });
+}
+
+function renderStats(data) {
+    const rateText = summary => summary.total > 0 ? `${summary.rate}%` : '-';
+    document.getElementById('statsCompleted').textContent = `${data.todos.completed} / ${data.todos.created}`;
+    document.getElementById('statsAverageHours').textContent = data.todos.completed > 0 ? data.todos.averageHoursToComplete : '-';
+    document.getElementById('statsPriorityRate').textContent = rateText(data.priorities);
+    document.getElementById('statsContactRate').textContent = rateText(data.contacts);
+    document.getElementById('statsWaterRate').textContent = rateText(data.water);
+
+    const labels = data.todos.series.map(point => point.date);
+    // Buckets with nothing due have no rate rather than 0%
+    const rates = summary => summary.series.map(point => point.total > 0 ? point.rate : null);
+
+    if (todoChart) todoChart.destroy();
+    todoChart = new Chart(document.getElementById('todoChart'), {
+        type: 'bar',
+        data: {
+            labels: labels,
+            datasets: [
+                { label: 'Created', data: data.todos.series.map(point => point.created), backgroundColor: '#0d6efd' },
+                { label: 'Completed', data: data.todos.series.map(point => point.completed), backgroundColor: '#198754' }
+            ]
+        },
+        options: { scales: { y: { beginAtZero: true, ticks: { precision: 0 } } } }
+    });
+
+    if (rateChart) rateChart.destroy();
+    rateChart = new Chart(document.getElementById('rateChart'), {
+        type: 'line',
+        data: {
+            labels: labels,
+            datasets: [
+                { label: 'Priorities', data: rates(data.priorities), borderColor: '#0d6efd', spanGaps: true },
+                { label: 'Follow-ups', data: rates(data.contacts), borderColor: '#fd7e14', spanGaps: true },
+                { label: 'Water', data: rates(data.water), borderColor: '#0dcaf0', spanGaps: true }
+            ]
+        },
+        options: { scales: { y: { min: 0, max: 100, ticks: { callback: value => `${value}%` } } } }
+    });
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    document.getElementById('statsForm').addEventListener('submit', function(event) {
+        event.preventDefault();
+        loadStats();
+    });
+    document.getElementById('statsInterval').addEventListener('change', function() {
+        // Each interval has its own default range
+        document.getElementById('statsFrom').value = '';
+        document.getElementById('statsTo').value = '';
+        loadStats();
+    });
+    loadStats();
+});